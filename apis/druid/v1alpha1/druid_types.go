@@ -270,6 +270,11 @@ type DruidSpec struct {
 	// +kubebuilder:default:=true
 	RollingDeploy bool `json:"rollingDeploy"`
 
+	// ServerSideApply Applies the generated resources with server-side apply instead of updating them whole, so
+	// that the operator only owns the fields it sets.
+	// +optional
+	ServerSideApply *ServerSideApplySpec `json:"serverSideApply,omitempty"`
+
 	// DefaultProbes If set to true this will add default probes (liveness / readiness / startup) for all druid components
 	// but it won't override existing probes
 	// +optional
@@ -306,9 +311,34 @@ type DruidSpec struct {
 	// +optional
 	DynamicConfig runtime.RawExtension `json:"dynamicConfig,omitempty"`
 
+	// CompactionTaskSlots Share of the task slots available to compaction tasks. Applied through the
+	// compaction task slots API.
+	// +optional
+	CompactionTaskSlots *CompactionTaskSlotsSpec `json:"compactionTaskSlots,omitempty"`
+
+	// DefaultRules Retention rules of the `_default` dataSource, used by every dataSource without rules of its own.
+	// +optional
+	DefaultRules []runtime.RawExtension `json:"defaultRules,omitempty"`
+
+	// Lookups Lookup configurations by tier, such as `{"__default": {"country": {"version": "v1", ...}}}`.
+	// Applied through the lookups configuration API, bump the version of a lookup to update it.
+	// +optional
+	Lookups runtime.RawExtension `json:"lookups,omitempty"`
+
 	// +optional
 	Auth druidapi.Auth `json:"auth,omitempty"`
 
+	// APIEndpoint is the URL of the Druid API the operator applies the dynamic configurations through, such as
+	// https://druid.example.com:9088. It is discovered from the router service when unset. It requires the
+	// --enable-api-endpoint flag of the operator.
+	// +optional
+	APIEndpoint string `json:"apiEndpoint,omitempty"`
+
+	// TLS makes the operator issue the certificates of the nodes, mount them as keystores into every pod and
+	// enable the TLS port of Druid.
+	// +optional
+	TLS *DruidTLSSpec `json:"tls,omitempty"`
+
 	// See v1.DNSPolicy for more details.
 	// +optional
 	DNSPolicy v1.DNSPolicy `json:"dnsPolicy,omitempty" protobuf:"bytes,6,opt,name=dnsPolicy,casttype=DNSPolicy"`
@@ -318,6 +348,64 @@ type DruidSpec struct {
 	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty" protobuf:"bytes,26,opt,name=dnsConfig"`
 }
 
+// DruidTLSSpec configures the certificates of the nodes.
+type DruidTLSSpec struct {
+	// CASecretName is the name of a secret holding the CA the certificates of the nodes are issued by, in its
+	// tls.crt and tls.key keys. The CAs in its ca.crt key are also trusted, such as the previous CA while the
+	// certificates are issued again. The operator generates a CA in the <druid>-druid-tls-ca secret when unset.
+	// +optional
+	CASecretName string `json:"caSecretName,omitempty"`
+
+	// CertificateSecretName is the name of a secret holding the certificate of the nodes in its tls.crt, tls.key
+	// and ca.crt keys, such as a cert-manager certificate. The operator issues the certificates when unset.
+	// +optional
+	CertificateSecretName string `json:"certificateSecretName,omitempty"`
+
+	// CertificateDuration is the validity of the certificates issued by the operator, 8760h by default. They are
+	// renewed once two thirds of it elapsed.
+	// +optional
+	CertificateDuration *metav1.Duration `json:"certificateDuration,omitempty"`
+
+	// DNSNames are added to the names of the certificates issued by the operator.
+	// +optional
+	DNSNames []string `json:"dnsNames,omitempty"`
+
+	// DisablePlaintextPort disables the plaintext port of the nodes, the probes must then use the TLS port. It
+	// can't be set with defaultProbes, which use the plaintext port.
+	// +optional
+	DisablePlaintextPort bool `json:"disablePlaintextPort,omitempty"`
+
+	// ValidateHostnames makes the nodes check that the certificates of the nodes they call name their host. The
+	// nodes must then announce a name of their certificate in druid.host instead of their pod IP.
+	// +optional
+	ValidateHostnames bool `json:"validateHostnames,omitempty"`
+}
+
+// ServerSideApplySpec configures the server-side apply of the resources generated by the operator.
+type ServerSideApplySpec struct {
+	// Enabled Whether to apply the generated resources with server-side apply, as the `druid-operator` field manager.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ForceConflicts Whether to take over the fields set by other field managers, the conflicts fail the
+	// reconciliation otherwise.
+	// +optional
+	ForceConflicts bool `json:"forceConflicts,omitempty"`
+}
+
+// CompactionTaskSlotsSpec limits the task slots compaction tasks can use.
+type CompactionTaskSlotsSpec struct {
+	// Ratio Ratio of the total task slots compaction tasks can use, such as `0.1`.
+	// +optional
+	// +kubebuilder:validation:Pattern:=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	Ratio string `json:"ratio,omitempty"`
+
+	// Max Maximum number of task slots compaction tasks can use.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Max *int32 `json:"max,omitempty"`
+}
+
 // DruidNodeSpec Specification of `Druid` Node type and its configurations.
 // The key in following map can be arbitrary string that helps you identify resources for a specific nodeSpec.
 // It is used in the Kubernetes resources' names, so it must be compliant with restrictions
@@ -493,6 +581,11 @@ type DruidNodeSpec struct {
 	// +optional
 	HPAutoScaler *autoscalev2.HorizontalPodAutoscalerSpec `json:"hpAutoscaler,omitempty"`
 
+	// TaskSlotAutoScaler scales the middleManagers or indexers of the node spec with the task slots needed by the
+	// ingestion tasks, as reported by the overlord. It can't be combined with the hpAutoscaler.
+	// +optional
+	TaskSlotAutoScaler *TaskSlotAutoScalerSpec `json:"taskSlotAutoScaler,omitempty"`
+
 	// TopologySpreadConstraints Kubernetes Native `topologySpreadConstraints` specification.
 	// +optional
 	TopologySpreadConstraints []v1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
@@ -530,6 +623,31 @@ type DruidNodeSpec struct {
 	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty" protobuf:"bytes,26,opt,name=dnsConfig"`
 }
 
+// TaskSlotAutoScalerSpec scales a StatefulSet of middleManagers or indexers between its min and max replicas, with
+// the task slots used by the running tasks and needed by the pending tasks. The workers are disabled and drained
+// of their running tasks before a scale down.
+type TaskSlotAutoScalerSpec struct {
+	// MinReplicas is the lower limit of the replicas.
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas int32 `json:"minReplicas"`
+
+	// MaxReplicas is the upper limit of the replicas.
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// ScaleUpCooldown is the minimum time between the last scaling and a scale up, 1m by default.
+	// +optional
+	ScaleUpCooldown *metav1.Duration `json:"scaleUpCooldown,omitempty"`
+
+	// ScaleDownCooldown is the minimum time between the last scaling and a scale down, 10m by default.
+	// +optional
+	ScaleDownCooldown *metav1.Duration `json:"scaleDownCooldown,omitempty"`
+
+	// PollPeriod is the period the overlord is polled at, 30s by default.
+	// +optional
+	PollPeriod *metav1.Duration `json:"pollPeriod,omitempty"`
+}
+
 // ZookeeperSpec IGNORED (Future API): In order to make Druid dependency setup extensible from within Druid operator.
 type ZookeeperSpec struct {
 	Type string          `json:"type"`
@@ -563,6 +681,13 @@ const (
 
 type DruidNodeConditionType string
 
+// Condition types of a Druid.
+const (
+	// DruidConditionReferencesResolved reports whether the configmaps and secrets referenced by the
+	// extraCommonConfig and the runtime properties are found.
+	DruidConditionReferencesResolved = "ReferencesResolved"
+)
+
 type DruidNodeTypeStatus struct {
 	DruidNode                string                 `json:"druidNode,omitempty"`
 	DruidNodeConditionStatus v1.ConditionStatus     `json:"druidNodeConditionStatus,omitempty"`
@@ -570,20 +695,53 @@ type DruidNodeTypeStatus struct {
 	Reason                   string                 `json:"reason,omitempty"`
 }
 
+// HPAutoScalerReplicas is the scaling decision of the HPA of a node spec.
+type HPAutoScalerReplicas struct {
+	// Name of the HPA.
+	Name string `json:"name"`
+	// CurrentReplicas is the number of replicas last seen by the HPA.
+	CurrentReplicas int32 `json:"currentReplicas,omitempty"`
+	// DesiredReplicas is the number of replicas last decided by the HPA.
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+}
+
+// TaskSlotAutoScalerStatus is the state of the task slot autoscaler of a node spec.
+type TaskSlotAutoScalerStatus struct {
+	// Name of the StatefulSet.
+	Name string `json:"name"`
+	// DesiredReplicas is the number of replicas last decided by the autoscaler.
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// LastScaleTime is the last time the replicas were changed by the autoscaler.
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+	// DrainingWorkers are the workers disabled for the scale down to the desired replicas, which happens once
+	// they have no running task left.
+	// +optional
+	DrainingWorkers []string `json:"drainingWorkers,omitempty"`
+}
+
 // DruidClusterStatus Defines the observed state of Druid.
 type DruidClusterStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	DruidNodeStatus        DruidNodeTypeStatus `json:"druidNodeStatus,omitempty"`
-	StatefulSets           []string            `json:"statefulSets,omitempty"`
-	Deployments            []string            `json:"deployments,omitempty"`
-	Services               []string            `json:"services,omitempty"`
-	ConfigMaps             []string            `json:"configMaps,omitempty"`
-	PodDisruptionBudgets   []string            `json:"podDisruptionBudgets,omitempty"`
-	Ingress                []string            `json:"ingress,omitempty"`
-	HPAutoScalers          []string            `json:"hpAutoscalers,omitempty"`
-	Pods                   []string            `json:"pods,omitempty"`
-	PersistentVolumeClaims []string            `json:"persistentVolumeClaims,omitempty"`
+	DruidNodeStatus      DruidNodeTypeStatus `json:"druidNodeStatus,omitempty"`
+	StatefulSets         []string            `json:"statefulSets,omitempty"`
+	Deployments          []string            `json:"deployments,omitempty"`
+	Services             []string            `json:"services,omitempty"`
+	ConfigMaps           []string            `json:"configMaps,omitempty"`
+	PodDisruptionBudgets []string            `json:"podDisruptionBudgets,omitempty"`
+	Ingress              []string            `json:"ingress,omitempty"`
+	HPAutoScalers        []string            `json:"hpAutoscalers,omitempty"`
+	// HPAutoScalerReplicas are the replicas decided by the HPAs, which own the replicas of their workloads.
+	HPAutoScalerReplicas []HPAutoScalerReplicas `json:"hpAutoscalerReplicas,omitempty"`
+	// TaskSlotAutoScalers are the states of the task slot autoscalers of the node specs.
+	TaskSlotAutoScalers    []TaskSlotAutoScalerStatus `json:"taskSlotAutoScalers,omitempty"`
+	Pods                   []string                   `json:"pods,omitempty"`
+	PersistentVolumeClaims []string                   `json:"persistentVolumeClaims,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // Druid is the Schema for the druids API.
@@ -0,0 +1,130 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package v1alpha1
+
+import (
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Condition types of a DruidDataSource.
+const (
+	// DruidDataSourceConditionSynced reports whether the rules and compaction config are applied on the cluster.
+	DruidDataSourceConditionSynced = "Synced"
+)
+
+// DruidDataSourceSpec manages the settings of a dataSource, independently of how it is ingested.
+type DruidDataSourceSpec struct {
+	// +required
+	DruidClusterName string `json:"druidCluster"`
+	// +optional
+	// DataSource is the name of the dataSource in Druid. Defaults to the name of the DruidDataSource.
+	DataSource string `json:"dataSource,omitempty"`
+	// +optional
+	Auth druidapi.Auth `json:"auth"`
+	// +optional
+	// Rules are the retention rules of the dataSource.
+	Rules []runtime.RawExtension `json:"rules,omitempty"`
+	// +optional
+	// Compaction is the auto-compaction config of the dataSource, its dataSource is set by the operator.
+	Compaction runtime.RawExtension `json:"compaction,omitempty"`
+	// +optional
+	// Kill is a one-shot kill task deleting the unused segments of an interval.
+	Kill *DataSourceKill `json:"kill,omitempty"`
+	// +optional
+	// OnDelete selects the cleanup run when the DruidDataSource is deleted.
+	OnDelete *DataSourceOnDelete `json:"onDelete,omitempty"`
+}
+
+// DataSourceKill is run once per generation, bump the generation to run it again.
+type DataSourceKill struct {
+	// +required
+	// Interval of the unused segments to delete, such as 2015-09-12/2015-09-13.
+	Interval string `json:"interval"`
+	// +required
+	Generation int64 `json:"generation"`
+}
+
+// DataSourceOnDelete selects the cleanup of the dataSource when its DruidDataSource is deleted.
+type DataSourceOnDelete struct {
+	// +optional
+	// DeleteCompaction deletes the compaction config of the dataSource.
+	DeleteCompaction bool `json:"deleteCompaction,omitempty"`
+	// +optional
+	// ResetRules resets the retention rules of the dataSource to the cluster default rules.
+	ResetRules bool `json:"resetRules,omitempty"`
+	// +optional
+	// MarkSegmentsUnused marks all segments of the dataSource as unused.
+	MarkSegmentsUnused bool `json:"markSegmentsUnused,omitempty"`
+}
+
+// DataSourceKillStatus is the outcome of the last kill task.
+type DataSourceKillStatus struct {
+	Interval   string      `json:"interval"`
+	Generation int64       `json:"generation"`
+	Succeeded  bool        `json:"succeeded"`
+	TaskId     string      `json:"taskId,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Time       metav1.Time `json:"time,omitempty"`
+}
+
+type DruidDataSourceStatus struct {
+	// DataSource is the name of the dataSource in Druid.
+	DataSource     string      `json:"dataSource,omitempty"`
+	Reason         string      `json:"reason,omitempty"`
+	Message        string      `json:"message,omitempty"`
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+	// CurrentRules are the last rules applied on the cluster.
+	CurrentRules []runtime.RawExtension `json:"rules,omitempty"`
+	// CurrentCompaction is the last compaction config applied on the cluster.
+	CurrentCompaction string `json:"compaction,omitempty"`
+	// LastKill is the outcome of the last kill task run from spec.kill.
+	LastKill *DataSourceKillStatus `json:"lastKill,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="DataSource",type="string",JSONPath=".status.dataSource"
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+// DruidDataSource is the Schema for the DruidDataSource API
+type DruidDataSource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DruidDataSourceSpec   `json:"spec"`
+	Status DruidDataSourceStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// DruidDataSourceList contains a list of DruidDataSource
+type DruidDataSourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DruidDataSource `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DruidDataSource{}, &DruidDataSourceList{})
+}
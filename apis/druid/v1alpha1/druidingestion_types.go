@@ -35,15 +35,120 @@ const (
 	HadoopIndexHadoop        DruidIngestionMethod = "index-hadoop"
 )
 
+// Condition types of a DruidIngestion, set from the polled status of its task or supervisor.
+const (
+	// DruidIngestionConditionRunning is true while the task or supervisor is running.
+	DruidIngestionConditionRunning = "Running"
+	// DruidIngestionConditionSucceeded is true once the task has succeeded.
+	DruidIngestionConditionSucceeded = "Succeeded"
+	// DruidIngestionConditionFailed is true once the task has failed.
+	DruidIngestionConditionFailed = "Failed"
+	// DruidIngestionConditionHealthy reports whether the supervisor is healthy.
+	DruidIngestionConditionHealthy = "Healthy"
+	// DruidIngestionConditionSuspended is true while the ingestion is suspended through spec.suspend.
+	DruidIngestionConditionSuspended = "Suspended"
+	// DruidIngestionConditionRulesInSync reports whether the rules on the coordinator match the spec.
+	DruidIngestionConditionRulesInSync = "RulesInSync"
+)
+
+// RulesDriftPolicy is what the operator does when the rules on the coordinator drift from the spec.
+type RulesDriftPolicy string
+
+const (
+	// RulesDriftReapply sets the rules of the spec again.
+	RulesDriftReapply RulesDriftPolicy = "Reapply"
+	// RulesDriftObserveOnly only reports the drift in the RulesInSync condition.
+	RulesDriftObserveOnly RulesDriftPolicy = "ObserveOnly"
+)
+
+type SupervisorActionType string
+
+const (
+	SupervisorActionReset        SupervisorActionType = "reset"
+	SupervisorActionResetOffsets SupervisorActionType = "resetOffsets"
+	SupervisorActionHandoff      SupervisorActionType = "handoff"
+	SupervisorActionTerminate    SupervisorActionType = "terminate"
+)
+
 type DruidIngestionSpec struct {
 	// +optional
 	Suspend bool `json:"suspend"`
 	// +required
 	DruidClusterName string `json:"druidCluster"`
+	// +optional
+	// APIEndpoint is the URL of the Druid API, such as https://druid.example.com:9088. It is discovered from the
+	// router service of the cluster when unset. It requires the --enable-api-endpoint flag of the operator.
+	APIEndpoint string `json:"apiEndpoint,omitempty"`
 	// +required
 	Ingestion IngestionSpec `json:"ingestion"`
 	// +optional
 	Auth druidapi.Auth `json:"auth"`
+	// +optional
+	// Action is a one-shot action run on the supervisor of a kafka or kinesis ingestion.
+	Action *SupervisorAction `json:"action,omitempty"`
+	// +optional
+	// RetryPolicy controls how a submission rejected by Druid is retried.
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+	// +optional
+	// Cleanup opts in to the cleanup of the supervisors and dataSources no longer ingested into.
+	Cleanup *CleanupPolicy `json:"cleanup,omitempty"`
+}
+
+// CleanupPolicy selects the cleanup run on the supervisors and dataSources a DruidIngestion has ingested into,
+// once they are replaced, such as after a rename of the dataSource, and on deletion of the DruidIngestion.
+type CleanupPolicy struct {
+	// +optional
+	// ShutdownSupervisor terminates the replaced supervisors.
+	ShutdownSupervisor bool `json:"shutdownSupervisor,omitempty"`
+	// +optional
+	// DeleteCompaction deletes the compaction config of the replaced dataSources.
+	DeleteCompaction bool `json:"deleteCompaction,omitempty"`
+	// +optional
+	// ResetRules resets the retention rules of the replaced dataSources to the cluster default rules.
+	ResetRules bool `json:"resetRules,omitempty"`
+	// +optional
+	// MarkSegmentsUnused marks all segments of the replaced dataSources as unused.
+	MarkSegmentsUnused bool `json:"markSegmentsUnused,omitempty"`
+}
+
+// RetryPolicy retries failed submissions with an exponential backoff.
+type RetryPolicy struct {
+	// +optional
+	// MaxAttempts is the number of failed submissions after which the Failed condition is set, 0 never sets it.
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+	// +optional
+	// InitialBackoff is the wait after the first failed submission, doubled after every further failure. Defaults to 10s.
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"`
+	// +optional
+	// MaxBackoff caps the backoff. Defaults to 10m.
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+	// +optional
+	// GiveUp stops retrying once maxAttempts is reached, until the spec changes.
+	GiveUp bool `json:"giveUp,omitempty"`
+}
+
+// SupervisorAction is run once per generation, bump the generation to run it again.
+type SupervisorAction struct {
+	// +required
+	// +kubebuilder:validation:Enum=reset;resetOffsets;handoff;terminate
+	Type SupervisorActionType `json:"type"`
+	// +required
+	Generation int64 `json:"generation"`
+	// +optional
+	// PartitionOffsets maps the partitions, or shards, to the offsets, or sequence numbers, resetOffsets resets to.
+	PartitionOffsets map[string]string `json:"partitionOffsets,omitempty"`
+	// +optional
+	// TaskGroupIds are the task groups handoff hands off early.
+	TaskGroupIds []int `json:"taskGroupIds,omitempty"`
+}
+
+// SupervisorActionStatus is the outcome of the last supervisor action.
+type SupervisorActionStatus struct {
+	Type       SupervisorActionType `json:"type"`
+	Generation int64                `json:"generation"`
+	Succeeded  bool                 `json:"succeeded"`
+	Message    string               `json:"message,omitempty"`
+	Time       metav1.Time          `json:"time,omitempty"`
 }
 
 type IngestionSpec struct {
@@ -60,9 +165,28 @@ type IngestionSpec struct {
 	// Note: Spec will be ignored if nativeSpec is provided.
 	NativeSpec runtime.RawExtension `json:"nativeSpec,omitempty"`
 	// +optional
+	// sql is the INSERT or REPLACE statement submitted to the SQL task API when type is sql.
+	// Note: Spec and nativeSpec will be ignored if sql is provided.
+	SQL *SQLSpec `json:"sql,omitempty"`
+	// +optional
 	Compaction runtime.RawExtension `json:"compaction,omitempty"`
 	// +optional
 	Rules []runtime.RawExtension `json:"rules,omitempty"`
+	// +optional
+	// RulesDriftPolicy is what the operator does when the rules are changed on the coordinator, such as from
+	// the web console. Defaults to Reapply.
+	// +kubebuilder:validation:Enum=Reapply;ObserveOnly
+	RulesDriftPolicy RulesDriftPolicy `json:"rulesDriftPolicy,omitempty"`
+}
+
+// SQLSpec is a SQL-based ingestion, run as a multi-stage query task.
+type SQLSpec struct {
+	// +required
+	// Query is the INSERT or REPLACE statement.
+	Query string `json:"query"`
+	// +optional
+	// Context is the query context, such as maxNumTasks or finalizeAggregations.
+	Context runtime.RawExtension `json:"context,omitempty"`
 }
 
 type DruidIngestionStatus struct {
@@ -76,6 +200,35 @@ type DruidIngestionStatus struct {
 	// IngestionSpecs that are stored as JSON strings.
 	CurrentIngestionSpec string                 `json:"currentIngestionSpec.json"`
 	CurrentRules         []runtime.RawExtension `json:"rules,omitempty"`
+	// CompactionDataSource is the dataSource the compaction config of the spec has been set on, it is
+	// deleted once the compaction config is removed from the spec.
+	CompactionDataSource string `json:"compactionDataSource,omitempty"`
+	// RulesDataSource is the dataSource the rules of the spec have been set on, they are reset to the
+	// cluster default rules once removed from the spec.
+	RulesDataSource string `json:"rulesDataSource,omitempty"`
+	// TaskStatus is the last polled status of the task, RUNNING, SUCCESS or FAILED,
+	// or the last polled state of the supervisor.
+	TaskStatus string `json:"taskStatus,omitempty"`
+	// Suspended is true once the supervisor has been suspended, or the task cancelled, because of spec.suspend.
+	Suspended bool `json:"suspended,omitempty"`
+	// AggregateLag is the last polled lag of the supervisor, summed over all partitions.
+	AggregateLag *int64 `json:"aggregateLag,omitempty"`
+	// SubmitAttempts is the number of failed submissions of the spec at FailedGeneration.
+	SubmitAttempts int32 `json:"submitAttempts,omitempty"`
+	// FailedGeneration is the generation whose submissions failed.
+	FailedGeneration int64 `json:"failedGeneration,omitempty"`
+	// NextSubmitTime is when the failed submission is retried, unset once retries are given up.
+	NextSubmitTime *metav1.Time `json:"nextSubmitTime,omitempty"`
+	// DataSources are the dataSources ingested into and not cleaned up yet.
+	DataSources []string `json:"dataSources,omitempty"`
+	// Supervisors are the supervisors submitted and not cleaned up yet.
+	Supervisors []string `json:"supervisors,omitempty"`
+	// LastAction is the outcome of the last supervisor action run from spec.action.
+	LastAction *SupervisorActionStatus `json:"lastAction,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,112 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package v1alpha1
+
+import (
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Condition types of a DruidLookup.
+const (
+	// DruidLookupConditionSynced reports whether the lookup is applied on the cluster.
+	DruidLookupConditionSynced = "Synced"
+	// DruidLookupConditionLoaded reports whether every node of the tier loaded the current version of the lookup.
+	DruidLookupConditionLoaded = "Loaded"
+)
+
+// DruidLookupSpec manages a lookup of a tier.
+type DruidLookupSpec struct {
+	// +required
+	DruidClusterName string `json:"druidCluster"`
+	// +optional
+	// +kubebuilder:default:=__default
+	// Tier is the tier of the lookup.
+	Tier string `json:"tier,omitempty"`
+	// +optional
+	// Name is the name of the lookup in Druid. Defaults to the name of the DruidLookup.
+	Name string `json:"name,omitempty"`
+	// +optional
+	// Version of the lookup. When unset, a new version is generated on every change of the lookup.
+	Version string `json:"version,omitempty"`
+	// +optional
+	// ExtractionNamespace is the extractionNamespace of a cachedNamespace lookup.
+	ExtractionNamespace runtime.RawExtension `json:"extractionNamespace,omitempty"`
+	// +optional
+	// LookupExtractorFactory is the lookupExtractorFactory of the lookup, for lookups other than cachedNamespace
+	// ones or to set more fields of the factory. It is exclusive with extractionNamespace.
+	LookupExtractorFactory runtime.RawExtension `json:"lookupExtractorFactory,omitempty"`
+	// +optional
+	Auth druidapi.Auth `json:"auth"`
+}
+
+// DruidLookupNodeStatus is the load status of the lookup on a node of the tier.
+type DruidLookupNodeStatus struct {
+	Host   string `json:"host"`
+	Loaded bool   `json:"loaded"`
+}
+
+type DruidLookupStatus struct {
+	// Tier is the tier of the lookup in Druid.
+	Tier string `json:"tier,omitempty"`
+	// Name is the name of the lookup in Druid.
+	Name string `json:"name,omitempty"`
+	// Version is the last version applied on the cluster.
+	Version string `json:"version,omitempty"`
+	// CurrentLookupExtractorFactory is the last lookupExtractorFactory applied on the cluster.
+	CurrentLookupExtractorFactory string      `json:"lookupExtractorFactory,omitempty"`
+	Reason                        string      `json:"reason,omitempty"`
+	Message                       string      `json:"message,omitempty"`
+	LastUpdateTime                metav1.Time `json:"lastUpdateTime,omitempty"`
+	// Nodes is the load status of the lookup on the nodes of the tier.
+	Nodes []DruidLookupNodeStatus `json:"nodes,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="Tier",type="string",JSONPath=".status.tier"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version"
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+// +kubebuilder:printcolumn:name="Loaded",type="string",JSONPath=".status.conditions[?(@.type==\"Loaded\")].status"
+// DruidLookup is the Schema for the DruidLookup API
+type DruidLookup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DruidLookupSpec   `json:"spec"`
+	Status DruidLookupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// DruidLookupList contains a list of DruidLookup
+type DruidLookupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DruidLookup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DruidLookup{}, &DruidLookupList{})
+}
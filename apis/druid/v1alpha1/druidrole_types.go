@@ -0,0 +1,106 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package v1alpha1
+
+import (
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of a DruidRole.
+const (
+	// DruidRoleConditionSynced reports whether the role and its permissions are applied on the cluster.
+	DruidRoleConditionSynced = "Synced"
+)
+
+// DruidResource is a resource of a Druid permission.
+type DruidResource struct {
+	// +required
+	// Name of the resource, a regular expression matched against the resource names.
+	Name string `json:"name"`
+	// +required
+	// +kubebuilder:validation:Enum:=DATASOURCE;VIEW;CONFIG;STATE;SYSTEM_TABLE;QUERY_CONTEXT;EXTERNAL
+	Type string `json:"type"`
+}
+
+// DruidPermission grants an action on a resource.
+type DruidPermission struct {
+	// +required
+	Resource DruidResource `json:"resource"`
+	// +required
+	// +kubebuilder:validation:Enum:=READ;WRITE
+	Action string `json:"action"`
+}
+
+// DruidRoleSpec manages a role of the basic authorizer of a Druid cluster.
+type DruidRoleSpec struct {
+	// +required
+	DruidClusterName string `json:"druidCluster"`
+	// +optional
+	Auth druidapi.Auth `json:"auth"`
+	// +required
+	// AuthorizerName is the name of the basic authorizer of the role.
+	AuthorizerName string `json:"authorizerName"`
+	// +optional
+	// RoleName is the name of the role in Druid. Defaults to the name of the DruidRole.
+	RoleName string `json:"roleName,omitempty"`
+	// +optional
+	// Permissions are the permissions of the role, they replace the permissions set outside of the operator.
+	Permissions []DruidPermission `json:"permissions,omitempty"`
+}
+
+type DruidRoleStatus struct {
+	// RoleName is the name of the role in Druid.
+	RoleName string `json:"roleName,omitempty"`
+	// CurrentPermissions are the last permissions applied on the cluster.
+	CurrentPermissions []DruidPermission `json:"permissions,omitempty"`
+	Reason             string            `json:"reason,omitempty"`
+	Message            string            `json:"message,omitempty"`
+	LastUpdateTime     metav1.Time       `json:"lastUpdateTime,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=".status.roleName"
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+// DruidRole is the Schema for the DruidRole API
+type DruidRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DruidRoleSpec   `json:"spec"`
+	Status DruidRoleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// DruidRoleList contains a list of DruidRole
+type DruidRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DruidRole `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DruidRole{}, &DruidRoleList{})
+}
@@ -0,0 +1,97 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package v1alpha1
+
+import (
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types of a DruidUser.
+const (
+	// DruidUserConditionSynced reports whether the user and its roles are applied on the cluster.
+	DruidUserConditionSynced = "Synced"
+)
+
+// DruidUserSpec manages a user of the basic authenticator and authorizer of a Druid cluster.
+type DruidUserSpec struct {
+	// +required
+	DruidClusterName string `json:"druidCluster"`
+	// +optional
+	Auth druidapi.Auth `json:"auth"`
+	// +required
+	// AuthenticatorName is the name of the basic authenticator the user logs in with.
+	AuthenticatorName string `json:"authenticatorName"`
+	// +required
+	// AuthorizerName is the name of the basic authorizer the roles of the user are bound in.
+	AuthorizerName string `json:"authorizerName"`
+	// +optional
+	// UserName is the name of the user in Druid. Defaults to the name of the DruidUser.
+	UserName string `json:"userName,omitempty"`
+	// +optional
+	// PasswordSecret selects the key of a Secret holding the password of the user, the password is set again
+	// whenever the Secret changes.
+	PasswordSecret *v1.SecretKeySelector `json:"passwordSecret,omitempty"`
+	// +optional
+	// Roles are the names of the roles bound to the user.
+	Roles []string `json:"roles,omitempty"`
+}
+
+type DruidUserStatus struct {
+	// UserName is the name of the user in Druid.
+	UserName string `json:"userName,omitempty"`
+	// Roles are the roles bound to the user by the operator.
+	Roles []string `json:"roles,omitempty"`
+	// PasswordSecretVersion is the resource version of the Secret the password was last set from.
+	PasswordSecretVersion string      `json:"passwordSecretVersion,omitempty"`
+	Reason                string      `json:"reason,omitempty"`
+	Message               string      `json:"message,omitempty"`
+	LastUpdateTime        metav1.Time `json:"lastUpdateTime,omitempty"`
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="User",type="string",JSONPath=".status.userName"
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+// DruidUser is the Schema for the DruidUser API
+type DruidUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DruidUserSpec   `json:"spec"`
+	Status DruidUserStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// DruidUserList contains a list of DruidUser
+type DruidUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DruidUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DruidUser{}, &DruidUserList{})
+}
@@ -1,3 +1,5 @@
+//go:build !ignore_autogenerated
+
 /*
 Licensed to the Apache Software Foundation (ASF) under one
 or more contributor license agreements.  See the NOTICE file
@@ -16,7 +18,6 @@ KIND, either express or implied.  See the License for the
 specific language governing permissions and limitations
 under the License.
 */
-//go:build !ignore_autogenerated
 
 /*
 Copyright 2023.
@@ -45,6 +46,7 @@ import (
 	"k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -100,6 +102,87 @@ func (in *AdditionalContainer) DeepCopy() *AdditionalContainer {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CleanupPolicy) DeepCopyInto(out *CleanupPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CleanupPolicy.
+func (in *CleanupPolicy) DeepCopy() *CleanupPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CleanupPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompactionTaskSlotsSpec) DeepCopyInto(out *CompactionTaskSlotsSpec) {
+	*out = *in
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompactionTaskSlotsSpec.
+func (in *CompactionTaskSlotsSpec) DeepCopy() *CompactionTaskSlotsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CompactionTaskSlotsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceKill) DeepCopyInto(out *DataSourceKill) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceKill.
+func (in *DataSourceKill) DeepCopy() *DataSourceKill {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceKill)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceKillStatus) DeepCopyInto(out *DataSourceKillStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceKillStatus.
+func (in *DataSourceKillStatus) DeepCopy() *DataSourceKillStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceKillStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSourceOnDelete) DeepCopyInto(out *DataSourceOnDelete) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSourceOnDelete.
+func (in *DataSourceOnDelete) DeepCopy() *DataSourceOnDelete {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSourceOnDelete)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeepStorageSpec) DeepCopyInto(out *DeepStorageSpec) {
 	*out = *in
@@ -186,6 +269,18 @@ func (in *DruidClusterStatus) DeepCopyInto(out *DruidClusterStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.HPAutoScalerReplicas != nil {
+		in, out := &in.HPAutoScalerReplicas, &out.HPAutoScalerReplicas
+		*out = make([]HPAutoScalerReplicas, len(*in))
+		copy(*out, *in)
+	}
+	if in.TaskSlotAutoScalers != nil {
+		in, out := &in.TaskSlotAutoScalers, &out.TaskSlotAutoScalers
+		*out = make([]TaskSlotAutoScalerStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Pods != nil {
 		in, out := &in.Pods, &out.Pods
 		*out = make([]string, len(*in))
@@ -196,6 +291,13 @@ func (in *DruidClusterStatus) DeepCopyInto(out *DruidClusterStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidClusterStatus.
@@ -208,6 +310,134 @@ func (in *DruidClusterStatus) DeepCopy() *DruidClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidDataSource) DeepCopyInto(out *DruidDataSource) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidDataSource.
+func (in *DruidDataSource) DeepCopy() *DruidDataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidDataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidDataSource) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidDataSourceList) DeepCopyInto(out *DruidDataSourceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DruidDataSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidDataSourceList.
+func (in *DruidDataSourceList) DeepCopy() *DruidDataSourceList {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidDataSourceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidDataSourceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidDataSourceSpec) DeepCopyInto(out *DruidDataSourceSpec) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Compaction.DeepCopyInto(&out.Compaction)
+	if in.Kill != nil {
+		in, out := &in.Kill, &out.Kill
+		*out = new(DataSourceKill)
+		**out = **in
+	}
+	if in.OnDelete != nil {
+		in, out := &in.OnDelete, &out.OnDelete
+		*out = new(DataSourceOnDelete)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidDataSourceSpec.
+func (in *DruidDataSourceSpec) DeepCopy() *DruidDataSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidDataSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidDataSourceStatus) DeepCopyInto(out *DruidDataSourceStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.CurrentRules != nil {
+		in, out := &in.CurrentRules, &out.CurrentRules
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastKill != nil {
+		in, out := &in.LastKill, &out.LastKill
+		*out = new(DataSourceKillStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidDataSourceStatus.
+func (in *DruidDataSourceStatus) DeepCopy() *DruidDataSourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidDataSourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DruidIngestion) DeepCopyInto(out *DruidIngestion) {
 	*out = *in
@@ -271,7 +501,22 @@ func (in *DruidIngestionList) DeepCopyObject() runtime.Object {
 func (in *DruidIngestionSpec) DeepCopyInto(out *DruidIngestionSpec) {
 	*out = *in
 	in.Ingestion.DeepCopyInto(&out.Ingestion)
-	out.Auth = in.Auth
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.Action != nil {
+		in, out := &in.Action, &out.Action
+		*out = new(SupervisorAction)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cleanup != nil {
+		in, out := &in.Cleanup, &out.Cleanup
+		*out = new(CleanupPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidIngestionSpec.
@@ -295,6 +540,37 @@ func (in *DruidIngestionStatus) DeepCopyInto(out *DruidIngestionStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AggregateLag != nil {
+		in, out := &in.AggregateLag, &out.AggregateLag
+		*out = new(int64)
+		**out = **in
+	}
+	if in.NextSubmitTime != nil {
+		in, out := &in.NextSubmitTime, &out.NextSubmitTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DataSources != nil {
+		in, out := &in.DataSources, &out.DataSources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Supervisors != nil {
+		in, out := &in.Supervisors, &out.Supervisors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastAction != nil {
+		in, out := &in.LastAction, &out.LastAction
+		*out = new(SupervisorActionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidIngestionStatus.
@@ -340,47 +616,167 @@ func (in *DruidList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DruidNodeSpec) DeepCopyInto(out *DruidNodeSpec) {
+func (in *DruidLookup) DeepCopyInto(out *DruidLookup) {
 	*out = *in
-	if in.PodLabels != nil {
-		in, out := &in.PodLabels, &out.PodLabels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.PodDisruptionBudgetSpec != nil {
-		in, out := &in.PodDisruptionBudgetSpec, &out.PodDisruptionBudgetSpec
-		*out = new(policyv1.PodDisruptionBudgetSpec)
-		(*in).DeepCopyInto(*out)
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidLookup.
+func (in *DruidLookup) DeepCopy() *DruidLookup {
+	if in == nil {
+		return nil
 	}
-	if in.Services != nil {
-		in, out := &in.Services, &out.Services
-		*out = make([]v1.Service, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	out := new(DruidLookup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidLookup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	if in.Tolerations != nil {
-		in, out := &in.Tolerations, &out.Tolerations
-		*out = make([]v1.Toleration, len(*in))
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidLookupList) DeepCopyInto(out *DruidLookupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DruidLookup, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Affinity != nil {
-		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidLookupList.
+func (in *DruidLookupList) DeepCopy() *DruidLookupList {
+	if in == nil {
+		return nil
 	}
-	if in.NodeSelector != nil {
-		in, out := &in.NodeSelector, &out.NodeSelector
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	out := new(DruidLookupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidLookupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	if in.TerminationGracePeriodSeconds != nil {
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidLookupNodeStatus) DeepCopyInto(out *DruidLookupNodeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidLookupNodeStatus.
+func (in *DruidLookupNodeStatus) DeepCopy() *DruidLookupNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidLookupNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidLookupSpec) DeepCopyInto(out *DruidLookupSpec) {
+	*out = *in
+	in.ExtractionNamespace.DeepCopyInto(&out.ExtractionNamespace)
+	in.LookupExtractorFactory.DeepCopyInto(&out.LookupExtractorFactory)
+	in.Auth.DeepCopyInto(&out.Auth)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidLookupSpec.
+func (in *DruidLookupSpec) DeepCopy() *DruidLookupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidLookupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidLookupStatus) DeepCopyInto(out *DruidLookupStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]DruidLookupNodeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidLookupStatus.
+func (in *DruidLookupStatus) DeepCopy() *DruidLookupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidLookupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidNodeSpec) DeepCopyInto(out *DruidNodeSpec) {
+	*out = *in
+	if in.PodLabels != nil {
+		in, out := &in.PodLabels, &out.PodLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodDisruptionBudgetSpec != nil {
+		in, out := &in.PodDisruptionBudgetSpec, &out.PodDisruptionBudgetSpec
+		*out = new(policyv1.PodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Services != nil {
+		in, out := &in.Services, &out.Services
+		*out = make([]v1.Service, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TerminationGracePeriodSeconds != nil {
 		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
 		*out = new(int64)
 		**out = **in
@@ -493,6 +889,11 @@ func (in *DruidNodeSpec) DeepCopyInto(out *DruidNodeSpec) {
 		*out = new(v2.HorizontalPodAutoscalerSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TaskSlotAutoScaler != nil {
+		in, out := &in.TaskSlotAutoScaler, &out.TaskSlotAutoScaler
+		*out = new(TaskSlotAutoScalerSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.TopologySpreadConstraints != nil {
 		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
 		*out = make([]v1.TopologySpreadConstraint, len(*in))
@@ -561,6 +962,145 @@ func (in *DruidNodeTypeStatus) DeepCopy() *DruidNodeTypeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidPermission) DeepCopyInto(out *DruidPermission) {
+	*out = *in
+	out.Resource = in.Resource
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidPermission.
+func (in *DruidPermission) DeepCopy() *DruidPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidPermission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidResource) DeepCopyInto(out *DruidResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidResource.
+func (in *DruidResource) DeepCopy() *DruidResource {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidRole) DeepCopyInto(out *DruidRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidRole.
+func (in *DruidRole) DeepCopy() *DruidRole {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidRoleList) DeepCopyInto(out *DruidRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DruidRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidRoleList.
+func (in *DruidRoleList) DeepCopy() *DruidRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidRoleSpec) DeepCopyInto(out *DruidRoleSpec) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]DruidPermission, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidRoleSpec.
+func (in *DruidRoleSpec) DeepCopy() *DruidRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidRoleStatus) DeepCopyInto(out *DruidRoleStatus) {
+	*out = *in
+	if in.CurrentPermissions != nil {
+		in, out := &in.CurrentPermissions, &out.CurrentPermissions
+		*out = make([]DruidPermission, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidRoleStatus.
+func (in *DruidRoleStatus) DeepCopy() *DruidRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DruidSpec) DeepCopyInto(out *DruidSpec) {
 	*out = *in
@@ -722,7 +1262,30 @@ func (in *DruidSpec) DeepCopyInto(out *DruidSpec) {
 		(*in).DeepCopyInto(*out)
 	}
 	in.DynamicConfig.DeepCopyInto(&out.DynamicConfig)
-	out.Auth = in.Auth
+	if in.CompactionTaskSlots != nil {
+		in, out := &in.CompactionTaskSlots, &out.CompactionTaskSlots
+		*out = new(CompactionTaskSlotsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultRules != nil {
+		in, out := &in.DefaultRules, &out.DefaultRules
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Lookups.DeepCopyInto(&out.Lookups)
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(DruidTLSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApplySpec)
+		**out = **in
+	}
 	if in.DNSConfig != nil {
 		in, out := &in.DNSConfig, &out.DNSConfig
 		*out = new(v1.PodDNSConfig)
@@ -740,10 +1303,168 @@ func (in *DruidSpec) DeepCopy() *DruidSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidTLSSpec) DeepCopyInto(out *DruidTLSSpec) {
+	*out = *in
+	if in.CertificateDuration != nil {
+		in, out := &in.CertificateDuration, &out.CertificateDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DNSNames != nil {
+		in, out := &in.DNSNames, &out.DNSNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidTLSSpec.
+func (in *DruidTLSSpec) DeepCopy() *DruidTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidUser) DeepCopyInto(out *DruidUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidUser.
+func (in *DruidUser) DeepCopy() *DruidUser {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidUserList) DeepCopyInto(out *DruidUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DruidUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidUserList.
+func (in *DruidUserList) DeepCopy() *DruidUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DruidUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidUserSpec) DeepCopyInto(out *DruidUserSpec) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidUserSpec.
+func (in *DruidUserSpec) DeepCopy() *DruidUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DruidUserStatus) DeepCopyInto(out *DruidUserStatus) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DruidUserStatus.
+func (in *DruidUserStatus) DeepCopy() *DruidUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DruidUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HPAutoScalerReplicas) DeepCopyInto(out *HPAutoScalerReplicas) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HPAutoScalerReplicas.
+func (in *HPAutoScalerReplicas) DeepCopy() *HPAutoScalerReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(HPAutoScalerReplicas)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngestionSpec) DeepCopyInto(out *IngestionSpec) {
 	*out = *in
 	in.NativeSpec.DeepCopyInto(&out.NativeSpec)
+	if in.SQL != nil {
+		in, out := &in.SQL, &out.SQL
+		*out = new(SQLSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Compaction.DeepCopyInto(&out.Compaction)
 	if in.Rules != nil {
 		in, out := &in.Rules, &out.Rules
@@ -784,6 +1505,159 @@ func (in *MetadataStoreSpec) DeepCopy() *MetadataStoreSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.InitialBackoff != nil {
+		in, out := &in.InitialBackoff, &out.InitialBackoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxBackoff != nil {
+		in, out := &in.MaxBackoff, &out.MaxBackoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SQLSpec) DeepCopyInto(out *SQLSpec) {
+	*out = *in
+	in.Context.DeepCopyInto(&out.Context)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SQLSpec.
+func (in *SQLSpec) DeepCopy() *SQLSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SQLSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSideApplySpec) DeepCopyInto(out *ServerSideApplySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSideApplySpec.
+func (in *ServerSideApplySpec) DeepCopy() *ServerSideApplySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSideApplySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SupervisorAction) DeepCopyInto(out *SupervisorAction) {
+	*out = *in
+	if in.PartitionOffsets != nil {
+		in, out := &in.PartitionOffsets, &out.PartitionOffsets
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TaskGroupIds != nil {
+		in, out := &in.TaskGroupIds, &out.TaskGroupIds
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupervisorAction.
+func (in *SupervisorAction) DeepCopy() *SupervisorAction {
+	if in == nil {
+		return nil
+	}
+	out := new(SupervisorAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SupervisorActionStatus) DeepCopyInto(out *SupervisorActionStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupervisorActionStatus.
+func (in *SupervisorActionStatus) DeepCopy() *SupervisorActionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SupervisorActionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskSlotAutoScalerSpec) DeepCopyInto(out *TaskSlotAutoScalerSpec) {
+	*out = *in
+	if in.ScaleUpCooldown != nil {
+		in, out := &in.ScaleUpCooldown, &out.ScaleUpCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ScaleDownCooldown != nil {
+		in, out := &in.ScaleDownCooldown, &out.ScaleDownCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PollPeriod != nil {
+		in, out := &in.PollPeriod, &out.PollPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSlotAutoScalerSpec.
+func (in *TaskSlotAutoScalerSpec) DeepCopy() *TaskSlotAutoScalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskSlotAutoScalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TaskSlotAutoScalerStatus) DeepCopyInto(out *TaskSlotAutoScalerStatus) {
+	*out = *in
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DrainingWorkers != nil {
+		in, out := &in.DrainingWorkers, &out.DrainingWorkers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TaskSlotAutoScalerStatus.
+func (in *TaskSlotAutoScalerStatus) DeepCopy() *TaskSlotAutoScalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TaskSlotAutoScalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ZookeeperSpec) DeepCopyInto(out *ZookeeperSpec) {
 	*out = *in
@@ -0,0 +1,68 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package datasource
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+)
+
+// DruidDataSourceReconciler manages the rules, compaction config and segments of a dataSource.
+type DruidDataSourceReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// reconcile time duration, defaults to 10s
+	ReconcileWait time.Duration
+	Recorder      record.EventRecorder
+}
+
+func NewDruidDataSourceReconciler(mgr ctrl.Manager) *DruidDataSourceReconciler {
+	return &DruidDataSourceReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("DataSource"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("druid-datasource"),
+	}
+}
+
+// +kubebuilder:rbac:groups=druid.apache.org,resources=druiddatasources,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=druid.apache.org,resources=druiddatasources/status,verbs=get;update;patch
+func (r *DruidDataSourceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	druidDataSourceCR := &v1alpha1.DruidDataSource{}
+	return util.ReconcileObject(ctx, r.Client, req, druidDataSourceCR, func(ctx context.Context) error {
+		return r.do(ctx, druidDataSourceCR)
+	})
+}
+
+func (r *DruidDataSourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.DruidDataSource{}).
+		Complete(r)
+}
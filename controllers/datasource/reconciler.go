@@ -0,0 +1,445 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	DruidDataSourceControllerUpdateSuccess  = "DruidDataSourceControllerUpdateSuccess"
+	DruidDataSourceControllerUpdateFail     = "DruidDataSourceControllerUpdateFail"
+	DruidDataSourceControllerKillSuccess    = "DruidDataSourceControllerKillSuccess"
+	DruidDataSourceControllerKillFail       = "DruidDataSourceControllerKillFail"
+	DruidDataSourceControllerCleanupSuccess = "DruidDataSourceControllerCleanupSuccess"
+	DruidDataSourceControllerCleanupFail    = "DruidDataSourceControllerCleanupFail"
+	DruidDataSourceControllerFinalizer      = "druiddatasource.datainfra.io/finalizer"
+)
+
+func (r *DruidDataSourceReconciler) do(ctx context.Context, dds *v1alpha1.DruidDataSource) error {
+	auth, err := druidapi.GetAuth(
+		ctx,
+		r.Client,
+		dds.Namespace,
+		dds.Spec.Auth,
+	)
+	if err != nil {
+		return err
+	}
+
+	svcName, err := druidapi.GetRouterSvcUrl(dds.Namespace, dds.Spec.DruidClusterName, "", druidapi.CoordinatorAPI, dds.Spec.Auth.TLS != nil, r.Client)
+	if err != nil {
+		return err
+	}
+
+	overlordSvcName, err := druidapi.GetRouterSvcUrl(dds.Namespace, dds.Spec.DruidClusterName, "", druidapi.IndexerAPI, dds.Spec.Auth.TLS != nil, r.Client)
+	if err != nil {
+		return err
+	}
+
+	build := builder.NewBuilder(
+		builder.ToNewBuilderRecorder(builder.BuilderRecorder{Recorder: r.Recorder, ControllerName: "DruidDataSourceController"}),
+	)
+
+	if dds.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(dds, DruidDataSourceControllerFinalizer) {
+			controllerutil.AddFinalizer(dds, DruidDataSourceControllerFinalizer)
+			if err := r.Update(ctx, dds.DeepCopyObject().(*v1alpha1.DruidDataSource)); err != nil {
+				return err
+			}
+		}
+		return r.Sync(dds, svcName, overlordSvcName, *build, auth)
+	}
+
+	if controllerutil.ContainsFinalizer(dds, DruidDataSourceControllerFinalizer) {
+		if err := r.Cleanup(dds, svcName, *build, auth); err != nil {
+			return err
+		}
+
+		controllerutil.RemoveFinalizer(dds, DruidDataSourceControllerFinalizer)
+		if err := r.Update(ctx, dds.DeepCopyObject().(*v1alpha1.DruidDataSource)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getDataSource returns the name of the dataSource in Druid, the name of the DruidDataSource by default.
+func getDataSource(dds *v1alpha1.DruidDataSource) string {
+	if dds.Spec.DataSource != "" {
+		return dds.Spec.DataSource
+	}
+	return dds.Name
+}
+
+// getRules returns the rules of the DruidDataSource as a slice of maps.
+func getRules(dds *v1alpha1.DruidDataSource) ([]map[string]interface{}, error) {
+	rules := make([]map[string]interface{}, 0, len(dds.Spec.Rules))
+	for _, rule := range dds.Spec.Rules {
+		var ruleMap map[string]interface{}
+		if err := json.Unmarshal(rule.Raw, &ruleMap); err != nil {
+			return nil, fmt.Errorf("error unmarshalling rule: %v", err)
+		}
+		rules = append(rules, ruleMap)
+	}
+	return rules, nil
+}
+
+// getCompactionJson returns the compaction config of the DruidDataSource, with its dataSource set.
+func getCompactionJson(dds *v1alpha1.DruidDataSource) (string, error) {
+	compaction := make(map[string]interface{})
+	if err := json.Unmarshal(dds.Spec.Compaction.Raw, &compaction); err != nil {
+		return "", fmt.Errorf("error unmarshalling compaction: %v", err)
+	}
+	compaction["dataSource"] = getDataSource(dds)
+	return util.ToJsonString(compaction)
+}
+
+// Sync applies the rules and compaction config of the DruidDataSource, runs its kill task and reports
+// the outcome in the Synced condition. The kill task is submitted to overlordSvcName.
+func (r *DruidDataSourceReconciler) Sync(
+	dds *v1alpha1.DruidDataSource,
+	svcName string,
+	overlordSvcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	dataSource := getDataSource(dds)
+
+	rulesUpdated, rulesErr := r.UpdateRules(dds, svcName, auth)
+	compaction, compactionUpdated, compactionErr := r.UpdateCompaction(dds, svcName, auth)
+	syncErr := errors.Join(rulesErr, compactionErr)
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, dds, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidDataSource)
+		in.Status.DataSource = dataSource
+		if rulesErr == nil {
+			in.Status.CurrentRules = dds.Spec.Rules
+		}
+		if compactionErr == nil {
+			in.Status.CurrentCompaction = compaction
+		}
+
+		condition := metav1.Condition{
+			Type:               v1alpha1.DruidDataSourceConditionSynced,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Synced",
+			Message:            "rules and compaction are applied",
+			ObservedGeneration: in.Generation,
+		}
+		if syncErr != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SyncFailed"
+			condition.Message = syncErr.Error()
+		}
+		if rulesUpdated || compactionUpdated || syncErr != nil {
+			in.Status.Reason = condition.Reason
+			in.Status.Message = condition.Message
+			in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		}
+		meta.SetStatusCondition(&in.Status.Conditions, condition)
+		return in
+	}); err != nil {
+		return err
+	}
+
+	if syncErr != nil {
+		build.Recorder.GenericEvent(dds, v1.EventTypeWarning, syncErr.Error(), DruidDataSourceControllerUpdateFail)
+		return syncErr
+	}
+	if rulesUpdated {
+		build.Recorder.GenericEvent(dds, v1.EventTypeNormal, "rules updated", DruidDataSourceControllerUpdateSuccess)
+	}
+	if compactionUpdated {
+		build.Recorder.GenericEvent(dds, v1.EventTypeNormal, "compaction updated", DruidDataSourceControllerUpdateSuccess)
+	}
+
+	return r.RunKill(dds, overlordSvcName, build, auth)
+}
+
+// UpdateRules sets the rules of the dataSource when they changed since last applied.
+func (r *DruidDataSourceReconciler) UpdateRules(
+	dds *v1alpha1.DruidDataSource,
+	svcName string,
+	auth internalhttp.Auth,
+) (bool, error) {
+	dataSource := getDataSource(dds)
+	if len(dds.Spec.Rules) == 0 ||
+		(dds.Status.DataSource == dataSource && reflect.DeepEqual(dds.Status.CurrentRules, dds.Spec.Rules)) {
+		return false, nil
+	}
+
+	rules, err := getRules(dds)
+	if err != nil {
+		return false, err
+	}
+	if err := druidapi.ValidateRules(rules); err != nil {
+		return false, err
+	}
+	rulesData, err := util.ToJsonString(rules)
+	if err != nil {
+		return false, err
+	}
+
+	postHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	respUpdateRules, err := postHttp.Do(
+		http.MethodPost,
+		druidapi.MakePath(svcName, "coordinator", "rules", dataSource),
+		[]byte(rulesData),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if respUpdateRules.StatusCode == 200 {
+		return true, nil
+	}
+
+	return false, fmt.Errorf("failed to update rules, status code: %d, response body: %s", respUpdateRules.StatusCode, respUpdateRules.ResponseBody)
+}
+
+// UpdateCompaction sets the auto-compaction config of the dataSource when it differs from the one on the cluster.
+// It returns the applied compaction config.
+func (r *DruidDataSourceReconciler) UpdateCompaction(
+	dds *v1alpha1.DruidDataSource,
+	svcName string,
+	auth internalhttp.Auth,
+) (string, bool, error) {
+	if dds.Spec.Compaction.Size() == 0 {
+		return "", false, nil
+	}
+
+	desiredCompactionJson, err := getCompactionJson(dds)
+	if err != nil {
+		return "", false, err
+	}
+
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	// Get current compaction settings
+	currentResp, err := httpClient.Do(
+		http.MethodGet,
+		druidapi.MakePath(svcName, "coordinator", "config", "compaction", getDataSource(dds)),
+		nil,
+	)
+	if err != nil {
+		return "", false, err
+	}
+
+	var currentCompactionJson string
+	if currentResp.StatusCode == http.StatusOK {
+		currentCompactionJson = currentResp.ResponseBody
+	} else if currentResp.StatusCode == http.StatusNotFound {
+		// Assume no compaction settings are currently set
+		currentCompactionJson = "{}"
+	} else {
+		return "", false, fmt.Errorf("failed to retrieve current compaction settings, status code: %d", currentResp.StatusCode)
+	}
+
+	if areEqual, err := util.IncludesJson(currentCompactionJson, desiredCompactionJson); err != nil {
+		return "", false, err
+	} else if areEqual {
+		return desiredCompactionJson, false, nil
+	}
+
+	respUpdateCompaction, err := httpClient.Do(
+		http.MethodPost,
+		druidapi.MakePath(svcName, "coordinator", "config", "compaction"),
+		[]byte(desiredCompactionJson),
+	)
+	if err != nil {
+		return "", false, err
+	}
+
+	if respUpdateCompaction.StatusCode == 200 {
+		return desiredCompactionJson, true, nil
+	}
+
+	return "", false, fmt.Errorf(
+		"failed to update compaction, status code: %d, response body: %s",
+		respUpdateCompaction.StatusCode, respUpdateCompaction.ResponseBody)
+}
+
+// isKillPending returns true when spec.kill has not been run for its generation yet.
+func isKillPending(dds *v1alpha1.DruidDataSource) bool {
+	if dds.Spec.Kill == nil {
+		return false
+	}
+	last := dds.Status.LastKill
+	return last == nil || last.Generation != dds.Spec.Kill.Generation || last.Interval != dds.Spec.Kill.Interval
+}
+
+// RunKill submits a kill task deleting the unused segments of spec.kill.interval, once per generation,
+// and records its outcome in status.lastKill. A failed submission is not retried until the generation is bumped.
+func (r *DruidDataSourceReconciler) RunKill(
+	dds *v1alpha1.DruidDataSource,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	if !isKillPending(dds) {
+		return nil
+	}
+
+	kill := dds.Spec.Kill
+	killTask, err := util.ToJsonString(map[string]interface{}{
+		"type":       "kill",
+		"dataSource": getDataSource(dds),
+		"interval":   kill.Interval,
+	})
+	if err != nil {
+		return err
+	}
+
+	postHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	resp, err := postHttp.Do(http.MethodPost, druidapi.MakePath(svcName, "indexer", "task"), []byte(killTask))
+	if err != nil {
+		return err
+	}
+
+	var task struct {
+		Task string `json:"task"`
+	}
+	succeeded := resp.StatusCode == 200 && json.Unmarshal([]byte(resp.ResponseBody), &task) == nil
+	msg := fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode)
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, dds, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidDataSource)
+		in.Status.LastKill = &v1alpha1.DataSourceKillStatus{
+			Interval:   kill.Interval,
+			Generation: kill.Generation,
+			Succeeded:  succeeded,
+			TaskId:     task.Task,
+			Message:    msg,
+			Time:       metav1.Time{Time: time.Now()},
+		}
+		return in
+	}); err != nil {
+		return err
+	}
+
+	eventMsg := fmt.Sprintf("kill of interval [%s] generation [%d]: %s", kill.Interval, kill.Generation, msg)
+	if succeeded {
+		build.Recorder.GenericEvent(dds, v1.EventTypeNormal, eventMsg, DruidDataSourceControllerKillSuccess)
+	} else {
+		build.Recorder.GenericEvent(dds, v1.EventTypeWarning, eventMsg, DruidDataSourceControllerKillFail)
+	}
+	return nil
+}
+
+// Cleanup runs the cleanup selected in spec.onDelete on the dataSource. A failed cleanup is reported in an
+// event and does not block the deletion.
+func (r *DruidDataSourceReconciler) Cleanup(
+	dds *v1alpha1.DruidDataSource,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	policy := dds.Spec.OnDelete
+	if policy == nil {
+		return nil
+	}
+
+	dataSource := getDataSource(dds)
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	type cleanup struct {
+		enabled bool
+		method  string
+		path    string
+		body    []byte
+		msg     string
+	}
+	cleanups := []cleanup{
+		{
+			// an empty list of rules falls back to the cluster default rules
+			enabled: policy.ResetRules,
+			method:  http.MethodPost,
+			path:    druidapi.MakePath(svcName, "coordinator", "rules", dataSource),
+			body:    []byte("[]"),
+			msg:     fmt.Sprintf("rules of dataSource [%s] reset", dataSource),
+		},
+		{
+			enabled: policy.DeleteCompaction,
+			method:  http.MethodDelete,
+			path:    druidapi.MakePath(svcName, "coordinator", "config", "compaction", dataSource),
+			msg:     fmt.Sprintf("compaction config of dataSource [%s] deleted", dataSource),
+		},
+		{
+			enabled: policy.MarkSegmentsUnused,
+			method:  http.MethodDelete,
+			path:    druidapi.MakePath(svcName, "coordinator", "datasources", dataSource),
+			msg:     fmt.Sprintf("segments of dataSource [%s] marked unused", dataSource),
+		},
+	}
+
+	for _, c := range cleanups {
+		if !c.enabled {
+			continue
+		}
+		resp, err := httpClient.Do(c.method, c.path, c.body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound {
+			build.Recorder.GenericEvent(dds, v1.EventTypeNormal, c.msg, DruidDataSourceControllerCleanupSuccess)
+		} else {
+			build.Recorder.GenericEvent(
+				dds,
+				v1.EventTypeWarning,
+				fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode),
+				DruidDataSourceControllerCleanupFail,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package datasource
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestReconciler(t *testing.T, objs ...client.Object) *DruidDataSourceReconciler {
+	return &DruidDataSourceReconciler{
+		Client:   testutil.NewFakeClient(t, objs...),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+// druidStub is a stand-in for the coordinator and overlord APIs, it records the calls and their bodies.
+type druidStub struct {
+	calls      []string
+	compaction string
+	statusCode int
+}
+
+func (s *druidStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	call := r.Method + " " + r.URL.Path
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		call += " " + string(body)
+	}
+	s.calls = append(s.calls, call)
+
+	if s.statusCode != 0 {
+		w.WriteHeader(s.statusCode)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/druid/coordinator/v1/config/compaction/wikipedia":
+		if s.compaction == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(s.compaction))
+	case r.URL.Path == "/druid/indexer/v1/task":
+		_, _ = w.Write([]byte(`{"task": "kill_wikipedia_1"}`))
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func TestSync(t *testing.T) {
+	stub := &druidStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dds := &v1alpha1.DruidDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "wikipedia", Namespace: "default"},
+		Spec: v1alpha1.DruidDataSourceSpec{
+			DruidClusterName: "tiny-cluster",
+			Rules: []runtime.RawExtension{
+				{Raw: []byte(`{"type": "loadByPeriod", "period": "P7D", "tieredReplicants": {"_default_tier": 1}}`)},
+				{Raw: []byte(`{"type": "dropForever"}`)},
+			},
+			Compaction: runtime.RawExtension{Raw: []byte(`{"skipOffsetFromLatest": "PT1H"}`)},
+		},
+	}
+
+	r := newTestReconciler(t, dds)
+	build := testutil.NewBuilder(r.Recorder, "DruidDataSourceController")
+
+	assert.NoError(t, r.Sync(dds, server.URL, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		`POST /druid/coordinator/v1/rules/wikipedia [{"period":"P7D","tieredReplicants":{"_default_tier":1},"type":"loadByPeriod"},{"type":"dropForever"}]`,
+		"GET /druid/coordinator/v1/config/compaction/wikipedia",
+		`POST /druid/coordinator/v1/config/compaction {"dataSource":"wikipedia","skipOffsetFromLatest":"PT1H"}`,
+	}, stub.calls)
+
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dds), dds))
+	assert.Equal(t, "wikipedia", dds.Status.DataSource)
+	assert.Len(t, dds.Status.CurrentRules, 2)
+	assert.True(t, meta.IsStatusConditionTrue(dds.Status.Conditions, v1alpha1.DruidDataSourceConditionSynced))
+
+	// nothing changed, only the compaction config on the cluster is read
+	stub.calls = nil
+	stub.compaction = `{"dataSource": "wikipedia", "skipOffsetFromLatest": "PT1H", "taskPriority": 25}`
+	assert.NoError(t, r.Sync(dds, server.URL, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{"GET /druid/coordinator/v1/config/compaction/wikipedia"}, stub.calls)
+}
+
+func TestSyncFailure(t *testing.T) {
+	stub := &druidStub{statusCode: http.StatusInternalServerError}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dds := &v1alpha1.DruidDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "wiki", Namespace: "default"},
+		Spec: v1alpha1.DruidDataSourceSpec{
+			DruidClusterName: "tiny-cluster",
+			DataSource:       "wikipedia",
+			Rules:            []runtime.RawExtension{{Raw: []byte(`{"type": "dropForever"}`)}},
+		},
+	}
+
+	r := newTestReconciler(t, dds)
+	build := testutil.NewBuilder(r.Recorder, "DruidDataSourceController")
+
+	assert.Error(t, r.Sync(dds, server.URL, server.URL, build, internalhttp.Auth{}))
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dds), dds))
+	assert.Empty(t, dds.Status.CurrentRules)
+	assert.True(t, meta.IsStatusConditionFalse(dds.Status.Conditions, v1alpha1.DruidDataSourceConditionSynced))
+	assert.Contains(t, dds.Status.Message, "failed to update rules")
+}
+
+func TestSyncInvalidRules(t *testing.T) {
+	stub := &druidStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dds := &v1alpha1.DruidDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "wikipedia", Namespace: "default"},
+		Spec: v1alpha1.DruidDataSourceSpec{
+			DruidClusterName: "tiny-cluster",
+			Rules:            []runtime.RawExtension{{Raw: []byte(`{"type": "loadByPeriod"}`)}},
+		},
+	}
+
+	r := newTestReconciler(t, dds)
+	build := testutil.NewBuilder(r.Recorder, "DruidDataSourceController")
+
+	assert.Error(t, r.Sync(dds, server.URL, server.URL, build, internalhttp.Auth{}))
+	assert.Empty(t, stub.calls)
+}
+
+func TestRunKill(t *testing.T) {
+	stub := &druidStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dds := &v1alpha1.DruidDataSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "wikipedia", Namespace: "default"},
+		Spec: v1alpha1.DruidDataSourceSpec{
+			DruidClusterName: "tiny-cluster",
+			Kill:             &v1alpha1.DataSourceKill{Interval: "2015-09-12/2015-09-13", Generation: 1},
+		},
+	}
+
+	r := newTestReconciler(t, dds)
+	build := testutil.NewBuilder(r.Recorder, "DruidDataSourceController")
+
+	assert.NoError(t, r.RunKill(dds, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		`POST /druid/indexer/v1/task {"dataSource":"wikipedia","interval":"2015-09-12/2015-09-13","type":"kill"}`,
+	}, stub.calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dds), dds))
+	assert.True(t, dds.Status.LastKill.Succeeded)
+	assert.Equal(t, "kill_wikipedia_1", dds.Status.LastKill.TaskId)
+
+	// the kill runs once per generation
+	assert.NoError(t, r.RunKill(dds, server.URL, build, internalhttp.Auth{}))
+	assert.Len(t, stub.calls, 1)
+
+	dds.Spec.Kill.Generation = 2
+	assert.NoError(t, r.RunKill(dds, server.URL, build, internalhttp.Auth{}))
+	assert.Len(t, stub.calls, 2)
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name          string
+		onDelete      *v1alpha1.DataSourceOnDelete
+		expectedCalls []string
+	}{
+		{
+			name: "no cleanup",
+		},
+		{
+			name: "all cleanups",
+			onDelete: &v1alpha1.DataSourceOnDelete{
+				ResetRules:         true,
+				DeleteCompaction:   true,
+				MarkSegmentsUnused: true,
+			},
+			expectedCalls: []string{
+				"POST /druid/coordinator/v1/rules/wikipedia []",
+				"DELETE /druid/coordinator/v1/config/compaction/wikipedia",
+				"DELETE /druid/coordinator/v1/datasources/wikipedia",
+			},
+		},
+		{
+			name:     "compaction only",
+			onDelete: &v1alpha1.DataSourceOnDelete{DeleteCompaction: true},
+			expectedCalls: []string{
+				"DELETE /druid/coordinator/v1/config/compaction/wikipedia",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &druidStub{}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			dds := &v1alpha1.DruidDataSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "wikipedia", Namespace: "default"},
+				Spec: v1alpha1.DruidDataSourceSpec{
+					DruidClusterName: "tiny-cluster",
+					OnDelete:         tt.onDelete,
+				},
+			}
+
+			r := newTestReconciler(t, dds)
+			build := testutil.NewBuilder(r.Recorder, "DruidDataSourceController")
+
+			assert.NoError(t, r.Cleanup(dds, server.URL, build, internalhttp.Auth{}))
+			assert.Equal(t, tt.expectedCalls, stub.calls)
+		})
+	}
+}
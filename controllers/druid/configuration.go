@@ -21,6 +21,8 @@ package druid
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
 	v1 "k8s.io/api/core/v1"
@@ -44,12 +46,111 @@ func makeConfigMap(name string, namespace string, labels map[string]string, data
 	}, nil
 }
 
-func makeCommonConfigMap(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, ls map[string]string) (*v1.ConfigMap, error) {
-	prop := m.Spec.CommonRuntimeProperties
+func makeSecret(name, namespace string, labels map[string]string, data map[string][]byte) *v1.Secret {
+	return &v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Data: data,
+	}
+}
+
+func getSecret(ctx context.Context, sdk client.Client, name, namespace string) (*v1.Secret, error) {
+	secret := &v1.Secret{}
+	if err := sdk.Get(ctx, *namespacedName(name, namespace), secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// propertyReference matches the ${secret:name/key} and ${configmap:name/key} references of the runtime properties,
+// resolved from the secrets and configmaps of the namespace of the Druid CR.
+var propertyReference = regexp.MustCompile(`\$\{(secret|configmap):([a-z0-9]([-a-z0-9.]*[a-z0-9])?)/([-._a-zA-Z0-9]+)\}`)
+
+// hasSecretReference returns true when the properties reference a secret. Such properties are written to a
+// secret instead of a configmap.
+func hasSecretReference(properties string) bool {
+	for _, match := range propertyReference.FindAllStringSubmatch(properties, -1) {
+		if match[1] == "secret" {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyValueEscaper escapes the characters of the resolved values which the java properties format would
+// otherwise read as a line break or an escape, such as the trailing newline of a secret created from a file.
+var propertyValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "\f", `\f`)
+
+// resolveProperties replaces the references of the properties by the values they reference, escaped for the java
+// properties format.
+func resolveProperties(ctx context.Context, sdk client.Client, namespace, properties string) (string, error) {
+	var resolveErr error
+	resolved := propertyReference.ReplaceAllStringFunc(properties, func(reference string) string {
+		match := propertyReference.FindStringSubmatch(reference)
+		kind, name, key := match[1], match[2], match[4]
+		if resolveErr != nil {
+			return reference
+		}
+
+		if kind == "secret" {
+			secret, err := getSecret(ctx, sdk, name, namespace)
+			if err != nil {
+				resolveErr = fmt.Errorf("failed to resolve [%s]: %v", reference, err)
+				return reference
+			}
+			value, ok := secret.Data[key]
+			if !ok {
+				resolveErr = fmt.Errorf("failed to resolve [%s]: key %q not found in secret %s/%s", reference, key, namespace, name)
+				return reference
+			}
+			return propertyValueEscaper.Replace(string(value))
+		}
+
+		cm := &v1.ConfigMap{}
+		if err := sdk.Get(ctx, *namespacedName(name, namespace), cm); err != nil {
+			resolveErr = fmt.Errorf("failed to resolve [%s]: %v", reference, err)
+			return reference
+		}
+		value, ok := cm.Data[key]
+		if !ok {
+			resolveErr = fmt.Errorf("failed to resolve [%s]: key %q not found in configmap %s/%s", reference, key, namespace, name)
+			return reference
+		}
+		return propertyValueEscaper.Replace(value)
+	})
+	return resolved, resolveErr
+}
+
+// makeLabelsForConfigSecret returns the labels of the secrets holding the properties which reference secrets,
+// the unused ones are deleted by them.
+func makeLabelsForConfigSecret(m *v1alpha1.Druid) map[string]string {
+	labels := makeLabelsForDruid(m)
+	labels["component"] = "config"
+	return labels
+}
+
+// makeCommonConfigMap returns the common configmap and, when the common runtime properties reference a secret,
+// the secret holding common.runtime.properties in its place.
+func makeCommonConfigMap(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, ls map[string]string) (*v1.ConfigMap, *v1.Secret, error) {
+	prop, err := resolveProperties(ctx, sdk, m.Namespace, m.Spec.CommonRuntimeProperties)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.Spec.TLS != nil {
+		prop = makeTLSProperties(m) + "\n" + prop
+	}
 
 	if m.Spec.Zookeeper != nil {
 		if zm, err := createZookeeperManager(m.Spec.Zookeeper); err != nil {
-			return nil, err
+			return nil, nil, err
 		} else {
 			prop = prop + "\n" + zm.Configuration() + "\n"
 		}
@@ -57,7 +158,7 @@ func makeCommonConfigMap(ctx context.Context, sdk client.Client, m *v1alpha1.Dru
 
 	if m.Spec.MetadataStore != nil {
 		if msm, err := createMetadataStoreManager(m.Spec.MetadataStore); err != nil {
-			return nil, err
+			return nil, nil, err
 		} else {
 			prop = prop + "\n" + msm.Configuration() + "\n"
 		}
@@ -65,7 +166,7 @@ func makeCommonConfigMap(ctx context.Context, sdk client.Client, m *v1alpha1.Dru
 
 	if m.Spec.DeepStorage != nil {
 		if dsm, err := createDeepStorageManager(m.Spec.DeepStorage); err != nil {
-			return nil, err
+			return nil, nil, err
 		} else {
 			prop = prop + "\n" + dsm.Configuration() + "\n"
 		}
@@ -86,15 +187,24 @@ func makeCommonConfigMap(ctx context.Context, sdk client.Client, m *v1alpha1.Dru
 	}
 
 	if err := addExtraCommonConfig(ctx, sdk, m, data); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	name := getCommonConfigName(m)
+	var secret *v1.Secret
+	if hasSecretReference(m.Spec.CommonRuntimeProperties) {
+		secret = makeSecret(name, m.Namespace, makeLabelsForConfigSecret(m), map[string][]byte{
+			"common.runtime.properties": []byte(data["common.runtime.properties"]),
+		})
+		delete(data, "common.runtime.properties")
 	}
 
 	cfg, err := makeConfigMap(
-		fmt.Sprintf("%s-druid-common-config", m.ObjectMeta.Name),
+		name,
 		m.Namespace,
 		ls,
 		data)
-	return cfg, err
+	return cfg, secret, err
 }
 
 func addExtraCommonConfig(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, data map[string]string) error {
@@ -103,11 +213,15 @@ func addExtraCommonConfig(ctx context.Context, sdk client.Client, m *v1alpha1.Dr
 	}
 
 	for _, cmRef := range m.Spec.ExtraCommonConfig {
+		if cmRef == nil {
+			continue
+		}
 		cm := &v1.ConfigMap{}
 		if err := sdk.Get(ctx, types.NamespacedName{
 			Name:      cmRef.Name,
-			Namespace: cmRef.Namespace}, cm); err != nil {
-			// If a configMap is not found - output error and keep reconciliation
+			Namespace: firstNonEmptyStr(cmRef.Namespace, m.Namespace)}, cm); err != nil {
+			// If a configMap is not found, it's reported by the ReferencesResolved condition and the
+			// reconciliation goes on
 			continue
 		}
 
@@ -119,10 +233,16 @@ func addExtraCommonConfig(ctx context.Context, sdk client.Client, m *v1alpha1.Dr
 	return nil
 }
 
-func makeConfigMapForNodeSpec(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, lm map[string]string, nodeSpecUniqueStr string) (*v1.ConfigMap, error) {
+// makeConfigMapForNodeSpec returns the configmap of a node spec and, when its runtime properties reference a
+// secret, the secret holding runtime.properties in its place.
+func makeConfigMapForNodeSpec(ctx context.Context, sdk client.Client, nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, lm map[string]string, nodeSpecUniqueStr string) (*v1.ConfigMap, *v1.Secret, error) {
+	prop, err := resolveProperties(ctx, sdk, m.Namespace, nodeSpec.RuntimeProperties)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	data := map[string]string{
-		"runtime.properties": fmt.Sprintf("druid.port=%d\n%s", nodeSpec.DruidPort, nodeSpec.RuntimeProperties),
+		"runtime.properties": fmt.Sprintf("druid.port=%d\n%s", nodeSpec.DruidPort, prop),
 		"jvm.config":         fmt.Sprintf("%s\n%s", firstNonEmptyStr(nodeSpec.JvmOptions, m.Spec.JvmOptions), nodeSpec.ExtraJvmOptions),
 	}
 	log4jconfig := firstNonEmptyStr(nodeSpec.Log4jConfig, m.Spec.Log4jConfig)
@@ -130,11 +250,51 @@ func makeConfigMapForNodeSpec(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Drui
 		data["log4j2.xml"] = log4jconfig
 	}
 
-	return makeConfigMap(
-		fmt.Sprintf("%s-config", nodeSpecUniqueStr),
+	name := getNodeConfigName(nodeSpecUniqueStr)
+	var secret *v1.Secret
+	if hasSecretReference(nodeSpec.RuntimeProperties) {
+		labels := makeLabelsForConfigSecret(m)
+		labels["nodeSpecUniqueStr"] = nodeSpecUniqueStr
+		secret = makeSecret(name, m.Namespace, labels, map[string][]byte{
+			"runtime.properties": []byte(data["runtime.properties"]),
+		})
+		delete(data, "runtime.properties")
+	}
+
+	cfg, err := makeConfigMap(
+		name,
 		m.Namespace,
 		lm,
 		data)
+	return cfg, secret, err
+}
+
+func getCommonConfigName(m *v1alpha1.Druid) string {
+	return fmt.Sprintf("%s-druid-common-config", m.ObjectMeta.Name)
+}
+
+func getNodeConfigName(nodeSpecUniqueStr string) string {
+	return fmt.Sprintf("%s-config", nodeSpecUniqueStr)
+}
+
+// getConfigVolumeSource returns the source of a config volume: the configmap, projected with the secret of the
+// same name holding the properties when they reference a secret.
+func getConfigVolumeSource(name string, sensitive bool) v1.VolumeSource {
+	if !sensitive {
+		return v1.VolumeSource{
+			ConfigMap: &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{Name: name},
+			},
+		}
+	}
+	return v1.VolumeSource{
+		Projected: &v1.ProjectedVolumeSource{
+			Sources: []v1.VolumeProjection{
+				{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: v1.LocalObjectReference{Name: name}}},
+				{Secret: &v1.SecretProjection{LocalObjectReference: v1.LocalObjectReference{Name: name}}},
+			},
+		},
+	}
 }
 
 func getNodeConfigMountPath(nodeSpec *v1alpha1.DruidNodeSpec) string {
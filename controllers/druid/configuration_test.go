@@ -0,0 +1,151 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMakeConfigMapForNodeSpecWithReferences(t *testing.T) {
+	drd := &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid"},
+	}
+	sdk := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "metadata", Namespace: "druid"},
+			Data:       map[string][]byte{"password": []byte("s3cr3t"), "multiline": []byte("s3c\\r3t\nx=1\n")},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "druid"},
+			Data:       map[string]string{"threads": "4", "multiline": "4\r\ny=2"},
+		},
+	).Build()
+	ctx := context.TODO()
+	nodeSpecUniqueStr := "druid-tiny-cluster-brokers"
+
+	tests := []struct {
+		name           string
+		properties     string
+		expectErr      bool
+		expectSecret   bool
+		expectResolved string
+	}{
+		{
+			name:           "configmap reference",
+			properties:     "druid.processing.numThreads=${configmap:settings/threads}",
+			expectResolved: "druid.processing.numThreads=4",
+		},
+		{
+			name:           "secret reference",
+			properties:     "druid.metadata.storage.connector.password=${secret:metadata/password}\ndruid.processing.numThreads=${configmap:settings/threads}",
+			expectSecret:   true,
+			expectResolved: "druid.metadata.storage.connector.password=s3cr3t\ndruid.processing.numThreads=4",
+		},
+		{
+			name:           "line breaks and backslashes are escaped",
+			properties:     "druid.metadata.storage.connector.password=${secret:metadata/multiline}\ndruid.processing.numThreads=${configmap:settings/multiline}",
+			expectSecret:   true,
+			expectResolved: `druid.metadata.storage.connector.password=s3c\\r3t\nx=1\n` + "\n" + `druid.processing.numThreads=4\r\ny=2`,
+		},
+		{
+			name:           "other placeholders are kept",
+			properties:     "druid.emitter.http.recipientBaseUrl=${env:EMITTER_URL}",
+			expectResolved: "druid.emitter.http.recipientBaseUrl=${env:EMITTER_URL}",
+		},
+		{
+			name:       "missing key",
+			properties: "druid.metadata.storage.connector.password=${secret:metadata/missing}",
+			expectErr:  true,
+		},
+		{
+			name:       "missing secret",
+			properties: "druid.metadata.storage.connector.password=${secret:missing/password}",
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeSpec := &druidv1alpha1.DruidNodeSpec{NodeType: broker, DruidPort: 8088, RuntimeProperties: tc.properties}
+			cm, secret, err := makeConfigMapForNodeSpec(ctx, sdk, nodeSpec, drd, map[string]string{}, nodeSpecUniqueStr)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			expected := "druid.port=8088\n" + tc.expectResolved
+			if tc.expectSecret {
+				if secret == nil {
+					t.Fatalf("expected the properties to be written to a secret")
+				}
+				if _, ok := cm.Data["runtime.properties"]; ok {
+					t.Errorf("expected the properties not to be written to the configmap")
+				}
+				if actual := string(secret.Data["runtime.properties"]); actual != expected {
+					t.Errorf("expected properties %q, got %q", expected, actual)
+				}
+				if secret.Labels["component"] != "config" {
+					t.Errorf("expected the secret to be labelled as config, got %v", secret.Labels)
+				}
+				source := getConfigVolumeSource(getNodeConfigName(nodeSpecUniqueStr), hasSecretReference(nodeSpec.RuntimeProperties))
+				if source.Projected == nil || len(source.Projected.Sources) != 2 {
+					t.Errorf("expected the volume to project the configmap and the secret, got %+v", source)
+				}
+				return
+			}
+
+			if secret != nil {
+				t.Errorf("expected no secret, got %s", secret.Name)
+			}
+			if actual := cm.Data["runtime.properties"]; actual != expected {
+				t.Errorf("expected properties %q, got %q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetConfigHashWithSecret(t *testing.T) {
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "druid-tiny-cluster-brokers-config"}}
+	secret := makeSecret("druid-tiny-cluster-brokers-config", "druid", nil, map[string][]byte{"runtime.properties": []byte("a=1")})
+
+	withoutSecret, err := getConfigHash(cm, nil)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	before, _ := getConfigHash(cm, secret)
+	secret.Data["runtime.properties"] = []byte("a=2")
+	after, _ := getConfigHash(cm, secret)
+
+	if !strings.HasPrefix(before, withoutSecret+"-") {
+		t.Errorf("expected the hash %q to extend the configmap hash %q", before, withoutSecret)
+	}
+	if before == after {
+		t.Errorf("expected the hash to change with the secret")
+	}
+}
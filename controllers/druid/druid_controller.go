@@ -23,14 +23,23 @@ import (
 	"os"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalev2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
@@ -41,7 +50,7 @@ type DruidReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
-	// reconcile time duration, defaults to 10s
+	// resync period of the Druid CRs, defaults to 10m
 	ReconcileWait time.Duration
 	Recorder      record.EventRecorder
 }
@@ -60,6 +69,7 @@ func NewDruidReconciler(mgr ctrl.Manager) *DruidReconciler {
 // +kubebuilder:rbac:groups=druid.apache.org,resources=druids/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;patch
@@ -87,6 +97,11 @@ func (r *DruidReconciler) Reconcile(ctx context.Context, request reconcile.Reque
 		return ctrl.Result{}, err
 	}
 
+	// The events of the pods and other resources are not filtered, an ignored or denied Druid is skipped here
+	if !IgnoreNamespacePredicate(instance) || !IgnoreIgnoredObjectPredicate(instance) {
+		return ctrl.Result{}, nil
+	}
+
 	// Initialize Emit Events
 	var emitEvent EventEmitter = EmitEventFuncs{r.Recorder}
 
@@ -100,33 +115,86 @@ func (r *DruidReconciler) Reconcile(ctx context.Context, request reconcile.Reque
 		return ctrl.Result{}, err
 	}
 
-	// If both operations succeed, requeue after specified wait time
-	return ctrl.Result{RequeueAfter: r.ReconcileWait}, nil
+	// Scale the workers with the task slots needed by the ingestion tasks
+	if err := autoScaleTaskSlots(ctx, r.Client, instance, emitEvent); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// If all operations succeed, requeue after the resync period, if any, or sooner to poll the overlord
+	requeueAfter := r.ReconcileWait
+	if period := getTaskSlotAutoScalerPollPeriod(instance); period > 0 && (requeueAfter == 0 || period < requeueAfter) {
+		requeueAfter = period
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *DruidReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	ctx := context.Background()
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &druidv1alpha1.Druid{}, configMapReferenceIndex, indexReferences(configMapReference)); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &druidv1alpha1.Druid{}, secretReferenceIndex, indexReferences(secretReference)); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&druidv1alpha1.Druid{}).
-		WithEventFilter(GenericPredicates{}).
+		For(&druidv1alpha1.Druid{}, builder.WithPredicates(GenericPredicates{})).
+		// correct the drift of the owned resources as soon as they change, rather than on the next resync
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&v1.Service{}).
+		Owns(&v1.ConfigMap{}, builder.OnlyMetadata).
+		Owns(&v1.Secret{}, builder.OnlyMetadata).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&autoscalev2.HorizontalPodAutoscaler{}).
+		Owns(&networkingv1.Ingress{}).
+		// the pods are owned by the workloads, they are mapped to their Druid CR by the druid_cr label
+		Watches(&v1.Pod{}, handler.EnqueueRequestsFromMapFunc(mapPodToDruid),
+			builder.WithPredicates(predicate.NewPredicateFuncs(hasDruidCRLabel))).
+		// reconcile the Druid CRs as soon as the configmaps and secrets they reference change, only their metadata
+		// is watched as the manager client reads their data from the API server, see util.NewClient
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(mapReferencedDruids(r.Client, configMapReferenceIndex)),
+			builder.OnlyMetadata).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(mapReferencedDruids(r.Client, secretReferenceIndex)),
+			builder.OnlyMetadata).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: getMaxConcurrentReconciles(),
 		}).
 		Complete(r)
 }
 
+// defaultResyncPeriod is the period of the resync of the Druid CRs when RESYNC_PERIOD is not set.
+const defaultResyncPeriod = 10 * time.Minute
+
+// LookupReconcileTime returns the period of the resync of the Druid CRs, which are otherwise reconciled as soon
+// as they or the resources they own change. It's read from RESYNC_PERIOD, where 0 disables the resync, and
+// defaults to 10m. RECONCILE_WAIT only sets the requeue of the other CRs.
 func LookupReconcileTime() time.Duration {
-	val, exists := os.LookupEnv("RECONCILE_WAIT")
+	val, exists := os.LookupEnv("RESYNC_PERIOD")
 	if !exists {
-		return time.Second * 10
-	} else {
-		v, err := time.ParseDuration(val)
-		if err != nil {
-			logger.Error(err, err.Error())
-			// Exit Program if not valid
-			os.Exit(1)
-		}
-		return v
+		return defaultResyncPeriod
+	}
+	v, err := time.ParseDuration(val)
+	if err != nil {
+		logger.Error(err, err.Error())
+		// Exit Program if not valid
+		os.Exit(1)
+	}
+	return v
+}
+
+// hasDruidCRLabel returns true for the objects labelled with the Druid CR they belong to.
+func hasDruidCRLabel(obj client.Object) bool {
+	return obj.GetLabels()["druid_cr"] != ""
+}
+
+// mapPodToDruid returns the request of the Druid CR of a pod, named by its druid_cr label.
+func mapPodToDruid(ctx context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()["druid_cr"]
+	if name == "" {
+		return nil
 	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: obj.GetNamespace()}}}
 }
 
 func getMaxConcurrentReconciles() int {
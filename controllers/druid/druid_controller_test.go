@@ -19,7 +19,11 @@ under the License.
 package druid
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"reflect"
+	"testing"
 	"time"
 
 	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
@@ -30,7 +34,17 @@ import (
 	v1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // +kubebuilder:docs-gen:collapse=Imports
@@ -164,11 +178,11 @@ var _ = Describe("Druid Operator", func() {
 				return druid.Spec.Nodes[componentName].Replicas == 2
 			}, timeout, interval).Should(BeTrue())
 
-			// Fetch deployment and check replicas
-			Eventually(func() bool {
+			// The brokers are scaled by an HPA, the deployment replicas are left to it
+			Consistently(func() bool {
 				k8sClient.Get(ctx, depNamespacedName, createdDeploy)
-				return *createdDeploy.Spec.Replicas == 2
-			}, timeout, interval).Should(BeTrue())
+				return *createdDeploy.Spec.Replicas == 1
+			}, time.Second*5, interval).Should(BeTrue())
 		})
 
 		// Test statefulsets replica count and update the replica count then match
@@ -312,6 +326,24 @@ var _ = Describe("Druid Operator", func() {
 
 			})
 		}
+		// The resync period defaults to 10m, the owned resources are corrected from their own events
+		It("Test deleted service is recreated before the resync", func() {
+			serviceName := fmt.Sprintf("druid-%s-brokers", druidCR.Name)
+			serviceNamespacedName := types.NamespacedName{Name: serviceName, Namespace: druidCR.Namespace}
+			deletedService := &v1.Service{}
+
+			By(fmt.Sprintf("By deleting service %s", serviceName))
+			Expect(k8sClient.Get(ctx, serviceNamespacedName, deletedService)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, deletedService)).Should(Succeed())
+
+			By(fmt.Sprintf("By checking service %s is recreated", serviceName))
+			Eventually(func() bool {
+				recreatedService := &v1.Service{}
+				err := k8sClient.Get(ctx, serviceNamespacedName, recreatedService)
+				return err == nil && recreatedService.UID != deletedService.UID
+			}, time.Second*10, interval).Should(BeTrue())
+		})
+
 		// Check for HPA
 		It("Test horizontal pod autoscaler for broker", func() {
 			componentName := "brokers"
@@ -348,3 +380,125 @@ func areStringArraysEqual(a1, a2 []string) bool {
 	}
 	return true
 }
+
+func TestMapPodToDruid(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "druid-tiny-cluster-brokers-0",
+		Namespace: "druid",
+		Labels:    map[string]string{"app": "druid", "druid_cr": "tiny-cluster"},
+	}}
+	if !hasDruidCRLabel(pod) {
+		t.Errorf("expected the pod to be selected by its druid_cr label")
+	}
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "tiny-cluster", Namespace: "druid"}}}
+	if requests := mapPodToDruid(context.TODO(), pod); !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+
+	other := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "druid"}}
+	if hasDruidCRLabel(other) {
+		t.Errorf("expected the pod without druid_cr label to be filtered out")
+	}
+	if requests := mapPodToDruid(context.TODO(), other); len(requests) != 0 {
+		t.Errorf("expected no request, got %v", requests)
+	}
+}
+
+func TestReconcileIgnoredDruidOnPodEvent(t *testing.T) {
+	drd, err := readDruidClusterSpecFromFile("testdata/druid-smoke-test-cluster.yaml")
+	if err != nil {
+		t.Fatalf("failed to read the cluster spec: %v", err)
+	}
+	drd.Annotations = map[string]string{ignoredAnnotation: "true"}
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatalf("failed to add the client-go scheme: %v", err)
+	}
+	if err := druidv1alpha1.AddToScheme(s); err != nil {
+		t.Fatalf("failed to add the druid scheme: %v", err)
+	}
+
+	writes := 0
+	sdk := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(drd).
+		WithStatusSubresource(&druidv1alpha1.Druid{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				writes++
+				return c.Create(ctx, obj, opts...)
+			},
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				writes++
+				return c.Update(ctx, obj, opts...)
+			},
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				writes++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				writes++
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	r := &DruidReconciler{
+		Client:        sdk,
+		Log:           ctrl.Log.WithName("controllers").WithName("Druid"),
+		Scheme:        s,
+		ReconcileWait: defaultResyncPeriod,
+		Recorder:      &record.FakeRecorder{},
+	}
+
+	if (GenericPredicates{}).Update(event.UpdateEvent{ObjectOld: drd, ObjectNew: drd}) {
+		t.Errorf("expected the events of the ignored Druid to be filtered out")
+	}
+
+	// the pod events are not filtered by the annotation of the Druid they are mapped to
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "druid-" + drd.Name + "-brokers-0",
+		Namespace: drd.Namespace,
+		Labels:    map[string]string{"app": "druid", "druid_cr": drd.Name},
+	}}
+	for _, request := range mapPodToDruid(context.TODO(), pod) {
+		result, err := r.Reconcile(context.TODO(), request)
+		if err != nil {
+			t.Fatalf("failed to reconcile: %v", err)
+		}
+		if result.RequeueAfter != 0 {
+			t.Errorf("expected the ignored Druid not to be requeued, got %v", result.RequeueAfter)
+		}
+	}
+	if writes != 0 {
+		t.Errorf("expected the ignored Druid not to be reconciled, got %d writes", writes)
+	}
+}
+
+func TestLookupReconcileTime(t *testing.T) {
+	tests := []struct {
+		name          string
+		resyncPeriod  string
+		reconcileWait string
+		expected      time.Duration
+	}{
+		{name: "default", expected: 10 * time.Minute},
+		{name: "reconcile wait ignored", reconcileWait: "30s", expected: 10 * time.Minute},
+		{name: "resync period", resyncPeriod: "1h", reconcileWait: "30s", expected: time.Hour},
+		{name: "resync disabled", resyncPeriod: "0", reconcileWait: "30s", expected: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for env, val := range map[string]string{"RESYNC_PERIOD": tc.resyncPeriod, "RECONCILE_WAIT": tc.reconcileWait} {
+				// t.Setenv restores the environment once the test is done, even when it's unset here
+				t.Setenv(env, val)
+				if val == "" {
+					os.Unsetenv(env)
+				}
+			}
+			if actual := LookupReconcileTime(); actual != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
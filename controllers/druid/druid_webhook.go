@@ -0,0 +1,143 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DruidWebhook defaults and validates Druid objects at admission time, so an invalid spec is
+// rejected on apply instead of being accepted and then skipped by the reconciler.
+type DruidWebhook struct{}
+
+var _ admission.CustomDefaulter = &DruidWebhook{}
+var _ admission.CustomValidator = &DruidWebhook{}
+
+func (w *DruidWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.Druid{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-druid-apache-org-v1alpha1-druid,mutating=true,failurePolicy=fail,sideEffects=None,groups=druid.apache.org,resources=druids,verbs=create;update,versions=v1alpha1,name=mdruid.kb.io,admissionReviewVersions=v1
+
+// Default fills in the values the reconciler would otherwise derive at runtime.
+func (w *DruidWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	drd, ok := obj.(*v1alpha1.Druid)
+	if !ok {
+		return fmt.Errorf("expected a Druid but got a %T", obj)
+	}
+
+	setDruidDefaults(drd)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-druid-apache-org-v1alpha1-druid,mutating=false,failurePolicy=fail,sideEffects=None,groups=druid.apache.org,resources=druids,verbs=create;update,versions=v1alpha1,name=vdruid.kb.io,admissionReviewVersions=v1
+
+// ValidateCreate runs the same checks the reconciler runs before deploying a cluster.
+func (w *DruidWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	drd, ok := obj.(*v1alpha1.Druid)
+	if !ok {
+		return nil, fmt.Errorf("expected a Druid but got a %T", obj)
+	}
+
+	return nil, validateDruid(nil, drd)
+}
+
+// ValidateUpdate additionally rejects changes that can't be rolled out, such as shrinking volumes.
+func (w *DruidWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldDrd, ok := oldObj.(*v1alpha1.Druid)
+	if !ok {
+		return nil, fmt.Errorf("expected a Druid but got a %T", oldObj)
+	}
+	newDrd, ok := newObj.(*v1alpha1.Druid)
+	if !ok {
+		return nil, fmt.Errorf("expected a Druid but got a %T", newObj)
+	}
+
+	return nil, validateDruid(oldDrd, newDrd)
+}
+
+// ValidateDelete allows every delete, cleanup is handled by the finalizers.
+func (w *DruidWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateDruid validates the new Druid object, oldDrd is nil on create. The updates of a Druid being deleted,
+// or of its metadata only, are allowed whatever its spec, so that the finalizer of a Druid accepted before a
+// validation was tightened can still be removed.
+func validateDruid(oldDrd, newDrd *v1alpha1.Druid) error {
+	if oldDrd != nil && (newDrd.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldDrd.Spec, newDrd.Spec)) {
+		return nil
+	}
+
+	if err := verifyDruidSpec(newDrd); err != nil {
+		return fmt.Errorf("invalid DruidSpec[%s] due to [%s]", newDrd.Name, err.Error())
+	}
+
+	if oldDrd != nil {
+		if err := validateVolumeClaimTemplateShrink(oldDrd, newDrd); err != nil {
+			return fmt.Errorf("invalid DruidSpec[%s] due to [%s]", newDrd.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// setDruidDefaults sets the node kind, config mount paths and, when defaultProbes is enabled,
+// the default probes of every node spec that doesn't define them.
+func setDruidDefaults(drd *v1alpha1.Druid) {
+	if drd.Spec.CommonConfigMountPath == "" {
+		drd.Spec.CommonConfigMountPath = defaultCommonConfigMountPath
+	}
+
+	for key, nodeSpec := range drd.Spec.Nodes {
+		if nodeSpec.Kind == "" {
+			nodeSpec.Kind = "StatefulSet"
+		}
+
+		if nodeSpec.NodeConfigMountPath == "" {
+			nodeSpec.NodeConfigMountPath = getNodeConfigMountPath(&nodeSpec)
+		}
+
+		if drd.Spec.DefaultProbes {
+			// The port is left unset so that it keeps following druid.port, see updateDefaultPortInProbe.
+			if nodeSpec.LivenessProbe == nil && drd.Spec.LivenessProbe == nil {
+				nodeSpec.LivenessProbe = setDefaultProbe(0, nodeSpec.NodeType, "liveness")
+			}
+			if nodeSpec.ReadinessProbe == nil && drd.Spec.ReadinessProbe == nil {
+				nodeSpec.ReadinessProbe = setDefaultProbe(0, nodeSpec.NodeType, "readiness")
+			}
+			if nodeSpec.StartUpProbe == nil && drd.Spec.StartUpProbe == nil {
+				nodeSpec.StartUpProbe = setDefaultProbe(0, nodeSpec.NodeType, "startup")
+			}
+		}
+
+		drd.Spec.Nodes[key] = nodeSpec
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:docs-gen:collapse=Imports
+
+/*
+druid webhook test
+*/
+var _ = Describe("Test Druid webhook", func() {
+	const filePath = "testdata/druid-test-cr.yaml"
+	webhook := &DruidWebhook{}
+
+	Context("When validating a Druid CR", func() {
+		It("should accept a valid spec", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+
+			_, err = webhook.ValidateCreate(context.TODO(), druid)
+			Expect(err).Should(BeNil())
+		})
+
+		It("should reject invalid node keys", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			druid.Spec.Nodes["Brokers_2"] = druid.Spec.Nodes["brokers"]
+
+			_, err = webhook.ValidateCreate(context.TODO(), druid)
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("Brokers_2"))
+		})
+
+		It("should reject a missing image", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			druid.Spec.Image = ""
+
+			_, err = webhook.ValidateCreate(context.TODO(), druid)
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("Image missing"))
+		})
+
+		It("should reject an unknown nodeType", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			brokers := druid.Spec.Nodes["brokers"]
+			brokers.NodeType = "brokr"
+			druid.Spec.Nodes["brokers"] = brokers
+
+			_, err = webhook.ValidateCreate(context.TODO(), druid)
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("brokr"))
+		})
+
+		It("should reject an unknown zookeeper type", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			druid.Spec.Zookeeper.Type = "external"
+
+			_, err = webhook.ValidateCreate(context.TODO(), druid)
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("external"))
+		})
+
+		It("should reject duplicate additional containers", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			druid.Spec.AdditionalContainer = []druidv1alpha1.AdditionalContainer{
+				{ContainerName: "sidecar", Image: "busybox"},
+				{ContainerName: "sidecar", Image: "busybox"},
+			}
+
+			_, err = webhook.ValidateCreate(context.TODO(), druid)
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("duplicate container name"))
+		})
+
+		It("should reject shrinking a volume claim template", func() {
+			oldDruid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			newDruid := oldDruid.DeepCopy()
+
+			brokers := newDruid.Spec.Nodes["brokers"]
+			brokers.VolumeClaimTemplates[0].Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("1Gi")
+			newDruid.Spec.Nodes["brokers"] = brokers
+
+			_, err = webhook.ValidateUpdate(context.TODO(), oldDruid, newDruid)
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).Should(ContainSubstring("can not shrink"))
+
+			By("By allowing the volume claim template to grow")
+			brokers.VolumeClaimTemplates[0].Spec.Resources.Requests[v1.ResourceStorage] = resource.MustParse("5Gi")
+			_, err = webhook.ValidateUpdate(context.TODO(), oldDruid, newDruid)
+			Expect(err).Should(BeNil())
+		})
+	})
+
+	Context("When defaulting a Druid CR", func() {
+		It("should set kind, mount paths and probes", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			Expect(webhook.Default(context.TODO(), druid)).Should(Succeed())
+
+			for _, nodeSpec := range druid.Spec.Nodes {
+				Expect(nodeSpec.Kind).ShouldNot(BeEmpty())
+				Expect(nodeSpec.NodeConfigMountPath).ShouldNot(BeEmpty())
+				Expect(nodeSpec.LivenessProbe).ShouldNot(BeNil())
+				Expect(nodeSpec.StartUpProbe).ShouldNot(BeNil())
+				// readinessProbe is defined at the cluster level
+				Expect(nodeSpec.ReadinessProbe).Should(BeNil())
+			}
+		})
+
+		It("should not change the generated pod probes", func() {
+			druid, err := readDruidClusterSpecFromFile(filePath)
+			Expect(err).Should(BeNil())
+			nodeSpec := druid.Spec.Nodes["historicals"]
+			expected := setLivenessProbe(nodeSpec.DeepCopy(), druid)
+
+			Expect(webhook.Default(context.TODO(), druid)).Should(Succeed())
+			nodeSpec = druid.Spec.Nodes["historicals"]
+			Expect(setLivenessProbe(&nodeSpec, druid)).Should(Equal(expected))
+		})
+	})
+
+	Context("When applying a Druid CR to the API server", func() {
+		It("should be rejected by the admission webhook", func() {
+			druid, err := readDruidClusterSpecFromFile("testdata/druid-smoke-test-cluster.yaml")
+			Expect(err).Should(BeNil())
+			druid.Name = "webhook-invalid"
+			druid.Spec.Zookeeper = &druidv1alpha1.ZookeeperSpec{Type: "unknown", Spec: json.RawMessage(`{}`)}
+
+			Expect(k8sClient.Create(ctx, druid)).ShouldNot(Succeed())
+		})
+	})
+})
+
+func TestValidateDruid(t *testing.T) {
+	valid, err := readDruidClusterSpecFromFile("testdata/druid-test-cr.yaml")
+	if err != nil {
+		t.Fatalf("failed to read the druid: %v", err)
+	}
+	invalid := valid.DeepCopy()
+	invalid.Spec.Nodes["Brokers_2"] = invalid.Spec.Nodes["brokers"]
+
+	deleting := invalid.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{}
+	deleting.Finalizers = nil
+
+	relabelled := invalid.DeepCopy()
+	relabelled.Labels = map[string]string{"team": "data"}
+
+	fixed := invalid.DeepCopy()
+	delete(fixed.Spec.Nodes, "Brokers_2")
+
+	changed := invalid.DeepCopy()
+	changed.Spec.Image = "apache/druid:28.0.0"
+
+	tests := []struct {
+		name    string
+		oldDrd  *druidv1alpha1.Druid
+		newDrd  *druidv1alpha1.Druid
+		wantErr bool
+	}{
+		{name: "create of a valid spec", newDrd: valid},
+		{name: "create of an invalid spec", newDrd: invalid, wantErr: true},
+		{name: "removal of the finalizers of an invalid spec", oldDrd: invalid, newDrd: deleting},
+		{name: "metadata update of an invalid spec", oldDrd: invalid, newDrd: relabelled},
+		{name: "fix of an invalid spec", oldDrd: invalid, newDrd: fixed},
+		{name: "spec update keeping it invalid", oldDrd: invalid, newDrd: changed, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateDruid(tc.oldDrd, tc.newDrd); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSetDruidDefaults(t *testing.T) {
+	readinessProbe := &v1.Probe{TimeoutSeconds: 10}
+	drd := &druidv1alpha1.Druid{
+		Spec: druidv1alpha1.DruidSpec{
+			DefaultProbes: true,
+			Nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"brokers": {NodeType: broker, Kind: "Deployment", ReadinessProbe: readinessProbe},
+				"historicals": {
+					NodeType:            historical,
+					NodeConfigMountPath: "/opt/druid/conf/historical",
+				},
+			},
+		},
+	}
+
+	setDruidDefaults(drd)
+
+	if drd.Spec.CommonConfigMountPath != defaultCommonConfigMountPath {
+		t.Errorf("expected the default common config mount path, got %q", drd.Spec.CommonConfigMountPath)
+	}
+	brokers, historicals := drd.Spec.Nodes["brokers"], drd.Spec.Nodes["historicals"]
+	if brokers.Kind != "Deployment" || historicals.Kind != "StatefulSet" {
+		t.Errorf("expected the kinds Deployment and StatefulSet, got %q and %q", brokers.Kind, historicals.Kind)
+	}
+	if brokers.NodeConfigMountPath != "/druid/conf/druid/broker" || historicals.NodeConfigMountPath != "/opt/druid/conf/historical" {
+		t.Errorf("expected the node config mount paths to be defaulted, got %q and %q",
+			brokers.NodeConfigMountPath, historicals.NodeConfigMountPath)
+	}
+	if brokers.ReadinessProbe != readinessProbe {
+		t.Errorf("expected the readiness probe of the spec to be kept, got %+v", brokers.ReadinessProbe)
+	}
+	for key, nodeSpec := range drd.Spec.Nodes {
+		if nodeSpec.LivenessProbe == nil || nodeSpec.StartUpProbe == nil || nodeSpec.ReadinessProbe == nil {
+			t.Errorf("expected the default probes of %s", key)
+		}
+	}
+
+	withoutProbes := &druidv1alpha1.Druid{Spec: druidv1alpha1.DruidSpec{
+		Nodes: map[string]druidv1alpha1.DruidNodeSpec{"brokers": {NodeType: broker}},
+	}}
+	setDruidDefaults(withoutProbes)
+	if probe := withoutProbes.Spec.Nodes["brokers"].LivenessProbe; probe != nil {
+		t.Errorf("expected no default probe when defaultProbes is disabled, got %+v", probe)
+	}
+}
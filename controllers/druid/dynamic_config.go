@@ -22,6 +22,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
 	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
@@ -30,147 +34,350 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// updateDruidDynamicConfigs updates the Druid cluster's dynamic configurations
-// for both overlords (middlemanagers) and coordinators.
+// dynamicConfigApis maps the node types to the dynamic configuration API their dynamicConfig is applied through.
+var dynamicConfigApis = map[string]string{
+	middleManager: "worker",
+	indexer:       "worker",
+	overlord:      "worker",
+	coordinator:   "coordinator",
+}
+
+// druidConfig is a cluster-wide configuration applied through the Druid API. The current configuration is read
+// from getPath and compared with body, the body is posted to postPath when it differs.
+type druidConfig struct {
+	name     string
+	getPath  string
+	postPath string
+	body     []byte
+	// includes reports whether the current configuration includes the desired one.
+	includes func(currentJson, desiredJson string) (bool, error)
+	// initBody, when set, is posted first when the configuration was never initialized on the cluster.
+	initBody []byte
+}
+
+// getDynamicConfigNodes returns the keys of the node specs with a dynamicConfig by dynamic configuration API,
+// sorted by key.
+func getDynamicConfigNodes(drd *v1alpha1.Druid) map[string][]string {
+	nodes := map[string][]string{}
+	for key, nodeSpec := range drd.Spec.Nodes {
+		api, ok := dynamicConfigApis[nodeSpec.NodeType]
+		if !ok || nodeSpec.DynamicConfig.Size() == 0 {
+			continue
+		}
+		nodes[api] = append(nodes[api], key)
+	}
+	for api := range nodes {
+		sort.Strings(nodes[api])
+	}
+	return nodes
+}
+
+// validateDynamicConfigs makes sure a single node spec sets the dynamicConfig of each dynamic configuration API.
+func validateDynamicConfigs(drd *v1alpha1.Druid) error {
+	for api, keys := range getDynamicConfigNodes(drd) {
+		if len(keys) > 1 {
+			return fmt.Errorf("nodes [%s] all set the %s dynamicConfig, only one of them can", strings.Join(keys, ","), api)
+		}
+	}
+	return nil
+}
+
+// hasDruidConfigs reports whether the spec sets any cluster-wide configuration.
+func hasDruidConfigs(drd *v1alpha1.Druid) bool {
+	return len(getDynamicConfigNodes(drd)) > 0 ||
+		(drd.Spec.CompactionTaskSlots != nil && (drd.Spec.CompactionTaskSlots.Ratio != "" || drd.Spec.CompactionTaskSlots.Max != nil)) ||
+		len(drd.Spec.DefaultRules) > 0 ||
+		drd.Spec.Lookups.Size() > 0
+}
+
+// getDruidConfigs returns the cluster-wide configurations of the spec: the dynamic configurations of the node
+// specs, found by nodeType, the compaction task slots, the default rules and the lookups. The worker config is
+// set through the indexer API at overlordSvcName, the others through the coordinator API at svcName.
+func getDruidConfigs(drd *v1alpha1.Druid, svcName, overlordSvcName string) ([]druidConfig, error) {
+	var configs []druidConfig
+
+	nodes := getDynamicConfigNodes(drd)
+	if keys, ok := nodes["worker"]; ok {
+		path := druidapi.MakePath(overlordSvcName, "indexer", "worker")
+		configs = append(configs, druidConfig{
+			name:     fmt.Sprintf("%s dynamic", drd.Spec.Nodes[keys[0]].NodeType),
+			getPath:  path,
+			postPath: path,
+			body:     drd.Spec.Nodes[keys[0]].DynamicConfig.Raw,
+			includes: util.IncludesJson,
+		})
+	}
+	if keys, ok := nodes["coordinator"]; ok {
+		path := druidapi.MakePath(svcName, "coordinator", "config")
+		configs = append(configs, druidConfig{
+			name:     fmt.Sprintf("%s dynamic", drd.Spec.Nodes[keys[0]].NodeType),
+			getPath:  path,
+			postPath: path,
+			body:     drd.Spec.Nodes[keys[0]].DynamicConfig.Raw,
+			includes: util.IncludesJson,
+		})
+	}
+
+	if slots := drd.Spec.CompactionTaskSlots; slots != nil && (slots.Ratio != "" || slots.Max != nil) {
+		params := url.Values{}
+		desired := map[string]string{}
+		if slots.Ratio != "" {
+			ratio, err := strconv.ParseFloat(slots.Ratio, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid compaction task slots ratio [%s]: %v", slots.Ratio, err)
+			}
+			params.Set("ratio", slots.Ratio)
+			desired["compactionTaskSlotRatio"] = strconv.FormatFloat(ratio, 'f', -1, 64)
+		}
+		if slots.Max != nil {
+			params.Set("max", strconv.Itoa(int(*slots.Max)))
+			desired["maxCompactionTaskSlots"] = strconv.Itoa(int(*slots.Max))
+		}
+
+		// the task slots are set by the query parameters, the body is only compared with the current
+		// configuration and its numbers are written as is to compare with the numbers of the coordinator
+		var fields []string
+		for key, value := range desired {
+			fields = append(fields, fmt.Sprintf("%q: %s", key, value))
+		}
+		sort.Strings(fields)
+
+		configs = append(configs, druidConfig{
+			name:     "compaction task slots",
+			getPath:  druidapi.MakePath(svcName, "coordinator", "config", "compaction"),
+			postPath: druidapi.MakePath(svcName, "coordinator", "config", "compaction", "taskslots") + "?" + params.Encode(),
+			body:     []byte("{" + strings.Join(fields, ", ") + "}"),
+			includes: util.IncludesJson,
+		})
+	}
+
+	if len(drd.Spec.DefaultRules) > 0 {
+		rules, err := util.ToJsonString(drd.Spec.DefaultRules)
+		if err != nil {
+			return nil, err
+		}
+		path := druidapi.MakePath(svcName, "coordinator", "rules", "_default")
+		configs = append(configs, druidConfig{
+			name:     "default rules",
+			getPath:  path,
+			postPath: path,
+			body:     []byte(rules),
+			includes: util.IncludesJsonList,
+		})
+	}
+
+	if drd.Spec.Lookups.Size() > 0 {
+		configs = append(configs, druidConfig{
+			name:     "lookups",
+			getPath:  druidapi.MakePath(svcName, "coordinator", "lookups", "config", "all"),
+			postPath: druidapi.MakePath(svcName, "coordinator", "lookups", "config"),
+			body:     drd.Spec.Lookups.Raw,
+			includes: util.IncludesJson,
+			initBody: []byte("{}"),
+		})
+	}
+
+	return configs, nil
+}
+
+// updateDruidDynamicConfigs updates the Druid cluster's dynamic configurations, compaction task slots,
+// default rules and lookups through the router.
 func updateDruidDynamicConfigs(
 	ctx context.Context,
 	client client.Client,
 	druid *v1alpha1.Druid,
 	emitEvent EventEmitter,
 ) error {
-	nodeTypes := []string{"middlemanagers", "coordinators"}
+	if !hasDruidConfigs(druid) {
+		// Skip the router lookup if no configuration is provided
+		return nil
+	}
 
-	for _, nodeType := range nodeTypes {
-		nodeConfig, exists := druid.Spec.Nodes[nodeType]
-		if !exists || nodeConfig.DynamicConfig.Size() == 0 {
-			// Skip if dynamic configurations are not provided for the node type
-			continue
-		}
+	svcName, httpClient, err := newDruidAPIClient(ctx, client, druid, druidapi.CoordinatorAPI, emitEvent)
+	if err != nil {
+		return err
+	}
 
-		dynamicConfig := nodeConfig.DynamicConfig.Raw
+	overlordSvcName, err := getDruidAPIUrl(client, druid, druidapi.IndexerAPI, emitEvent)
+	if err != nil {
+		return err
+	}
 
-		svcName, err := druidapi.GetRouterSvcUrl(druid.Namespace, druid.Name, client)
-		if err != nil {
-			emitEvent.EmitEventGeneric(
-				druid,
-				string(druidGetRouterSvcUrlFailed),
-				fmt.Sprintf("Failed to get router service URL for %s", nodeType),
-				err,
-			)
+	configs, err := getDruidConfigs(druid, svcName, overlordSvcName)
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		if err := updateDruidConfig(druid, httpClient, config, emitEvent); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
 
-		basicAuth, err := druidapi.GetAuthCreds(
-			ctx,
-			client,
-			druid.Spec.Auth,
+// getDruidAPIUrl returns the URL of the service of the cluster serving the Druid API.
+func getDruidAPIUrl(client client.Client, druid *v1alpha1.Druid, api string, emitEvent EventEmitter) (string, error) {
+	svcName, err := druidapi.GetRouterSvcUrl(druid.Namespace, druid.Name, druid.Spec.APIEndpoint, api, druid.Spec.Auth.TLS != nil, client)
+	if err != nil {
+		emitEvent.EmitEventGeneric(
+			druid,
+			string(druidGetRouterSvcUrlFailed),
+			"Failed to get router service URL",
+			err,
 		)
-		if err != nil {
-			emitEvent.EmitEventGeneric(
-				druid,
-				string(druidGetAuthCredsFailed),
-				fmt.Sprintf("Failed to get authentication credentials for %s", nodeType),
-				err,
-			)
-			return err
-		}
+		return "", err
+	}
+	return svcName, nil
+}
+
+// newDruidAPIClient returns the URL of the service of the cluster serving the Druid API, and an HTTP client
+// with the authentication of the spec to call it.
+func newDruidAPIClient(
+	ctx context.Context,
+	client client.Client,
+	druid *v1alpha1.Druid,
+	api string,
+	emitEvent EventEmitter,
+) (string, internalhttp.DruidHTTP, error) {
+	svcName, err := getDruidAPIUrl(client, druid, api, emitEvent)
+	if err != nil {
+		return "", nil, err
+	}
 
-		// Create the HTTP client with basic authentication
-		httpClient := internalhttp.NewHTTPClient(
-			&http.Client{},
-			&internalhttp.Auth{BasicAuth: basicAuth},
+	auth, err := druidapi.GetAuth(
+		ctx,
+		client,
+		druid.Namespace,
+		druid.Spec.Auth,
+	)
+	if err != nil {
+		emitEvent.EmitEventGeneric(
+			druid,
+			string(druidGetAuthCredsFailed),
+			"Failed to get authentication credentials",
+			err,
 		)
+		return "", nil, err
+	}
 
-		// Determine the URL path for dynamic configurations based on the nodeType
-		var dynamicConfigPath string
-		switch nodeType {
-		case "middlemanagers":
-			dynamicConfigPath = druidapi.MakePath(svcName, "indexer", "worker")
-		case "coordinators":
-			dynamicConfigPath = druidapi.MakePath(svcName, "coordinator", "config")
-		default:
-			return fmt.Errorf("unsupported node type: %s", nodeType)
-		}
+	// Create the HTTP client with the authentication of the spec
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+	return svcName, httpClient, nil
+}
 
-		// Fetch current dynamic configurations
-		currentResp, err := httpClient.Do(
-			http.MethodGet,
-			dynamicConfigPath,
-			nil,
+// updateDruidConfig posts the configuration when the one on the cluster doesn't include it.
+func updateDruidConfig(
+	druid *v1alpha1.Druid,
+	httpClient internalhttp.DruidHTTP,
+	config druidConfig,
+	emitEvent EventEmitter,
+) error {
+	// Fetch current configurations
+	currentResp, err := httpClient.Do(
+		http.MethodGet,
+		config.getPath,
+		nil,
+	)
+	if err != nil {
+		emitEvent.EmitEventGeneric(
+			druid,
+			string(druidFetchCurrentConfigsFailed),
+			fmt.Sprintf("Failed to fetch current %s configurations", config.name),
+			err,
 		)
-		if err != nil {
-			emitEvent.EmitEventGeneric(
-				druid,
-				string(druidFetchCurrentConfigsFailed),
-				fmt.Sprintf("Failed to fetch current %s dynamic configurations", nodeType),
-				err,
-			)
-			return err
-		}
-		if currentResp.StatusCode != http.StatusOK {
-			err = fmt.Errorf(
-				"failed to retrieve current Druid %s dynamic configurations. Status code: %d, Response body: %s",
-				nodeType, currentResp.StatusCode, string(currentResp.ResponseBody),
-			)
-			emitEvent.EmitEventGeneric(
-				druid,
-				string(druidFetchCurrentConfigsFailed),
-				fmt.Sprintf("Failed to fetch current %s dynamic configurations", nodeType),
-				err,
-			)
+		return err
+	}
+
+	var currentConfigsJson string
+	switch {
+	case currentResp.StatusCode == http.StatusNotFound && config.initBody != nil:
+		// Initialize the configuration, it was never set on the cluster
+		if err := postDruidConfig(druid, httpClient, config.name, config.postPath, config.initBody, emitEvent); err != nil {
 			return err
 		}
-
+		currentConfigsJson = string(config.initBody)
+	case currentResp.StatusCode != http.StatusOK:
+		err = fmt.Errorf(
+			"failed to retrieve current Druid %s configurations. Status code: %d, Response body: %s",
+			config.name, currentResp.StatusCode, string(currentResp.ResponseBody),
+		)
+		emitEvent.EmitEventGeneric(
+			druid,
+			string(druidFetchCurrentConfigsFailed),
+			fmt.Sprintf("Failed to fetch current %s configurations", config.name),
+			err,
+		)
+		return err
+	case len(currentResp.ResponseBody) == 0:
 		// Handle empty response body
-		var currentConfigsJson string
-		if len(currentResp.ResponseBody) == 0 {
-			currentConfigsJson = "{}" // Initialize as empty JSON object if response body is empty
+		if strings.HasPrefix(strings.TrimSpace(string(config.body)), "[") {
+			currentConfigsJson = "[]"
 		} else {
-			currentConfigsJson = currentResp.ResponseBody
-		}
-
-		// Compare current and desired configurations
-		equal, err := util.IncludesJson(currentConfigsJson, string(dynamicConfig))
-		if err != nil {
-			emitEvent.EmitEventGeneric(
-				druid,
-				string(druidConfigComparisonFailed),
-				fmt.Sprintf("Failed to compare %s configurations", nodeType),
-				err,
-			)
-			return err
-		}
-		if equal {
-			// Configurations are already up-to-date
-			continue
+			currentConfigsJson = "{}"
 		}
+	default:
+		currentConfigsJson = currentResp.ResponseBody
+	}
 
-		// Update the Druid cluster's dynamic configurations if needed
-		respDynamicConfigs, err := httpClient.Do(
-			http.MethodPost,
-			dynamicConfigPath,
-			dynamicConfig,
+	// Compare current and desired configurations
+	equal, err := config.includes(currentConfigsJson, string(config.body))
+	if err != nil {
+		emitEvent.EmitEventGeneric(
+			druid,
+			string(druidConfigComparisonFailed),
+			fmt.Sprintf("Failed to compare %s configurations", config.name),
+			err,
 		)
-		if err != nil {
-			emitEvent.EmitEventGeneric(
-				druid,
-				string(druidUpdateConfigsFailed),
-				fmt.Sprintf("Failed to update %s dynamic configurations", nodeType),
-				err,
-			)
-			return err
-		}
-		if respDynamicConfigs.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to update Druid %s dynamic configurations", nodeType)
-		}
+		return err
+	}
+	if equal {
+		// Configurations are already up-to-date
+		return nil
+	}
 
+	// Update the Druid cluster's configurations if needed
+	if err := postDruidConfig(druid, httpClient, config.name, config.postPath, config.body, emitEvent); err != nil {
+		return err
+	}
+
+	emitEvent.EmitEventGeneric(
+		druid,
+		string(druidUpdateConfigsSuccess),
+		fmt.Sprintf("Successfully updated %s configurations", config.name),
+		nil,
+	)
+	return nil
+}
+
+func postDruidConfig(
+	druid *v1alpha1.Druid,
+	httpClient internalhttp.DruidHTTP,
+	name, path string,
+	body []byte,
+	emitEvent EventEmitter,
+) error {
+	resp, err := httpClient.Do(
+		http.MethodPost,
+		path,
+		body,
+	)
+	if err != nil {
 		emitEvent.EmitEventGeneric(
 			druid,
-			string(druidUpdateConfigsSuccess),
-			fmt.Sprintf("Successfully updated %s dynamic configurations", nodeType),
-			nil,
+			string(druidUpdateConfigsFailed),
+			fmt.Sprintf("Failed to update %s configurations", name),
+			err,
 		)
+		return err
+	}
+	// the lookups API accepts the update asynchronously
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to update Druid %s configurations, status code: %d, response body: %s",
+			name, resp.StatusCode, resp.ResponseBody)
 	}
-
 	return nil
 }
@@ -0,0 +1,159 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// druidConfigStub is a stand-in for the router, it serves the configurations in current by path and records
+// the calls and their bodies.
+type druidConfigStub struct {
+	current map[string]string
+	calls   []string
+}
+
+func (s *druidConfigStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	call := r.Method + " " + r.URL.RequestURI()
+	if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+		call += " " + string(body)
+	}
+	s.calls = append(s.calls, call)
+
+	if r.Method == http.MethodPost {
+		if r.URL.Path == "/druid/coordinator/v1/lookups/config" {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		return
+	}
+	current, ok := s.current[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write([]byte(current))
+}
+
+func TestUpdateDruidConfigs(t *testing.T) {
+	max := int32(10)
+	drd := &druidv1alpha1.Druid{
+		Spec: druidv1alpha1.DruidSpec{
+			Nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				// dynamic configs are found by nodeType, whatever the key of the node spec
+				"mm": {
+					NodeType:      middleManager,
+					DynamicConfig: runtime.RawExtension{Raw: []byte(`{"selectStrategy": {"type": "equalDistribution"}}`)},
+				},
+				"coord": {
+					NodeType:      coordinator,
+					DynamicConfig: runtime.RawExtension{Raw: []byte(`{"maxSegmentsToMove": 100}`)},
+				},
+				"brokers": {
+					NodeType: broker,
+				},
+			},
+			CompactionTaskSlots: &druidv1alpha1.CompactionTaskSlotsSpec{Ratio: "0.50", Max: &max},
+			DefaultRules:        []runtime.RawExtension{{Raw: []byte(`{"type": "loadForever", "tieredReplicants": {"_default_tier": 2}}`)}},
+			Lookups:             runtime.RawExtension{Raw: []byte(`{"__default": {"country": {"version": "v1"}}}`)},
+		},
+	}
+	if !hasDruidConfigs(drd) {
+		t.Fatalf("expected the spec to have cluster-wide configurations")
+	}
+
+	stub := &druidConfigStub{current: map[string]string{
+		"/druid/indexer/v1/worker":                `{"selectStrategy": {"type": "equalDistribution"}}`,
+		"/druid/coordinator/v1/config":            `{"maxSegmentsToMove": 5, "balancerComputeThreads": 1}`,
+		"/druid/coordinator/v1/config/compaction": `{"compactionConfigs": [], "compactionTaskSlotRatio": 0.1, "maxCompactionTaskSlots": 10}`,
+		"/druid/coordinator/v1/rules/_default":    `[{"type": "loadForever", "tieredReplicants": {"_default_tier": 2}}]`,
+	}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	configs, err := getDruidConfigs(drd, server.URL, server.URL)
+	if err != nil {
+		t.Fatalf("failed to get configurations: %v", err)
+	}
+	emitEvent := EmitEventFuncs{record.NewFakeRecorder(100)}
+	httpClient := internalhttp.NewHTTPClient(&http.Client{}, &internalhttp.Auth{})
+	for _, config := range configs {
+		if err := updateDruidConfig(drd, httpClient, config, emitEvent); err != nil {
+			t.Fatalf("failed to update %s configurations: %v", config.name, err)
+		}
+	}
+
+	expected := []string{
+		"GET /druid/indexer/v1/worker",
+		"GET /druid/coordinator/v1/config",
+		`POST /druid/coordinator/v1/config {"maxSegmentsToMove": 100}`,
+		"GET /druid/coordinator/v1/config/compaction",
+		`POST /druid/coordinator/v1/config/compaction/taskslots?max=10&ratio=0.50 {"compactionTaskSlotRatio": 0.5, "maxCompactionTaskSlots": 10}`,
+		"GET /druid/coordinator/v1/rules/_default",
+		"GET /druid/coordinator/v1/lookups/config/all",
+		"POST /druid/coordinator/v1/lookups/config {}",
+		`POST /druid/coordinator/v1/lookups/config {"__default": {"country": {"version": "v1"}}}`,
+	}
+	if !reflect.DeepEqual(stub.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, stub.calls)
+	}
+}
+
+func TestValidateDynamicConfigs(t *testing.T) {
+	dynamicConfig := runtime.RawExtension{Raw: []byte(`{"selectStrategy": {"type": "equalDistribution"}}`)}
+
+	tests := []struct {
+		name      string
+		nodes     map[string]druidv1alpha1.DruidNodeSpec
+		expectErr bool
+	}{
+		{
+			name: "one dynamic config per API",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {NodeType: middleManager, DynamicConfig: dynamicConfig},
+				"overlords":      {NodeType: overlord},
+			},
+		},
+		{
+			name: "two dynamic configs for the overlord API",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {NodeType: middleManager, DynamicConfig: dynamicConfig},
+				"overlords":      {NodeType: overlord, DynamicConfig: dynamicConfig},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			drd := &druidv1alpha1.Druid{Spec: druidv1alpha1.DruidSpec{Nodes: tc.nodes}}
+			if err := validateDynamicConfigs(drd); (err != nil) != tc.expectErr {
+				t.Errorf("expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
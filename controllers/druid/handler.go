@@ -49,6 +49,7 @@ import (
 
 const (
 	druidOpResourceHash          = "druidOpResourceHash"
+	fieldManager                 = "druid-operator"
 	defaultCommonConfigMountPath = "/druid/conf/druid/_common"
 	toBeDeletedLabel             = "toBeDeleted"
 	deletionTSLabel              = "deletionTS"
@@ -58,6 +59,11 @@ var logger = logf.Log.WithName("druid_operator_handler")
 
 func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, emitEvents EventEmitter) error {
 
+	// the finalizers run whatever the spec, an invalid spec must not hold the deletion
+	if m.GetDeletionTimestamp() != nil {
+		return executeFinalizers(ctx, sdk, m, emitEvents)
+	}
+
 	if err := verifyDruidSpec(m); err != nil {
 		e := fmt.Errorf("invalid DruidSpec[%s:%s] due to [%s]", m.Kind, m.Name, err.Error())
 		emitEvents.EmitEventGeneric(m, "DruidOperatorInvalidSpec", "", e)
@@ -74,33 +80,50 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 	hpaNames := make(map[string]bool)
 	ingressNames := make(map[string]bool)
 	pvcNames := make(map[string]bool)
+	secretNames := make(map[string]bool)
 
 	ls := makeLabelsForDruid(m)
 
-	commonConfig, err := makeCommonConfigMap(ctx, sdk, m, ls)
+	if err := updateReferencesCondition(ctx, sdk, m, emitEvents); err != nil {
+		return err
+	}
+
+	commonConfig, commonSecret, err := makeCommonConfigMap(ctx, sdk, m, ls)
 	if err != nil {
 		return err
 	}
-	commonConfigSHA, err := getObjectHash(commonConfig)
+	commonConfigSHA, err := getConfigHash(commonConfig, commonSecret)
 	if err != nil {
 		return err
 	}
 
 	if _, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
-		func() (object, error) { return makeCommonConfigMap(ctx, sdk, m, ls) },
+		func() (object, error) { return commonConfig, nil },
 		func() object { return &v1.ConfigMap{} },
 		alwaysTrueIsEqualsFn, noopUpdaterFn, m, configMapNames, emitEvents); err != nil {
 		return err
 	}
 
-	if m.GetDeletionTimestamp() != nil {
-		return executeFinalizers(ctx, sdk, m, emitEvents)
+	if commonSecret != nil {
+		if _, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
+			func() (object, error) { return commonSecret, nil },
+			func() object { return &v1.Secret{} },
+			alwaysTrueIsEqualsFn, noopUpdaterFn, m, secretNames, emitEvents); err != nil {
+			return err
+		}
 	}
 
 	if err := updateFinalizers(ctx, sdk, m, emitEvents); err != nil {
 		return err
 	}
 
+	var issuer *tlsIssuer
+	if m.Spec.TLS != nil {
+		if issuer, err = getTLSIssuer(ctx, sdk, m, secretNames, emitEvents); err != nil {
+			return err
+		}
+	}
+
 	for _, elem := range allNodeSpecs {
 		key := elem.key
 		nodeSpec := elem.spec
@@ -112,12 +135,12 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 		lm := makeLabelsForNodeSpec(&nodeSpec, m, m.Name, nodeSpecUniqueStr)
 
 		// create configmap first
-		nodeConfig, err := makeConfigMapForNodeSpec(&nodeSpec, m, lm, nodeSpecUniqueStr)
+		nodeConfig, nodeSecret, err := makeConfigMapForNodeSpec(ctx, sdk, &nodeSpec, m, lm, nodeSpecUniqueStr)
 		if err != nil {
 			return err
 		}
 
-		nodeConfigSHA, err := getObjectHash(nodeConfig)
+		nodeConfigSHA, err := getConfigHash(nodeConfig, nodeSecret)
 		if err != nil {
 			return err
 		}
@@ -129,6 +152,38 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 			return err
 		}
 
+		if nodeSecret != nil {
+			if _, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
+				func() (object, error) { return nodeSecret, nil },
+				func() object { return &v1.Secret{} },
+				alwaysTrueIsEqualsFn, noopUpdaterFn, m, secretNames, emitEvents); err != nil {
+				return err
+			}
+		}
+
+		configSHA := fmt.Sprintf("%s-%s", commonConfigSHA, nodeConfigSHA)
+
+		// the keystores are part of the SHA, so that the pods roll when the certificates rotate
+		if issuer != nil {
+			tlsSecret, err := makeTLSSecret(ctx, sdk, m, issuer, &nodeSpec, nodeSpecUniqueStr)
+			if err != nil {
+				return err
+			}
+
+			tlsSHA, err := getObjectHash(tlsSecret)
+			if err != nil {
+				return err
+			}
+			configSHA = fmt.Sprintf("%s-%s", configSHA, tlsSHA)
+
+			if _, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
+				func() (object, error) { return tlsSecret, nil },
+				func() object { return &v1.Secret{} },
+				alwaysTrueIsEqualsFn, noopUpdaterFn, m, secretNames, emitEvents); err != nil {
+				return err
+			}
+		}
+
 		//create services before creating statefulset
 		firstServiceName := ""
 		services := firstNonNilValue(nodeSpec.Services, m.Spec.Services).([]v1.Service)
@@ -150,10 +205,10 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 		if nodeSpec.Kind == "Deployment" {
 			if deployCreateUpdateStatus, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
 				func() (object, error) {
-					return makeDeployment(&nodeSpec, m, lm, nodeSpecUniqueStr, fmt.Sprintf("%s-%s", commonConfigSHA, nodeConfigSHA), firstServiceName)
+					return makeDeployment(&nodeSpec, m, lm, nodeSpecUniqueStr, configSHA, firstServiceName)
 				},
 				func() object { return &appsv1.Deployment{} },
-				deploymentIsEquals, noopUpdaterFn, m, deploymentNames, emitEvents); err != nil {
+				deploymentIsEquals, preserveReplicasUpdaterFn, m, deploymentNames, emitEvents); err != nil {
 				return err
 			} else if m.Spec.RollingDeploy {
 
@@ -186,10 +241,10 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 			// Create/Update StatefulSet
 			if stsCreateUpdateStatus, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
 				func() (object, error) {
-					return makeStatefulSet(&nodeSpec, m, lm, nodeSpecUniqueStr, fmt.Sprintf("%s-%s", commonConfigSHA, nodeConfigSHA), firstServiceName)
+					return makeStatefulSet(&nodeSpec, m, lm, nodeSpecUniqueStr, configSHA, firstServiceName)
 				},
 				func() object { return &appsv1.StatefulSet{} },
-				statefulSetIsEquals, noopUpdaterFn, m, statefulSetNames, emitEvents); err != nil {
+				statefulSetIsEquals, preserveReplicasUpdaterFn, m, statefulSetNames, emitEvents); err != nil {
 				return err
 			} else if m.Spec.RollingDeploy {
 
@@ -272,7 +327,10 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 	}
 
 	//update status and delete unwanted resources
-	updatedStatus := v1alpha1.DruidClusterStatus{}
+	updatedStatus := v1alpha1.DruidClusterStatus{
+		Conditions:          m.Status.Conditions,
+		TaskSlotAutoScalers: m.Status.TaskSlotAutoScalers,
+	}
 
 	updatedStatus.StatefulSets = deleteUnusedResources(ctx, sdk, m, statefulSetNames, ls,
 		func() objectList { return &appsv1.StatefulSetList{} },
@@ -310,6 +368,8 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 		}, emitEvents)
 	sort.Strings(updatedStatus.HPAutoScalers)
 
+	updatedStatus.HPAutoScalerReplicas = getHPAutoScalerReplicas(ctx, sdk, m, hpaNames, ls, emitEvents)
+
 	updatedStatus.Ingress = deleteUnusedResources(ctx, sdk, m, ingressNames, ls,
 		func() objectList { return &networkingv1.IngressList{} },
 		func(listObj runtime.Object) []object {
@@ -358,6 +418,21 @@ func deployDruidCluster(ctx context.Context, sdk client.Client, m *v1alpha1.Drui
 		}, emitEvents)
 	sort.Strings(updatedStatus.ConfigMaps)
 
+	// the secrets managed by the operator are only selected by their own labels, this also cleans up the secrets
+	// of the keystores once TLS is disabled and the ones of the properties once they no longer reference a secret
+	for _, secretLabels := range []map[string]string{makeLabelsForTLS(m), makeLabelsForConfigSecret(m)} {
+		deleteUnusedResources(ctx, sdk, m, secretNames, secretLabels,
+			func() objectList { return &v1.SecretList{} },
+			func(listObj runtime.Object) []object {
+				items := listObj.(*v1.SecretList).Items
+				result := make([]object, len(items))
+				for i := 0; i < len(items); i++ {
+					result[i] = &items[i]
+				}
+				return result
+			}, emitEvents)
+	}
+
 	podList, _ := readers.List(ctx, sdk, m, makeLabelsForDruid(m), emitEvents, func() objectList { return &v1.PodList{} }, func(listObj runtime.Object) []object {
 		items := listObj.(*v1.PodList).Items
 		result := make([]object, len(items))
@@ -682,6 +757,10 @@ func sdkCreateOrUpdateAsNeeded(
 		addHashToObject(obj)
 
 		prevObj := emptyObjFn()
+		if drd.Spec.ServerSideApply != nil && drd.Spec.ServerSideApply.Enabled {
+			return sdkApplyAsNeeded(ctx, sdk, obj, prevObj, isEqualFn, drd, emitEvent)
+		}
+
 		if err := sdk.Get(ctx, *namespacedName(obj.GetName(), obj.GetNamespace()), prevObj); err != nil {
 			if apierrors.IsNotFound(err) {
 				// resource does not exist, create it.
@@ -716,6 +795,51 @@ func sdkCreateOrUpdateAsNeeded(
 	}
 }
 
+// sdkApplyAsNeeded applies the object with server-side apply when it doesn't exist or changed. The updaterFns
+// aren't needed, the fields the operator doesn't set are kept by the API server.
+func sdkApplyAsNeeded(
+	ctx context.Context,
+	sdk client.Client,
+	obj, prevObj object,
+	isEqualFn func(prev, curr object) bool,
+	drd *v1alpha1.Druid,
+	emitEvent EventEmitter) (DruidNodeStatus, error) {
+
+	result := resourceUpdated
+	if err := sdk.Get(ctx, *namespacedName(obj.GetName(), obj.GetNamespace()), prevObj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			e := fmt.Errorf("Failed to get [%s:%s] due to [%s].", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName(), err.Error())
+			logger.Error(e, e.Error(), "Prev object", stringifyForLogging(prevObj, drd), "name", drd.Name, "namespace", drd.Namespace)
+			emitEvent.EmitEventGeneric(drd, string(druidOjectGetFail), "", err)
+			return "", e
+		}
+		result = resourceCreated
+	} else if obj.GetAnnotations()[druidOpResourceHash] == prevObj.GetAnnotations()[druidOpResourceHash] && isEqualFn(prevObj, obj) &&
+		isManagedBy(prevObj, fieldManager, metav1.ManagedFieldsOperationApply) {
+		return "", nil
+	}
+
+	var applyOptions []client.PatchOption
+	if drd.Spec.ServerSideApply.ForceConflicts {
+		applyOptions = append(applyOptions, client.ForceOwnership)
+	}
+	if err := writers.Apply(ctx, sdk, drd, obj, emitEvent, applyOptions...); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// isManagedBy returns true when a manager owns fields of the object through an operation, the objects which were
+// updated before server-side apply was enabled are applied once even if they didn't change.
+func isManagedBy(obj object, manager string, operation metav1.ManagedFieldsOperationType) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == manager && entry.Operation == operation {
+			return true
+		}
+	}
+	return false
+}
+
 func isObjFullyDeployed(ctx context.Context, sdk client.Client, nodeSpec v1alpha1.DruidNodeSpec, nodeSpecUniqueStr string, drd *v1alpha1.Druid, emptyObjFn func() object, emitEvent EventEmitter) (bool, error) {
 
 	// Get Object
@@ -771,7 +895,20 @@ func getVolumeClaimTemplateSizes(sts object, nodeSpec *v1alpha1.DruidNodeSpec, p
 
 }
 
+// stringifyForLogging serializes the object for the logs, the values of a secret are redacted and only its keys are
+// kept.
 func stringifyForLogging(obj object, drd *v1alpha1.Druid) string {
+	if secret, ok := obj.(*v1.Secret); ok {
+		redacted := secret.DeepCopy()
+		for key := range redacted.Data {
+			redacted.Data[key] = []byte("REDACTED")
+		}
+		for key := range redacted.StringData {
+			redacted.StringData[key] = "REDACTED"
+		}
+		obj = redacted
+	}
+
 	if bytes, err := json.Marshal(obj); err != nil {
 		logger.Error(err, err.Error(), fmt.Sprintf("Failed to serialize [%s:%s]", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName()), "name", drd.Name, "namespace", drd.Namespace)
 		return fmt.Sprintf("%v", obj)
@@ -804,6 +941,20 @@ func getObjectHash(obj object) (string, error) {
 	}
 }
 
+// getConfigHash returns the hash of a configmap and of the secret holding its properties, if any, so that the
+// pods roll when the secrets they reference change.
+func getConfigHash(cm *v1.ConfigMap, secret *v1.Secret) (string, error) {
+	sha, err := getObjectHash(cm)
+	if err != nil || secret == nil {
+		return sha, err
+	}
+	secretSHA, err := getObjectHash(secret)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", sha, secretSHA), nil
+}
+
 func makeNodeSpecificUniqueString(m *v1alpha1.Druid, key string) string {
 	return fmt.Sprintf("druid-%s-%s", m.Name, key)
 }
@@ -884,6 +1035,14 @@ func getVolumeMounts(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid) []v1.V
 		},
 	}
 
+	if m.Spec.TLS != nil {
+		volumeMount = append(volumeMount, v1.VolumeMount{
+			MountPath: tlsMountPath,
+			Name:      tlsVolumeName,
+			ReadOnly:  true,
+		})
+	}
+
 	volumeMount = append(volumeMount, m.Spec.VolumeMounts...)
 	volumeMount = append(volumeMount, nodeSpec.VolumeMounts...)
 	return volumeMount
@@ -915,34 +1074,29 @@ func getTopologySpreadConstraints(nodeSpec *v1alpha1.DruidNodeSpec) []v1.Topolog
 func getVolume(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, nodeSpecUniqueStr string) []v1.Volume {
 	volumesHolder := []v1.Volume{
 		{
-			Name: "common-config-volume",
-			VolumeSource: v1.VolumeSource{
-				ConfigMap: &v1.ConfigMapVolumeSource{
-					LocalObjectReference: v1.LocalObjectReference{
-						Name: fmt.Sprintf("%s-druid-common-config", m.ObjectMeta.Name),
-					},
-				}},
+			Name:         "common-config-volume",
+			VolumeSource: getConfigVolumeSource(getCommonConfigName(m), hasSecretReference(m.Spec.CommonRuntimeProperties)),
 		},
 		{
-			Name: "nodetype-config-volume",
-			VolumeSource: v1.VolumeSource{
-				ConfigMap: &v1.ConfigMapVolumeSource{
-					LocalObjectReference: v1.LocalObjectReference{
-						Name: fmt.Sprintf("%s-config", nodeSpecUniqueStr),
-					},
-				},
-			},
+			Name:         "nodetype-config-volume",
+			VolumeSource: getConfigVolumeSource(getNodeConfigName(nodeSpecUniqueStr), hasSecretReference(nodeSpec.RuntimeProperties)),
 		},
 	}
+	if m.Spec.TLS != nil {
+		volumesHolder = append(volumesHolder, getTLSVolume(nodeSpecUniqueStr))
+	}
 	volumesHolder = append(volumesHolder, m.Spec.Volumes...)
 	volumesHolder = append(volumesHolder, nodeSpec.Volumes...)
 	return volumesHolder
 }
 
-func getEnv(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, configMapSHA string) []v1.EnvVar {
+func getEnv(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, nodeSpecUniqueStr, configMapSHA string) []v1.EnvVar {
 	envHolder := firstNonNilValue(nodeSpec.Env, m.Spec.Env).([]v1.EnvVar)
 	// enables to do the trick to force redeployment in case of configmap changes.
 	envHolder = append(envHolder, v1.EnvVar{Name: "configMapSHA", Value: configMapSHA})
+	if m.Spec.TLS != nil {
+		envHolder = append(envHolder, getTLSEnv(nodeSpecUniqueStr))
+	}
 
 	return envHolder
 }
@@ -1069,7 +1223,7 @@ func makeStatefulSetSpec(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, ls
 		Selector: &metav1.LabelSelector{
 			MatchLabels: ls,
 		},
-		Replicas:             &nodeSpec.Replicas,
+		Replicas:             getReplicas(nodeSpec),
 		PodManagementPolicy:  appsv1.PodManagementPolicyType(firstNonEmptyStr(firstNonEmptyStr(string(nodeSpec.PodManagementPolicy), string(m.Spec.PodManagementPolicy)), string(appsv1.ParallelPodManagement))),
 		UpdateStrategy:       *updateStrategy,
 		Template:             makePodTemplate(nodeSpec, m, ls, nodeSpecificUniqueString, configMapSHA),
@@ -1080,13 +1234,37 @@ func makeStatefulSetSpec(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, ls
 
 }
 
+// getReplicas returns the replicas of the workload of a node spec. They are left to the HPA or the
+// taskSlotAutoScaler of the node spec, if any, so that its scaling decisions aren't reset.
+func getReplicas(nodeSpec *v1alpha1.DruidNodeSpec) *int32 {
+	if nodeSpec.HPAutoScaler != nil || nodeSpec.TaskSlotAutoScaler != nil {
+		return nil
+	}
+	return &nodeSpec.Replicas
+}
+
+// preserveReplicasUpdaterFn keeps the current replicas of a workload scaled by an autoscaler, the update would
+// reset them to the default otherwise.
+func preserveReplicasUpdaterFn(prev, curr object) {
+	switch curr := curr.(type) {
+	case *appsv1.StatefulSet:
+		if curr.Spec.Replicas == nil {
+			curr.Spec.Replicas = prev.(*appsv1.StatefulSet).Spec.Replicas
+		}
+	case *appsv1.Deployment:
+		if curr.Spec.Replicas == nil {
+			curr.Spec.Replicas = prev.(*appsv1.Deployment).Spec.Replicas
+		}
+	}
+}
+
 // makeDeploymentSpec shall create deployment Spec for deployments.
 func makeDeploymentSpec(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, ls map[string]string, nodeSpecificUniqueString, configMapSHA, serviceName string) appsv1.DeploymentSpec {
 	deploySpec := appsv1.DeploymentSpec{
 		Selector: &metav1.LabelSelector{
 			MatchLabels: ls,
 		},
-		Replicas: &nodeSpec.Replicas,
+		Replicas: getReplicas(nodeSpec),
 		Template: makePodTemplate(nodeSpec, m, ls, nodeSpecificUniqueString, configMapSHA),
 		Strategy: appsv1.DeploymentStrategy{
 			Type:          "RollingUpdate",
@@ -1118,7 +1296,7 @@ func makePodSpec(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, nodeSpecUn
 		ImagePullPolicy: v1.PullPolicy(firstNonEmptyStr(string(nodeSpec.ImagePullPolicy), string(m.Spec.ImagePullPolicy))),
 		Ports:           nodeSpec.Ports,
 		Resources:       nodeSpec.Resources,
-		Env:             getEnv(nodeSpec, m, configMapSHA),
+		Env:             getEnv(nodeSpec, m, nodeSpecUniqueStr, configMapSHA),
 		EnvFrom:         getEnvFrom(nodeSpec, m),
 		VolumeMounts:    getVolumeMounts(nodeSpec, m),
 		LivenessProbe:   setLivenessProbe(nodeSpec, m),
@@ -1231,6 +1409,39 @@ func makeHorizontalPodAutoscaler(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.D
 	return hpa, nil
 }
 
+// getHPAutoScalerReplicas returns the current and desired replicas of the HPAs of the node specs, sorted by name.
+func getHPAutoScalerReplicas(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, hpaNames map[string]bool, ls map[string]string, emitEvents EventEmitter) []v1alpha1.HPAutoScalerReplicas {
+	if len(hpaNames) == 0 {
+		return nil
+	}
+	hpaList, err := readers.List(ctx, sdk, m, ls, emitEvents, func() objectList { return &autoscalev2.HorizontalPodAutoscalerList{} }, func(listObj runtime.Object) []object {
+		items := listObj.(*autoscalev2.HorizontalPodAutoscalerList).Items
+		result := make([]object, len(items))
+		for i := 0; i < len(items); i++ {
+			result[i] = &items[i]
+		}
+		return result
+	})
+	if err != nil {
+		return nil
+	}
+
+	var replicas []v1alpha1.HPAutoScalerReplicas
+	for _, obj := range hpaList {
+		hpa := obj.(*autoscalev2.HorizontalPodAutoscaler)
+		if !hpaNames[hpa.Name] {
+			continue
+		}
+		replicas = append(replicas, v1alpha1.HPAutoScalerReplicas{
+			Name:            hpa.Name,
+			CurrentReplicas: hpa.Status.CurrentReplicas,
+			DesiredReplicas: hpa.Status.DesiredReplicas,
+		})
+	}
+	sort.Slice(replicas, func(i, j int) bool { return replicas[i].Name < replicas[j].Name })
+	return replicas
+}
+
 func makeIngress(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, ls map[string]string, nodeSpecUniqueStr string) (*networkingv1.Ingress, error) {
 	nodeIngressSpec := *nodeSpec.Ingress
 
@@ -1392,7 +1603,7 @@ func sendEvent(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, even
 }
 
 func verifyDruidSpec(drd *v1alpha1.Druid) error {
-	keyValidationRegex, err := regexp.Compile("[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*")
+	keyValidationRegex, err := regexp.Compile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$")
 	if err != nil {
 		return err
 	}
@@ -1405,6 +1616,22 @@ func verifyDruidSpec(drd *v1alpha1.Druid) error {
 		return err
 	}
 
+	if err = validateExtSpecs(drd); err != nil {
+		return err
+	}
+
+	if err = validateDynamicConfigs(drd); err != nil {
+		return err
+	}
+
+	if err = validateTaskSlotAutoScalers(drd); err != nil {
+		return err
+	}
+
+	if err = validateTLS(drd); err != nil {
+		return err
+	}
+
 	errorMsg := ""
 	for key, node := range drd.Spec.Nodes {
 		if drd.Spec.Image == "" && node.Image == "" {
@@ -1414,6 +1641,10 @@ func verifyDruidSpec(drd *v1alpha1.Druid) error {
 		if !keyValidationRegex.MatchString(key) {
 			errorMsg = fmt.Sprintf("%sNode[%s] Key must match k8s resource name regex '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*'", errorMsg, key)
 		}
+
+		if !containsString(druidNodeTypes, node.NodeType) {
+			errorMsg = fmt.Sprintf("%sNode[%s] has unknown nodeType [%s]\n", errorMsg, key, node.NodeType)
+		}
 	}
 
 	if errorMsg == "" {
@@ -1423,6 +1654,30 @@ func verifyDruidSpec(drd *v1alpha1.Druid) error {
 	}
 }
 
+// validateExtSpecs makes sure the zookeeper, metadataStore and deepStorage specs
+// refer to a registered extension type and can be unmarshalled into it.
+func validateExtSpecs(drd *v1alpha1.Druid) error {
+	if drd.Spec.Zookeeper != nil {
+		if _, err := createZookeeperManager(drd.Spec.Zookeeper); err != nil {
+			return err
+		}
+	}
+
+	if drd.Spec.MetadataStore != nil {
+		if _, err := createMetadataStoreManager(drd.Spec.MetadataStore); err != nil {
+			return err
+		}
+	}
+
+	if drd.Spec.DeepStorage != nil {
+		if _, err := createDeepStorageManager(drd.Spec.DeepStorage); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func namespacedName(name, namespace string) *types.NamespacedName {
 	return &types.NamespacedName{Name: name, Namespace: namespace}
 }
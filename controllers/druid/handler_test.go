@@ -19,16 +19,27 @@ under the License.
 package druid
 
 import (
+	"context"
 	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ghodss/yaml"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalev2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
 )
@@ -175,7 +186,7 @@ var _ = Describe("Test handler", func() {
 			filePath := "testdata/druid-test-cr.yaml"
 			clusterSpec, err := readDruidClusterSpecFromFile(filePath)
 			Expect(err).Should(BeNil())
-			actual, _ := makeCommonConfigMap(ctx, k8sClient, clusterSpec, makeLabelsForDruid(clusterSpec))
+			actual, _, _ := makeCommonConfigMap(ctx, k8sClient, clusterSpec, makeLabelsForDruid(clusterSpec))
 			addHashToObject(actual)
 
 			expected := new(corev1.ConfigMap)
@@ -194,7 +205,7 @@ var _ = Describe("Test handler", func() {
 			nodeSpecUniqueStr := makeNodeSpecificUniqueString(clusterSpec, "brokers")
 			nodeSpec := clusterSpec.Spec.Nodes["brokers"]
 
-			actual, _ := makeConfigMapForNodeSpec(&nodeSpec, clusterSpec, makeLabelsForNodeSpec(&nodeSpec, clusterSpec, clusterSpec.Name, nodeSpecUniqueStr), nodeSpecUniqueStr)
+			actual, _, _ := makeConfigMapForNodeSpec(ctx, k8sClient, &nodeSpec, clusterSpec, makeLabelsForNodeSpec(&nodeSpec, clusterSpec, clusterSpec.Name, nodeSpecUniqueStr), nodeSpecUniqueStr)
 			addHashToObject(actual)
 
 			expected := new(corev1.ConfigMap)
@@ -369,3 +380,193 @@ func TestPodSpecDNSPolicyYAML(t *testing.T) {
 		t.Errorf("expected DNSPolicy %q, got %q", expectedDNSPolicy, podSpec.DNSPolicy)
 	}
 }
+
+// TestSdkCreateOrUpdateAsNeededServerSideApply validates that the resources are applied as the druid-operator
+// field manager when server-side apply is enabled, and only when they changed.
+func TestSdkCreateOrUpdateAsNeededServerSideApply(t *testing.T) {
+	type applied struct {
+		patchType types.PatchType
+		options   client.PatchOptions
+	}
+	var patches []applied
+	sdk := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			options := client.PatchOptions{}
+			options.ApplyOptions(opts)
+			patches = append(patches, applied{patchType: patch.Type(), options: options})
+			// the fake client doesn't keep the managed fields of the applies, they are set as the API server would
+			obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: options.FieldManager, Operation: metav1.ManagedFieldsOperationApply}})
+			if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object)); apierrors.IsNotFound(err) {
+				return c.Create(ctx, obj)
+			}
+			return c.Update(ctx, obj)
+		},
+	}).Build()
+	ctx := context.TODO()
+	emitEvent := EmitEventFuncs{record.NewFakeRecorder(100)}
+	drd := &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid", UID: "uid"},
+		Spec: druidv1alpha1.DruidSpec{
+			ServerSideApply: &druidv1alpha1.ServerSideApplySpec{Enabled: true},
+		},
+	}
+	data := map[string]string{"runtime.properties": "druid.port=8088"}
+	objFn := func() (object, error) {
+		return makeConfigMap("druid-tiny-cluster-brokers-config", "druid", map[string]string{}, data)
+	}
+	apply := func() DruidNodeStatus {
+		status, err := sdkCreateOrUpdateAsNeeded(ctx, sdk, objFn, func() object { return &corev1.ConfigMap{} },
+			alwaysTrueIsEqualsFn, noopUpdaterFn, drd, map[string]bool{}, emitEvent)
+		if err != nil {
+			t.Fatalf("failed to apply: %v", err)
+		}
+		return status
+	}
+
+	if status := apply(); status != resourceCreated {
+		t.Errorf("expected the configmap to be created, got %q", status)
+	}
+	if status := apply(); status != "" || len(patches) != 1 {
+		t.Errorf("expected the unchanged configmap not to be applied, got %q and %d patches", status, len(patches))
+	}
+	if patches[0].patchType != types.ApplyPatchType || patches[0].options.FieldManager != fieldManager {
+		t.Errorf("expected an apply as %s, got %+v", fieldManager, patches[0])
+	}
+	if patches[0].options.Force != nil {
+		t.Errorf("expected the conflicts not to be forced")
+	}
+
+	data["runtime.properties"] = "druid.port=8082"
+	drd.Spec.ServerSideApply.ForceConflicts = true
+	if status := apply(); status != resourceUpdated || len(patches) != 2 {
+		t.Fatalf("expected the changed configmap to be applied, got %q and %d patches", status, len(patches))
+	}
+	if force := patches[1].options.Force; force == nil || !*force {
+		t.Errorf("expected the conflicts to be forced")
+	}
+}
+
+// TestWorkloadReplicasWithHPAutoScaler validates that the replicas of the workloads scaled by an HPA are left to
+// it, for both workload kinds.
+func TestWorkloadReplicasWithHPAutoScaler(t *testing.T) {
+	tests := []struct {
+		name        string
+		emptyObjFn  func() object
+		makeFn      func(nodeSpec *druidv1alpha1.DruidNodeSpec, m *druidv1alpha1.Druid) (object, error)
+		replicasFn  func(obj object) *int32
+		setReplicas func(obj object, replicas int32)
+	}{
+		{
+			name:       "Deployment",
+			emptyObjFn: func() object { return &appsv1.Deployment{} },
+			makeFn: func(nodeSpec *druidv1alpha1.DruidNodeSpec, m *druidv1alpha1.Druid) (object, error) {
+				return makeDeployment(nodeSpec, m, map[string]string{}, "druid-tiny-cluster-brokers", "sha", "")
+			},
+			replicasFn:  func(obj object) *int32 { return obj.(*appsv1.Deployment).Spec.Replicas },
+			setReplicas: func(obj object, replicas int32) { obj.(*appsv1.Deployment).Spec.Replicas = &replicas },
+		},
+		{
+			name:       "StatefulSet",
+			emptyObjFn: func() object { return &appsv1.StatefulSet{} },
+			makeFn: func(nodeSpec *druidv1alpha1.DruidNodeSpec, m *druidv1alpha1.Druid) (object, error) {
+				return makeStatefulSet(nodeSpec, m, map[string]string{}, "druid-tiny-cluster-brokers", "sha", "")
+			},
+			replicasFn:  func(obj object) *int32 { return obj.(*appsv1.StatefulSet).Spec.Replicas },
+			setReplicas: func(obj object, replicas int32) { obj.(*appsv1.StatefulSet).Spec.Replicas = &replicas },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &druidv1alpha1.Druid{ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid", UID: "uid"}}
+			nodeSpec := &druidv1alpha1.DruidNodeSpec{NodeType: broker, Replicas: 2}
+
+			obj, err := tc.makeFn(nodeSpec, m)
+			if err != nil {
+				t.Fatalf("failed to make the workload: %v", err)
+			}
+			if replicas := tc.replicasFn(obj); replicas == nil || *replicas != 2 {
+				t.Errorf("expected the replicas of the node spec without HPA, got %v", replicas)
+			}
+
+			nodeSpec.HPAutoScaler = &autoscalev2.HorizontalPodAutoscalerSpec{MaxReplicas: 10}
+			obj, err = tc.makeFn(nodeSpec, m)
+			if err != nil {
+				t.Fatalf("failed to make the workload: %v", err)
+			}
+			if replicas := tc.replicasFn(obj); replicas != nil {
+				t.Errorf("expected the replicas to be left to the HPA, got %d", *replicas)
+			}
+
+			// the HPA scaled the existing workload, the update keeps its decision
+			existing, _ := tc.makeFn(&druidv1alpha1.DruidNodeSpec{NodeType: broker, Replicas: 2}, m)
+			tc.setReplicas(existing, 5)
+			sdk := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+			status, err := sdkCreateOrUpdateAsNeeded(context.TODO(), sdk,
+				func() (object, error) { return tc.makeFn(nodeSpec, m) },
+				tc.emptyObjFn, alwaysTrueIsEqualsFn, preserveReplicasUpdaterFn, m, map[string]bool{},
+				EmitEventFuncs{record.NewFakeRecorder(100)})
+			if err != nil || status != resourceUpdated {
+				t.Fatalf("expected the workload to be updated, got %q: %v", status, err)
+			}
+			updated := tc.emptyObjFn()
+			if err := sdk.Get(context.TODO(), *namespacedName("druid-tiny-cluster-brokers", "druid"), updated); err != nil {
+				t.Fatalf("failed to get the workload: %v", err)
+			}
+			if replicas := tc.replicasFn(updated); replicas == nil || *replicas != 5 {
+				t.Errorf("expected the replicas of the HPA to be kept, got %v", replicas)
+			}
+		})
+	}
+}
+
+func TestGetHPAutoScalerReplicas(t *testing.T) {
+	m := &druidv1alpha1.Druid{ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid"}}
+	ls := makeLabelsForDruid(m)
+	hpa := func(name string, current, desired int32) *autoscalev2.HorizontalPodAutoscaler {
+		return &autoscalev2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "druid", Labels: ls},
+			Status:     autoscalev2.HorizontalPodAutoscalerStatus{CurrentReplicas: current, DesiredReplicas: desired},
+		}
+	}
+	sdk := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		hpa("druid-tiny-cluster-historicals", 3, 4),
+		hpa("druid-tiny-cluster-brokers", 2, 2),
+		hpa("druid-tiny-cluster-routers", 1, 1),
+	).Build()
+
+	replicas := getHPAutoScalerReplicas(context.TODO(), sdk, m,
+		map[string]bool{"druid-tiny-cluster-brokers": true, "druid-tiny-cluster-historicals": true}, ls,
+		EmitEventFuncs{record.NewFakeRecorder(100)})
+	expected := []druidv1alpha1.HPAutoScalerReplicas{
+		{Name: "druid-tiny-cluster-brokers", CurrentReplicas: 2, DesiredReplicas: 2},
+		{Name: "druid-tiny-cluster-historicals", CurrentReplicas: 3, DesiredReplicas: 4},
+	}
+	if !reflect.DeepEqual(replicas, expected) {
+		t.Errorf("expected %v, got %v", expected, replicas)
+	}
+}
+
+func TestStringifyForLoggingRedactsSecret(t *testing.T) {
+	m := &druidv1alpha1.Druid{ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "druid-tiny-cluster-tls", Namespace: "druid"},
+		Data:       map[string][]byte{"tls.key": []byte("private-key")},
+		StringData: map[string]string{"password": "secret-password"},
+	}
+
+	actual := stringifyForLogging(secret, m)
+	for _, value := range []string{"private-key", "cHJpdmF0ZS1rZXk=", "secret-password"} {
+		if strings.Contains(actual, value) {
+			t.Errorf("expected %s to be redacted, got %s", value, actual)
+		}
+	}
+	for _, key := range []string{"druid-tiny-cluster-tls", "tls.key", "password"} {
+		if !strings.Contains(actual, key) {
+			t.Errorf("expected %s to be logged, got %s", key, actual)
+		}
+	}
+	if string(secret.Data["tls.key"]) != "private-key" || secret.StringData["password"] != "secret-password" {
+		t.Errorf("expected the secret not to be modified, got %v", secret)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 type DruidNodeStatus string
@@ -56,17 +57,25 @@ const (
 	druidNodePatchFail     druidEventReason = "DruidOperatorPatchFail"
 	druidNodePatchSucess   druidEventReason = "DruidOperatorPatchSuccess"
 	druidObjectListFail    druidEventReason = "DruidOperatorListFail"
+	druidNodeApplySuccess  druidEventReason = "DruidOperatorApplySuccess"
+	druidNodeApplyFail     druidEventReason = "DruidOperatorApplyFail"
+	druidNodeApplyConflict druidEventReason = "DruidOperatorApplyConflict"
 
 	druidFinalizerTriggered druidEventReason = "DruidOperatorFinalizerTriggered"
 	druidFinalizerFailed    druidEventReason = "DruidFinalizerFailed"
 	druidFinalizerSuccess   druidEventReason = "DruidFinalizerSuccess"
 
+	druidReferenceNotFound druidEventReason = "DruidOperatorReferenceNotFound"
+
 	druidGetRouterSvcUrlFailed     druidEventReason = "DruidAPIGetRouterSvcUrlFailed"
 	druidGetAuthCredsFailed        druidEventReason = "DruidAPIGetAuthCredsFailed"
 	druidFetchCurrentConfigsFailed druidEventReason = "DruidAPIFetchCurrentConfigsFailed"
 	druidConfigComparisonFailed    druidEventReason = "DruidAPIConfigComparisonFailed"
 	druidUpdateConfigsFailed       druidEventReason = "DruidAPIUpdateConfigsFailed"
 	druidUpdateConfigsSuccess      druidEventReason = "DruidAPIUpdateConfigsSuccess"
+
+	druidTaskSlotAutoScaleFailed  druidEventReason = "DruidOperatorTaskSlotAutoScaleFail"
+	druidTaskSlotAutoScaleSuccess druidEventReason = "DruidOperatorTaskSlotAutoScaleSuccess"
 )
 
 // Reader Interface
@@ -81,6 +90,7 @@ type Writer interface {
 	Create(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, obj object, emitEvent EventEmitter) (DruidNodeStatus, error)
 	Update(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, obj object, emitEvent EventEmitter) (DruidNodeStatus, error)
 	Patch(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, obj object, status bool, patch client.Patch, emitEvent EventEmitter) error
+	Apply(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, obj object, emitEvent EventEmitter, applyOptions ...client.PatchOption) error
 }
 
 // EventEmitter Interface is a wrapper interface for all the emitter interface druid operator shall support.
@@ -105,6 +115,7 @@ type K8sEventEmitter interface {
 	EmitEventOnDelete(obj, deleteObj object, err error)
 	EmitEventOnCreate(obj, createObj object, err error)
 	EmitEventOnPatch(obj, patchObj object, err error)
+	EmitEventOnApply(obj, applyObj object, err error)
 	EmitEventOnList(obj object, listObj objectList, err error)
 }
 
@@ -161,6 +172,29 @@ func (f WriterFuncs) Patch(ctx context.Context, sdk client.Client, drd *v1alpha1
 	return nil
 }
 
+// Apply method shall apply the object with server-side apply, as the druid-operator field manager. The object
+// holds the fields owned by the operator only, the ones set by other managers are left as is.
+func (f WriterFuncs) Apply(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, obj object, emitEvent EventEmitter, applyOptions ...client.PatchOption) error {
+
+	if obj.GetObjectKind().GroupVersionKind().Empty() {
+		gvk, err := apiutil.GVKForObject(obj, sdk.Scheme())
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+
+	applyOptions = append([]client.PatchOption{client.FieldOwner(fieldManager)}, applyOptions...)
+	if err := sdk.Patch(ctx, obj, client.Apply, applyOptions...); err != nil {
+		emitEvent.EmitEventOnApply(drd, obj, err)
+		return err
+	}
+	emitEvent.EmitEventOnApply(drd, obj, nil)
+	return nil
+}
+
 // Update Func shall update the Object
 func (f WriterFuncs) Update(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, obj object, emitEvent EventEmitter) (DruidNodeStatus, error) {
 
@@ -295,6 +329,21 @@ func (e EmitEventFuncs) EmitEventOnCreate(obj, createObj object, err error) {
 	}
 }
 
+// EmitEventOnApply shall emit event on server-side APPLY operation, the conflicts with other field managers
+// are reported on their own
+func (e EmitEventFuncs) EmitEventOnApply(obj, applyObj object, err error) {
+	if err != nil && apierrors.IsConflict(err) {
+		errMsg := fmt.Errorf("Object [%s:%s] in namespace [%s] has fields managed by other field managers, set spec.serverSideApply.forceConflicts to take them over: [%s]", applyObj.GetName(), detectType(applyObj), applyObj.GetNamespace(), err.Error())
+		e.Event(obj, v1.EventTypeWarning, string(druidNodeApplyConflict), errMsg.Error())
+	} else if err != nil {
+		errMsg := fmt.Errorf("Error applying object [%s:%s] in namespace [%s] due to [%s]", applyObj.GetName(), detectType(applyObj), applyObj.GetNamespace(), err.Error())
+		e.Event(obj, v1.EventTypeWarning, string(druidNodeApplyFail), errMsg.Error())
+	} else {
+		msg := fmt.Sprintf("Successfully applied object [%s:%s] in namespace [%s]", applyObj.GetName(), detectType(applyObj), applyObj.GetNamespace())
+		e.Event(obj, v1.EventTypeNormal, string(druidNodeApplySuccess), msg)
+	}
+}
+
 // EmitEventOnPatch shall emit event on PATCH operation
 func (e EmitEventFuncs) EmitEventOnPatch(obj, patchObj object, err error) {
 	if err != nil {
@@ -0,0 +1,206 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// configMapReferenceIndex and secretReferenceIndex index the Druid CRs by the namespace/name of the configmaps
+	// and secrets they reference.
+	configMapReferenceIndex = "spec.configMapReferences"
+	secretReferenceIndex    = "spec.secretReferences"
+
+	configMapReference = "configmap"
+	secretReference    = "secret"
+)
+
+// druidReference is a configmap or a secret referenced by a Druid CR. The key is empty for the configmaps of
+// the extraCommonConfig, which are copied whole.
+type druidReference struct {
+	kind      string
+	namespace string
+	name      string
+	key       string
+}
+
+func (r druidReference) String() string {
+	if r.key == "" {
+		return fmt.Sprintf("%s %s/%s", r.kind, r.namespace, r.name)
+	}
+	return fmt.Sprintf("key %q of %s %s/%s", r.key, r.kind, r.namespace, r.name)
+}
+
+// getReferences returns the configmaps and secrets referenced by the extraCommonConfig and by the runtime
+// properties of a Druid CR.
+func getReferences(m *v1alpha1.Druid) []druidReference {
+	var references []druidReference
+	for _, cmRef := range m.Spec.ExtraCommonConfig {
+		if cmRef == nil {
+			continue
+		}
+		references = append(references, druidReference{
+			kind:      configMapReference,
+			namespace: firstNonEmptyStr(cmRef.Namespace, m.Namespace),
+			name:      cmRef.Name,
+		})
+	}
+
+	properties := []string{m.Spec.CommonRuntimeProperties}
+	for _, nodeSpec := range m.Spec.Nodes {
+		properties = append(properties, nodeSpec.RuntimeProperties)
+	}
+	for _, prop := range properties {
+		for _, match := range propertyReference.FindAllStringSubmatch(prop, -1) {
+			references = append(references, druidReference{
+				kind:      match[1],
+				namespace: m.Namespace,
+				name:      match[2],
+				key:       match[4],
+			})
+		}
+	}
+	return references
+}
+
+// indexReferences returns the indexer of the Druid CRs by the namespace/name of the references of a kind.
+func indexReferences(kind string) client.IndexerFunc {
+	return func(obj client.Object) []string {
+		m, ok := obj.(*v1alpha1.Druid)
+		if !ok {
+			return nil
+		}
+		keys := map[string]bool{}
+		for _, reference := range getReferences(m) {
+			if reference.kind == kind {
+				keys[reference.namespace+"/"+reference.name] = true
+			}
+		}
+		values := make([]string, 0, len(keys))
+		for key := range keys {
+			values = append(values, key)
+		}
+		sort.Strings(values)
+		return values
+	}
+}
+
+// mapReferencedDruids returns the requests of the Druid CRs referencing a configmap or a secret, found by index,
+// so that they are reconciled as soon as it changes.
+func mapReferencedDruids(sdk client.Client, index string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		druids := &v1alpha1.DruidList{}
+		if err := sdk.List(ctx, druids, client.MatchingFields{index: obj.GetNamespace() + "/" + obj.GetName()}); err != nil {
+			logger.Error(err, "failed to list the Druid CRs referencing an object", "index", index,
+				"name", obj.GetName(), "namespace", obj.GetNamespace())
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(druids.Items))
+		for _, drd := range druids.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: drd.Name, Namespace: drd.Namespace},
+			})
+		}
+		return requests
+	}
+}
+
+// getMissingReferences returns the references of a Druid CR which are not found.
+func getMissingReferences(ctx context.Context, sdk client.Client, m *v1alpha1.Druid) ([]string, error) {
+	var missing []string
+	for _, reference := range getReferences(m) {
+		var found bool
+		if reference.kind == secretReference {
+			secret := &v1.Secret{}
+			err := sdk.Get(ctx, *namespacedName(reference.name, reference.namespace), secret)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			_, hasKey := secret.Data[reference.key]
+			found = err == nil && hasKey
+		} else {
+			cm := &v1.ConfigMap{}
+			err := sdk.Get(ctx, *namespacedName(reference.name, reference.namespace), cm)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			_, hasKey := cm.Data[reference.key]
+			found = err == nil && (reference.key == "" || hasKey)
+		}
+		if !found {
+			missing = append(missing, reference.String())
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// updateReferencesCondition sets the ReferencesResolved condition of a Druid CR, and emits an event when a
+// reference goes missing.
+func updateReferencesCondition(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, emitEvents EventEmitter) error {
+	missing, err := getMissingReferences(ctx, sdk, m)
+	if err != nil {
+		return err
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.DruidConditionReferencesResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            "all the referenced configmaps and secrets are found",
+		ObservedGeneration: m.Generation,
+	}
+	if len(missing) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ReferenceNotFound"
+		condition.Message = fmt.Sprintf("not found: %s", strings.Join(missing, ", "))
+	}
+
+	current := meta.FindStatusCondition(m.Status.Conditions, condition.Type)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	if len(missing) > 0 && (current == nil || current.Message != condition.Message) {
+		emitEvents.EmitEventGeneric(m, string(druidReferenceNotFound), "", fmt.Errorf("%s", condition.Message))
+	}
+
+	conditions := append([]metav1.Condition{}, m.Status.Conditions...)
+	meta.SetStatusCondition(&conditions, condition)
+	patchBytes, err := json.Marshal(map[string]interface{}{"status": map[string]interface{}{"conditions": conditions}})
+	if err != nil {
+		return fmt.Errorf("failed to serialize status patch to bytes: %v", err)
+	}
+	return writers.Patch(ctx, sdk, m, m, true, client.RawPatch(types.MergePatchType, patchBytes), emitEvents)
+}
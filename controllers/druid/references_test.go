@@ -0,0 +1,183 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newReferencesTestClient(t *testing.T, objs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatalf("failed to add the client-go scheme: %v", err)
+	}
+	if err := druidv1alpha1.AddToScheme(s); err != nil {
+		t.Fatalf("failed to add the druid scheme: %v", err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(objs...).
+		WithStatusSubresource(&druidv1alpha1.Druid{}).
+		WithIndex(&druidv1alpha1.Druid{}, configMapReferenceIndex, indexReferences(configMapReference)).
+		WithIndex(&druidv1alpha1.Druid{}, secretReferenceIndex, indexReferences(secretReference)).
+		Build()
+}
+
+func newReferencingDruid() *druidv1alpha1.Druid {
+	return &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid"},
+		Spec: druidv1alpha1.DruidSpec{
+			CommonRuntimeProperties: "druid.metadata.storage.connector.password=${secret:metadata/password}",
+			ExtraCommonConfig:       []*v1.ObjectReference{{Name: "extra"}, {Name: "shared", Namespace: "common"}},
+			Nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"brokers": {NodeType: broker, RuntimeProperties: "druid.processing.numThreads=${configmap:settings/threads}"},
+			},
+		},
+	}
+}
+
+func TestMapReferencedDruids(t *testing.T) {
+	sdk := newReferencesTestClient(t, newReferencingDruid())
+	ctx := context.TODO()
+	expected := []reconcile.Request{{NamespacedName: *namespacedName("tiny-cluster", "druid")}}
+
+	tests := []struct {
+		name     string
+		index    string
+		obj      client.Object
+		expected []reconcile.Request
+	}{
+		{
+			name:     "extraCommonConfig in the namespace of the CR",
+			index:    configMapReferenceIndex,
+			obj:      &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "extra", Namespace: "druid"}},
+			expected: expected,
+		},
+		{
+			name:     "extraCommonConfig in another namespace",
+			index:    configMapReferenceIndex,
+			obj:      &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "common"}},
+			expected: expected,
+		},
+		{
+			name:     "configmap of the runtime properties",
+			index:    configMapReferenceIndex,
+			obj:      &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "druid"}},
+			expected: expected,
+		},
+		{
+			name:     "secret of the common runtime properties",
+			index:    secretReferenceIndex,
+			obj:      &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "metadata", Namespace: "druid"}},
+			expected: expected,
+		},
+		{
+			name:     "metadata of a secret",
+			index:    secretReferenceIndex,
+			obj:      &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "metadata", Namespace: "druid"}},
+			expected: expected,
+		},
+		{
+			name:     "unreferenced configmap",
+			index:    configMapReferenceIndex,
+			obj:      &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "metadata", Namespace: "druid"}},
+			expected: []reconcile.Request{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := mapReferencedDruids(sdk, tc.index)(ctx, tc.obj)
+			if !reflect.DeepEqual(requests, tc.expected) {
+				t.Errorf("expected requests %v, got %v", tc.expected, requests)
+			}
+		})
+	}
+}
+
+func TestUpdateReferencesCondition(t *testing.T) {
+	drd := newReferencingDruid()
+	sdk := newReferencesTestClient(t, drd,
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "extra", Namespace: "druid"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "common"}},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "druid"}},
+	)
+	ctx := context.TODO()
+	recorder := record.NewFakeRecorder(100)
+	emitEvent := EmitEventFuncs{recorder}
+
+	if err := updateReferencesCondition(ctx, sdk, drd, emitEvent); err != nil {
+		t.Fatalf("failed to update the condition: %v", err)
+	}
+	condition := meta.FindStatusCondition(drd.Status.Conditions, druidv1alpha1.DruidConditionReferencesResolved)
+	if condition == nil || condition.Status != metav1.ConditionFalse {
+		t.Fatalf("expected the condition to be false, got %+v", condition)
+	}
+	for _, missing := range []string{`key "threads" of configmap druid/settings`, `key "password" of secret druid/metadata`} {
+		if !strings.Contains(condition.Message, missing) {
+			t.Errorf("expected the message %q to report %s", condition.Message, missing)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected an event for the missing references, got %d", len(recorder.Events))
+	}
+
+	if err := sdk.Create(ctx, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "metadata", Namespace: "druid"},
+		Data:       map[string][]byte{"password": []byte("s3cr3t")},
+	}); err != nil {
+		t.Fatalf("failed to create the secret: %v", err)
+	}
+	settings := &v1.ConfigMap{}
+	if err := sdk.Get(ctx, *namespacedName("settings", "druid"), settings); err != nil {
+		t.Fatalf("failed to get the configmap: %v", err)
+	}
+	settings.Data = map[string]string{"threads": "4"}
+	if err := sdk.Update(ctx, settings); err != nil {
+		t.Fatalf("failed to update the configmap: %v", err)
+	}
+
+	if err := updateReferencesCondition(ctx, sdk, drd, emitEvent); err != nil {
+		t.Fatalf("failed to update the condition: %v", err)
+	}
+	stored := &druidv1alpha1.Druid{}
+	if err := sdk.Get(ctx, *namespacedName("tiny-cluster", "druid"), stored); err != nil {
+		t.Fatalf("failed to get the Druid: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(stored.Status.Conditions, druidv1alpha1.DruidConditionReferencesResolved) {
+		t.Errorf("expected the condition to be true, got %+v", stored.Status.Conditions)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected no event once the references are found, got %d", len(recorder.Events))
+	}
+}
@@ -0,0 +1,452 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultScaleUpCooldown      = time.Minute
+	defaultScaleDownCooldown    = 10 * time.Minute
+	defaultAutoScalerPollPeriod = 30 * time.Second
+)
+
+// taskSlotAutoScalerNodeTypes are the node types running the ingestion tasks, which can be scaled by a
+// taskSlotAutoScaler.
+var taskSlotAutoScalerNodeTypes = []string{middleManager, indexer}
+
+// druidWorker is a worker as listed by the overlord.
+type druidWorker struct {
+	Worker struct {
+		Host     string `json:"host"`
+		IP       string `json:"ip"`
+		Capacity int32  `json:"capacity"`
+	} `json:"worker"`
+	CurrentCapacityUsed int32    `json:"currentCapacityUsed"`
+	RunningTasks        []string `json:"runningTasks"`
+}
+
+// taskSlots is the load of the ingestion tasks, as reported by the overlord.
+type taskSlots struct {
+	workers      []druidWorker
+	pendingTasks int32
+	// clusterCapacity is the task slots of all the workers, -1 when the overlord doesn't know it.
+	clusterCapacity int32
+}
+
+// getTaskSlotAutoScalerNodes returns the keys of the node specs with a taskSlotAutoScaler, sorted.
+func getTaskSlotAutoScalerNodes(drd *v1alpha1.Druid) []string {
+	var keys []string
+	for key, nodeSpec := range drd.Spec.Nodes {
+		if nodeSpec.TaskSlotAutoScaler != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateTaskSlotAutoScalers makes sure the taskSlotAutoScalers scale the StatefulSets of middleManagers or
+// indexers without an HPA. A single node spec can set one, the pending tasks are not assigned to a node spec.
+func validateTaskSlotAutoScalers(drd *v1alpha1.Druid) error {
+	keys := getTaskSlotAutoScalerNodes(drd)
+	if len(keys) > 1 {
+		return fmt.Errorf("nodes [%s] all set a taskSlotAutoScaler, only one of them can", strings.Join(keys, ","))
+	}
+	for _, key := range keys {
+		nodeSpec := drd.Spec.Nodes[key]
+		autoScaler := nodeSpec.TaskSlotAutoScaler
+		switch {
+		case !containsString(taskSlotAutoScalerNodeTypes, nodeSpec.NodeType):
+			return fmt.Errorf("node[%s] of nodeType [%s] sets a taskSlotAutoScaler, only the %s can",
+				key, nodeSpec.NodeType, strings.Join(taskSlotAutoScalerNodeTypes, " and "))
+		case nodeSpec.Kind == "Deployment":
+			return fmt.Errorf("node[%s] sets a taskSlotAutoScaler, it can't run as a Deployment", key)
+		case nodeSpec.HPAutoScaler != nil:
+			return fmt.Errorf("node[%s] sets both a taskSlotAutoScaler and an hpAutoscaler", key)
+		case autoScaler.MinReplicas < 1 || autoScaler.MinReplicas > autoScaler.MaxReplicas:
+			return fmt.Errorf("node[%s] taskSlotAutoScaler has invalid replicas, min [%d] max [%d]",
+				key, autoScaler.MinReplicas, autoScaler.MaxReplicas)
+		}
+	}
+	return nil
+}
+
+// getTaskSlotAutoScalerPollPeriod returns the shortest poll period of the taskSlotAutoScalers, 0 when there is none.
+func getTaskSlotAutoScalerPollPeriod(drd *v1alpha1.Druid) time.Duration {
+	var period time.Duration
+	for _, key := range getTaskSlotAutoScalerNodes(drd) {
+		p := getDuration(drd.Spec.Nodes[key].TaskSlotAutoScaler.PollPeriod, defaultAutoScalerPollPeriod)
+		if period == 0 || p < period {
+			period = p
+		}
+	}
+	return period
+}
+
+func getDuration(d *metav1.Duration, defaultDuration time.Duration) time.Duration {
+	if d != nil && d.Duration > 0 {
+		return d.Duration
+	}
+	return defaultDuration
+}
+
+// autoScaleTaskSlots scales the StatefulSets of the node specs with a taskSlotAutoScaler with the task slots
+// needed by the ingestion tasks, and records the state of the autoscalers in the status of the Druid CR.
+func autoScaleTaskSlots(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, emitEvent EventEmitter) error {
+	keys := getTaskSlotAutoScalerNodes(drd)
+	if len(keys) == 0 && len(drd.Status.TaskSlotAutoScalers) == 0 {
+		return nil
+	}
+
+	svcName, httpClient, err := newDruidAPIClient(ctx, sdk, drd, druidapi.IndexerAPI, emitEvent)
+	if err != nil {
+		return err
+	}
+
+	var slots *taskSlots
+	if len(keys) > 0 {
+		if slots, err = getTaskSlots(httpClient, svcName); err != nil {
+			emitEvent.EmitEventGeneric(drd, string(druidTaskSlotAutoScaleFailed), "", err)
+			return err
+		}
+	}
+
+	now := metav1.Now()
+	var statuses []v1alpha1.TaskSlotAutoScalerStatus
+	for _, key := range keys {
+		nodeSpec := drd.Spec.Nodes[key]
+		status, err := autoScaleNodeTaskSlots(ctx, sdk, drd, httpClient, svcName, nodeSpec.TaskSlotAutoScaler,
+			makeNodeSpecificUniqueString(drd, key), slots, now, emitEvent)
+		if err != nil {
+			emitEvent.EmitEventGeneric(drd, string(druidTaskSlotAutoScaleFailed), "", err)
+			return err
+		}
+		if status != nil {
+			statuses = append(statuses, *status)
+		}
+	}
+
+	// the workers left disabled by a taskSlotAutoScaler removed while they were draining are enabled again
+	for _, prev := range drd.Status.TaskSlotAutoScalers {
+		if findTaskSlotAutoScalerStatus(statuses, prev.Name) == nil {
+			if err := setWorkersEnabled(httpClient, svcName, prev.DrainingWorkers, true); err != nil {
+				emitEvent.EmitEventGeneric(drd, string(druidTaskSlotAutoScaleFailed), "", err)
+				return err
+			}
+		}
+	}
+
+	if reflect.DeepEqual(statuses, drd.Status.TaskSlotAutoScalers) {
+		return nil
+	}
+	patchBytes, err := json.Marshal(map[string]interface{}{"status": map[string]interface{}{"taskSlotAutoScalers": statuses}})
+	if err != nil {
+		return fmt.Errorf("failed to serialize status patch to bytes: %v", err)
+	}
+	return writers.Patch(ctx, sdk, drd, drd, true, client.RawPatch(types.MergePatchType, patchBytes), emitEvent)
+}
+
+// autoScaleNodeTaskSlots scales the StatefulSet of a node spec with its taskSlotAutoScaler and returns the state
+// of the autoscaler. The replicas are raised right away once the scale up cooldown elapsed. They are lowered once
+// the scale down cooldown elapsed, after the workers of the removed pods were disabled and ran their tasks to
+// completion, so that no task is killed.
+func autoScaleNodeTaskSlots(
+	ctx context.Context,
+	sdk client.Client,
+	drd *v1alpha1.Druid,
+	httpClient internalhttp.DruidHTTP,
+	svcName string,
+	autoScaler *v1alpha1.TaskSlotAutoScalerSpec,
+	nodeSpecUniqueStr string,
+	slots *taskSlots,
+	now metav1.Time,
+	emitEvent EventEmitter,
+) (*v1alpha1.TaskSlotAutoScalerStatus, error) {
+	sts := &appsv1.StatefulSet{}
+	if err := sdk.Get(ctx, *namespacedName(nodeSpecUniqueStr, drd.Namespace), sts); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the StatefulSet waits for the rolling deploy of the other nodes
+			return findTaskSlotAutoScalerStatus(drd.Status.TaskSlotAutoScalers, nodeSpecUniqueStr), nil
+		}
+		return nil, err
+	}
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	pods, err := readers.List(ctx, sdk, drd, sts.Spec.Selector.MatchLabels, emitEvent, func() objectList { return &v1.PodList{} }, func(listObj runtime.Object) []object {
+		items := listObj.(*v1.PodList).Items
+		result := make([]object, len(items))
+		for i := 0; i < len(items); i++ {
+			result[i] = &items[i]
+		}
+		return result
+	})
+	if err != nil {
+		return nil, err
+	}
+	workers := getStatefulSetWorkers(sts.Name, pods, slots.workers)
+	desired := getDesiredTaskSlotReplicas(autoScaler, replicas, workers, slots)
+
+	status := &v1alpha1.TaskSlotAutoScalerStatus{Name: sts.Name, DesiredReplicas: replicas}
+	if prev := findTaskSlotAutoScalerStatus(drd.Status.TaskSlotAutoScalers, sts.Name); prev != nil {
+		status = prev.DeepCopy()
+	}
+
+	if len(status.DrainingWorkers) > 0 {
+		if desired > status.DesiredReplicas {
+			// the load rose while the workers were draining, they run tasks again
+			if err := setWorkersEnabled(httpClient, svcName, status.DrainingWorkers, true); err != nil {
+				return nil, err
+			}
+			emitEvent.EmitEventGeneric(drd, string(druidTaskSlotAutoScaleSuccess),
+				fmt.Sprintf("Cancelled the scale down of StatefulSet[%s] to [%d] replicas, enabled workers [%s]",
+					sts.Name, status.DesiredReplicas, strings.Join(status.DrainingWorkers, ",")), nil)
+			status.DesiredReplicas = replicas
+			status.DrainingWorkers = nil
+			return status, nil
+		}
+		if !areWorkersDrained(slots.workers, status.DrainingWorkers) {
+			return status, nil
+		}
+		if err := scaleStatefulSet(ctx, sdk, drd, sts, status.DesiredReplicas, emitEvent); err != nil {
+			return nil, err
+		}
+		status.DrainingWorkers = nil
+		status.LastScaleTime = &now
+		return status, nil
+	}
+
+	status.DesiredReplicas = replicas
+	switch {
+	case desired > replicas && (replicas < autoScaler.MinReplicas ||
+		isCooldownElapsed(status.LastScaleTime, autoScaler.ScaleUpCooldown, defaultScaleUpCooldown, now)):
+		if err := scaleStatefulSet(ctx, sdk, drd, sts, desired, emitEvent); err != nil {
+			return nil, err
+		}
+		status.DesiredReplicas = desired
+		status.LastScaleTime = &now
+	case desired < replicas && isCooldownElapsed(status.LastScaleTime, autoScaler.ScaleDownCooldown, defaultScaleDownCooldown, now):
+		// the StatefulSet removes the pods of the highest ordinals, their workers stop taking tasks first
+		var hosts []string
+		for ordinal, worker := range workers {
+			if ordinal >= int(desired) {
+				hosts = append(hosts, worker.Worker.Host)
+			}
+		}
+		sort.Strings(hosts)
+		status.DesiredReplicas = desired
+		if len(hosts) == 0 {
+			if err := scaleStatefulSet(ctx, sdk, drd, sts, desired, emitEvent); err != nil {
+				return nil, err
+			}
+			status.LastScaleTime = &now
+			return status, nil
+		}
+		if err := setWorkersEnabled(httpClient, svcName, hosts, false); err != nil {
+			return nil, err
+		}
+		emitEvent.EmitEventGeneric(drd, string(druidTaskSlotAutoScaleSuccess),
+			fmt.Sprintf("Disabled workers [%s] to scale StatefulSet[%s] down to [%d] replicas once they are drained",
+				strings.Join(hosts, ","), sts.Name, desired), nil)
+		status.DrainingWorkers = hosts
+	}
+	return status, nil
+}
+
+// getDesiredTaskSlotReplicas returns the replicas needed by the tasks running on the workers of the StatefulSet,
+// and by the pending tasks the other workers have no free slot for, within the limits of the autoscaler. The
+// replicas are unchanged until a worker of the StatefulSet reports its capacity.
+func getDesiredTaskSlotReplicas(autoScaler *v1alpha1.TaskSlotAutoScalerSpec, replicas int32, workers map[int]druidWorker, slots *taskSlots) int32 {
+	var workerCapacity, capacity, used, clusterUsed int32
+	for _, worker := range workers {
+		if worker.Worker.Capacity > workerCapacity {
+			workerCapacity = worker.Worker.Capacity
+		}
+		capacity += worker.Worker.Capacity
+		used += worker.CurrentCapacityUsed
+	}
+	for _, worker := range slots.workers {
+		clusterUsed += worker.CurrentCapacityUsed
+	}
+
+	desired := replicas
+	if workerCapacity > 0 {
+		var otherIdle int32
+		if slots.clusterCapacity >= 0 && (slots.clusterCapacity-capacity)-(clusterUsed-used) > 0 {
+			otherIdle = (slots.clusterCapacity - capacity) - (clusterUsed - used)
+		}
+		needed := used
+		if pending := slots.pendingTasks - otherIdle; pending > 0 {
+			needed += pending
+		}
+		desired = (needed + workerCapacity - 1) / workerCapacity
+	}
+
+	if desired < autoScaler.MinReplicas {
+		return autoScaler.MinReplicas
+	}
+	if desired > autoScaler.MaxReplicas {
+		return autoScaler.MaxReplicas
+	}
+	return desired
+}
+
+// getStatefulSetWorkers returns the workers of the pods of a StatefulSet by ordinal. A worker belongs to a pod
+// when its host is the name or the IP of the pod.
+func getStatefulSetWorkers(stsName string, pods []object, workers []druidWorker) map[int]druidWorker {
+	result := map[int]druidWorker{}
+	for _, obj := range pods {
+		pod := obj.(*v1.Pod)
+		if !strings.HasPrefix(pod.Name, stsName+"-") {
+			continue
+		}
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, stsName+"-"))
+		if err != nil {
+			continue
+		}
+		for _, worker := range workers {
+			host := worker.Worker.Host
+			if i := strings.LastIndex(host, ":"); i >= 0 {
+				host = host[:i]
+			}
+			if host == pod.Name || strings.HasPrefix(host, pod.Name+".") ||
+				(pod.Status.PodIP != "" && (host == pod.Status.PodIP || worker.Worker.IP == pod.Status.PodIP)) {
+				result[ordinal] = worker
+				break
+			}
+		}
+	}
+	return result
+}
+
+// areWorkersDrained reports whether the workers of the hosts run no task anymore, or are gone.
+func areWorkersDrained(workers []druidWorker, hosts []string) bool {
+	for _, worker := range workers {
+		if containsString(hosts, worker.Worker.Host) && (worker.CurrentCapacityUsed > 0 || len(worker.RunningTasks) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isCooldownElapsed(lastScaleTime *metav1.Time, cooldown *metav1.Duration, defaultCooldown time.Duration, now metav1.Time) bool {
+	return lastScaleTime == nil || now.Sub(lastScaleTime.Time) >= getDuration(cooldown, defaultCooldown)
+}
+
+func findTaskSlotAutoScalerStatus(statuses []v1alpha1.TaskSlotAutoScalerStatus, name string) *v1alpha1.TaskSlotAutoScalerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+func scaleStatefulSet(ctx context.Context, sdk client.Client, drd *v1alpha1.Druid, sts *appsv1.StatefulSet, replicas int32, emitEvent EventEmitter) error {
+	patch := client.RawPatch(types.MergePatchType, []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas)))
+	if err := writers.Patch(ctx, sdk, drd, sts, false, patch, emitEvent); err != nil {
+		return err
+	}
+	emitEvent.EmitEventGeneric(drd, string(druidTaskSlotAutoScaleSuccess),
+		fmt.Sprintf("Scaled StatefulSet[%s] to [%d] replicas", sts.Name, replicas), nil)
+	return nil
+}
+
+// getTaskSlots reads the workers, the pending tasks and the capacity of the cluster from the overlord.
+func getTaskSlots(httpClient internalhttp.DruidHTTP, svcName string) (*taskSlots, error) {
+	slots := &taskSlots{}
+	if err := getDruidJson(httpClient, druidapi.MakePath(svcName, "indexer", "workers"), &slots.workers); err != nil {
+		return nil, err
+	}
+
+	var pendingTasks []json.RawMessage
+	if err := getDruidJson(httpClient, druidapi.MakePath(svcName, "indexer", "pendingTasks"), &pendingTasks); err != nil {
+		return nil, err
+	}
+	slots.pendingTasks = int32(len(pendingTasks))
+
+	var capacity struct {
+		CurrentClusterCapacity *int32 `json:"currentClusterCapacity"`
+	}
+	if err := getDruidJson(httpClient, druidapi.MakePath(svcName, "indexer", "totalWorkerCapacity"), &capacity); err != nil {
+		return nil, err
+	}
+	slots.clusterCapacity = -1
+	if capacity.CurrentClusterCapacity != nil {
+		slots.clusterCapacity = *capacity.CurrentClusterCapacity
+	}
+	return slots, nil
+}
+
+func getDruidJson(httpClient internalhttp.DruidHTTP, path string, v interface{}) error {
+	resp, err := httpClient.Do(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get [%s], status code: %d, response body: %s", path, resp.StatusCode, resp.ResponseBody)
+	}
+	if err := json.Unmarshal([]byte(resp.ResponseBody), v); err != nil {
+		return fmt.Errorf("failed to parse the response of [%s]: %v", path, err)
+	}
+	return nil
+}
+
+// setWorkersEnabled enables or disables the workers of the hosts, a disabled worker is assigned no new task.
+func setWorkersEnabled(httpClient internalhttp.DruidHTTP, svcName string, hosts []string, enabled bool) error {
+	action := "disable"
+	if enabled {
+		action = "enable"
+	}
+	for _, host := range hosts {
+		path := druidapi.MakePath(svcName, "indexer", "worker", host, action)
+		resp, err := httpClient.Do(http.MethodPost, path, nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to %s worker [%s], status code: %d, response body: %s",
+				action, host, resp.StatusCode, resp.ResponseBody)
+		}
+	}
+	return nil
+}
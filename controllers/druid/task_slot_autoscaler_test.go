@@ -0,0 +1,359 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/datainfrahq/druid-operator/pkg/druidapi"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalev2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// overlordStub is a stand-in for the router in front of the overlord, it serves the workers, the pending tasks
+// and the capacity of the cluster, and records the workers enabled and disabled.
+type overlordStub struct {
+	workers      string
+	pendingTasks int
+	capacity     int
+	calls        []string
+}
+
+func (s *overlordStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost:
+		s.calls = append(s.calls, r.Method+" "+r.URL.Path)
+	case r.URL.Path == "/druid/indexer/v1/workers":
+		_, _ = w.Write([]byte(s.workers))
+	case r.URL.Path == "/druid/indexer/v1/pendingTasks":
+		_, _ = w.Write([]byte("[" + strings.TrimSuffix(strings.Repeat(`{"id": "task"},`, s.pendingTasks), ",") + "]"))
+	case r.URL.Path == "/druid/indexer/v1/totalWorkerCapacity":
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"currentClusterCapacity": %d}`, s.capacity)))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// makeStubWorkers returns the workers of the middleManagers pods, by the slots used and the tasks running on them.
+func makeStubWorkers(used ...int) string {
+	var workers []string
+	for i, u := range used {
+		var tasks []string
+		for j := 0; j < u; j++ {
+			tasks = append(tasks, fmt.Sprintf(`"task-%d-%d"`, i, j))
+		}
+		workers = append(workers, fmt.Sprintf(
+			`{"worker": {"host": "druid-tiny-cluster-middlemanagers-%d.druid-tiny-cluster-middlemanagers.druid.svc.cluster.local:8091", "capacity": 2}, "currentCapacityUsed": %d, "runningTasks": [%s]}`,
+			i, u, strings.Join(tasks, ",")))
+	}
+	return "[" + strings.Join(workers, ",") + "]"
+}
+
+// enableAPIEndpoint lets the Druid of the test reach the stub through its apiEndpoint.
+func enableAPIEndpoint(t *testing.T) {
+	druidapi.EnableAPIEndpoint = true
+	t.Cleanup(func() { druidapi.EnableAPIEndpoint = false })
+}
+
+func TestAutoScaleTaskSlots(t *testing.T) {
+	stub := &overlordStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	enableAPIEndpoint(t)
+
+	drd := &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid"},
+		Spec: druidv1alpha1.DruidSpec{
+			APIEndpoint: server.URL,
+			Nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {
+					NodeType: middleManager,
+					TaskSlotAutoScaler: &druidv1alpha1.TaskSlotAutoScalerSpec{
+						MinReplicas: 1,
+						MaxReplicas: 5,
+					},
+				},
+			},
+		},
+	}
+	replicas := int32(2)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "druid-tiny-cluster-middlemanagers", Namespace: "druid"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"component": middleManager}},
+		},
+	}
+	objs := []client.Object{drd, sts}
+	for i := 0; i < 3; i++ {
+		objs = append(objs, &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("druid-tiny-cluster-middlemanagers-%d", i),
+			Namespace: "druid",
+			Labels:    map[string]string{"component": middleManager},
+		}})
+	}
+	sdk := newReferencesTestClient(t, objs...)
+	emitEvent := EmitEventFuncs{record.NewFakeRecorder(100)}
+
+	autoScale := func() (int32, druidv1alpha1.TaskSlotAutoScalerStatus) {
+		t.Helper()
+		if err := sdk.Get(context.TODO(), client.ObjectKeyFromObject(drd), drd); err != nil {
+			t.Fatalf("failed to get the druid: %v", err)
+		}
+		if err := autoScaleTaskSlots(context.TODO(), sdk, drd, emitEvent); err != nil {
+			t.Fatalf("failed to autoscale: %v", err)
+		}
+		if err := sdk.Get(context.TODO(), client.ObjectKeyFromObject(sts), sts); err != nil {
+			t.Fatalf("failed to get the statefulset: %v", err)
+		}
+		if len(drd.Status.TaskSlotAutoScalers) != 1 {
+			t.Fatalf("expected the status of the autoscaler, got %v", drd.Status.TaskSlotAutoScalers)
+		}
+		return *sts.Spec.Replicas, drd.Status.TaskSlotAutoScalers[0]
+	}
+
+	// the 2 workers are busy and 3 tasks are pending
+	stub.workers, stub.pendingTasks, stub.capacity = makeStubWorkers(2, 2), 3, 4
+	replicas, status := autoScale()
+	if replicas != 4 || status.DesiredReplicas != 4 || status.LastScaleTime == nil {
+		t.Errorf("expected a scale up to 4 replicas, got %d replicas and status %+v", replicas, status)
+	}
+
+	// the cooldown holds the scale down
+	stub.workers, stub.pendingTasks, stub.capacity = makeStubWorkers(1, 0, 1, 0), 0, 8
+	if replicas, _ = autoScale(); replicas != 4 || len(stub.calls) > 0 {
+		t.Errorf("expected no scale down within the cooldown, got %d replicas and calls %v", replicas, stub.calls)
+	}
+
+	// 2 running tasks need a single worker, the workers of the removed pods are disabled first
+	past := metav1.NewTime(time.Now().Add(-time.Hour))
+	drd.Status.TaskSlotAutoScalers[0].LastScaleTime = &past
+	if err := sdk.Status().Update(context.TODO(), drd); err != nil {
+		t.Fatalf("failed to update the status: %v", err)
+	}
+	replicas, status = autoScale()
+	expectedCalls := []string{
+		"POST /druid/indexer/v1/worker/druid-tiny-cluster-middlemanagers-1.druid-tiny-cluster-middlemanagers.druid.svc.cluster.local:8091/disable",
+		"POST /druid/indexer/v1/worker/druid-tiny-cluster-middlemanagers-2.druid-tiny-cluster-middlemanagers.druid.svc.cluster.local:8091/disable",
+	}
+	if replicas != 4 || status.DesiredReplicas != 1 || len(status.DrainingWorkers) != 2 || !reflect.DeepEqual(stub.calls, expectedCalls) {
+		t.Errorf("expected the workers to drain before the scale down, got %d replicas, status %+v and calls %v",
+			replicas, status, stub.calls)
+	}
+
+	// a task still runs on a draining worker
+	if replicas, _ = autoScale(); replicas != 4 {
+		t.Errorf("expected no scale down while a worker is draining, got %d replicas", replicas)
+	}
+
+	// the draining workers are done
+	stub.workers = makeStubWorkers(1, 0, 0, 0)
+	replicas, status = autoScale()
+	if replicas != 1 || status.DesiredReplicas != 1 || len(status.DrainingWorkers) > 0 {
+		t.Errorf("expected a scale down to 1 replica once drained, got %d replicas and status %+v", replicas, status)
+	}
+}
+
+func TestAutoScaleTaskSlotsCancelsDraining(t *testing.T) {
+	stub := &overlordStub{workers: makeStubWorkers(2, 1), pendingTasks: 2, capacity: 4}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	enableAPIEndpoint(t)
+
+	drd := &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid"},
+		Spec: druidv1alpha1.DruidSpec{
+			APIEndpoint: server.URL,
+			Nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {
+					NodeType:           middleManager,
+					TaskSlotAutoScaler: &druidv1alpha1.TaskSlotAutoScalerSpec{MinReplicas: 1, MaxReplicas: 5},
+				},
+			},
+		},
+		Status: druidv1alpha1.DruidClusterStatus{
+			TaskSlotAutoScalers: []druidv1alpha1.TaskSlotAutoScalerStatus{{
+				Name:            "druid-tiny-cluster-middlemanagers",
+				DesiredReplicas: 1,
+				DrainingWorkers: []string{"druid-tiny-cluster-middlemanagers-1.druid-tiny-cluster-middlemanagers.druid.svc.cluster.local:8091"},
+			}},
+		},
+	}
+	replicas := int32(2)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "druid-tiny-cluster-middlemanagers", Namespace: "druid"},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"component": middleManager}},
+		},
+	}
+	sdk := newReferencesTestClient(t, drd, sts)
+
+	if err := autoScaleTaskSlots(context.TODO(), sdk, drd, EmitEventFuncs{record.NewFakeRecorder(100)}); err != nil {
+		t.Fatalf("failed to autoscale: %v", err)
+	}
+	expectedCalls := []string{
+		"POST /druid/indexer/v1/worker/druid-tiny-cluster-middlemanagers-1.druid-tiny-cluster-middlemanagers.druid.svc.cluster.local:8091/enable",
+	}
+	if !reflect.DeepEqual(stub.calls, expectedCalls) {
+		t.Errorf("expected the draining worker to be enabled as the load rose, got calls %v", stub.calls)
+	}
+	if status := drd.Status.TaskSlotAutoScalers; len(status) != 1 || status[0].DesiredReplicas != 2 || len(status[0].DrainingWorkers) > 0 {
+		t.Errorf("expected the scale down to be cancelled, got status %+v", status)
+	}
+}
+
+func TestGetDesiredTaskSlotReplicas(t *testing.T) {
+	autoScaler := &druidv1alpha1.TaskSlotAutoScalerSpec{MinReplicas: 2, MaxReplicas: 6}
+	worker := func(capacity, used int32) druidWorker {
+		w := druidWorker{CurrentCapacityUsed: used}
+		w.Worker.Capacity = capacity
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		replicas int32
+		workers  map[int]druidWorker
+		slots    taskSlots
+		expected int32
+	}{
+		{
+			name:     "no worker reported yet",
+			replicas: 3,
+			slots:    taskSlots{pendingTasks: 10, clusterCapacity: -1},
+			expected: 3,
+		},
+		{
+			name:     "running and pending tasks",
+			replicas: 2,
+			workers:  map[int]druidWorker{0: worker(3, 3), 1: worker(3, 2)},
+			slots:    taskSlots{workers: []druidWorker{worker(3, 3), worker(3, 2)}, pendingTasks: 4, clusterCapacity: 6},
+			expected: 3,
+		},
+		{
+			name:     "pending tasks fit on the idle slots of the other workers",
+			replicas: 2,
+			workers:  map[int]druidWorker{0: worker(2, 2), 1: worker(2, 2)},
+			slots:    taskSlots{workers: []druidWorker{worker(2, 2), worker(2, 2), worker(4, 0)}, pendingTasks: 3, clusterCapacity: 8},
+			expected: 2,
+		},
+		{
+			name:     "idle workers are limited by the min replicas",
+			replicas: 4,
+			workers:  map[int]druidWorker{0: worker(2, 0), 1: worker(2, 0), 2: worker(2, 0), 3: worker(2, 0)},
+			slots:    taskSlots{clusterCapacity: 8},
+			expected: 2,
+		},
+		{
+			name:     "pending tasks are limited by the max replicas",
+			replicas: 2,
+			workers:  map[int]druidWorker{0: worker(2, 2), 1: worker(2, 2)},
+			slots:    taskSlots{pendingTasks: 50, clusterCapacity: -1},
+			expected: 6,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if desired := getDesiredTaskSlotReplicas(autoScaler, tc.replicas, tc.workers, &tc.slots); desired != tc.expected {
+				t.Errorf("expected %d replicas, got %d", tc.expected, desired)
+			}
+		})
+	}
+}
+
+func TestValidateTaskSlotAutoScalers(t *testing.T) {
+	autoScaler := &druidv1alpha1.TaskSlotAutoScalerSpec{MinReplicas: 1, MaxReplicas: 3}
+	tests := []struct {
+		name    string
+		nodes   map[string]druidv1alpha1.DruidNodeSpec
+		wantErr bool
+	}{
+		{
+			name: "middleManagers",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {NodeType: middleManager, TaskSlotAutoScaler: autoScaler},
+				"brokers":        {NodeType: broker},
+			},
+		},
+		{
+			name:    "brokers",
+			nodes:   map[string]druidv1alpha1.DruidNodeSpec{"brokers": {NodeType: broker, TaskSlotAutoScaler: autoScaler}},
+			wantErr: true,
+		},
+		{
+			name: "several node specs",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {NodeType: middleManager, TaskSlotAutoScaler: autoScaler},
+				"indexers":       {NodeType: indexer, TaskSlotAutoScaler: autoScaler},
+			},
+			wantErr: true,
+		},
+		{
+			name: "deployment",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"indexers": {NodeType: indexer, Kind: "Deployment", TaskSlotAutoScaler: autoScaler},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hpAutoscaler",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {
+					NodeType:           middleManager,
+					TaskSlotAutoScaler: autoScaler,
+					HPAutoScaler:       &autoscalev2.HorizontalPodAutoscalerSpec{MaxReplicas: 3},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "min greater than max",
+			nodes: map[string]druidv1alpha1.DruidNodeSpec{
+				"middlemanagers": {
+					NodeType:           middleManager,
+					TaskSlotAutoScaler: &druidv1alpha1.TaskSlotAutoScalerSpec{MinReplicas: 4, MaxReplicas: 3},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			drd := &druidv1alpha1.Druid{Spec: druidv1alpha1.DruidSpec{Nodes: tc.nodes}}
+			if err := validateTaskSlotAutoScalers(drd); (err != nil) != tc.wantErr {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,564 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const (
+	tlsVolumeName       = "druid-tls"
+	tlsMountPath        = "/druid/tls"
+	tlsKeyStoreKey      = "keystore.p12"
+	tlsTrustStoreKey    = "truststore.p12"
+	tlsStorePasswordKey = "password"
+	tlsStorePasswordEnv = "DRUID_TLS_STORE_PASSWORD"
+	tlsNextCACertKey    = "next.crt"
+	tlsNextCAKeyKey     = "next.key"
+
+	defaultCertificateDuration = 365 * 24 * time.Hour
+	caCertificateDuration      = 10 * 365 * 24 * time.Hour
+)
+
+// tlsIssuer is the source of the certificates of the nodes: the CA issuing them, or the certificate referenced by
+// certificateSecretName. caPEM is the bundle of the CAs trusted by the nodes.
+type tlsIssuer struct {
+	caPEM  []byte
+	caCert *x509.Certificate
+	caKey  crypto.Signer
+
+	// next is the CA the generated one is renewed with, see renewTLSIssuer.
+	next *tlsIssuer
+
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func getTLSCASecretName(m *v1alpha1.Druid) string {
+	return fmt.Sprintf("%s-druid-tls-ca", m.Name)
+}
+
+func getTLSSecretName(nodeSpecUniqueStr string) string {
+	return fmt.Sprintf("%s-tls", nodeSpecUniqueStr)
+}
+
+// makeLabelsForTLS returns the labels of the secrets managed for TLS, the unused ones are deleted by them.
+func makeLabelsForTLS(m *v1alpha1.Druid) map[string]string {
+	labels := makeLabelsForDruid(m)
+	labels["component"] = "tls"
+	return labels
+}
+
+// makeTLSProperties returns the properties enabling the TLS port of the nodes with the keystores mounted by the
+// operator. They are written before the common runtime properties, which can override them.
+func makeTLSProperties(m *v1alpha1.Druid) string {
+	passwordProvider := fmt.Sprintf(`{"type": "environment", "variable": "%s"}`, tlsStorePasswordEnv)
+	props := []string{
+		"druid.enableTlsPort=true",
+		"druid.server.https.keyStoreType=pkcs12",
+		fmt.Sprintf("druid.server.https.keyStorePath=%s/%s", tlsMountPath, tlsKeyStoreKey),
+		"druid.server.https.keyStorePassword=" + passwordProvider,
+		"druid.client.https.trustStoreType=pkcs12",
+		fmt.Sprintf("druid.client.https.trustStorePath=%s/%s", tlsMountPath, tlsTrustStoreKey),
+		"druid.client.https.trustStorePassword=" + passwordProvider,
+	}
+	if !m.Spec.TLS.ValidateHostnames {
+		// the nodes announce their pod IP, which the certificates can't name
+		props = append(props, "druid.client.https.validateHostnames=false")
+	}
+	if m.Spec.TLS.DisablePlaintextPort {
+		props = append(props, "druid.enablePlaintextPort=false")
+	}
+	return strings.Join(props, "\n")
+}
+
+// validateTLS makes sure the default probes, which use the plaintext port, are not set along with
+// disablePlaintextPort.
+func validateTLS(drd *v1alpha1.Druid) error {
+	if drd.Spec.TLS != nil && drd.Spec.TLS.DisablePlaintextPort && drd.Spec.DefaultProbes {
+		return errors.New("tls.disablePlaintextPort can't be set with defaultProbes, the default probes use the plaintext port")
+	}
+	return nil
+}
+
+func getCertificateDuration(m *v1alpha1.Druid) time.Duration {
+	if m.Spec.TLS.CertificateDuration != nil && m.Spec.TLS.CertificateDuration.Duration > 0 {
+		return m.Spec.TLS.CertificateDuration.Duration
+	}
+	return defaultCertificateDuration
+}
+
+// getTLSDNSNames returns the names of the certificate of a node spec: its services and the pods behind them.
+func getTLSDNSNames(nodeSpec *v1alpha1.DruidNodeSpec, m *v1alpha1.Druid, nodeSpecUniqueStr string) []string {
+	serviceNames := map[string]bool{nodeSpecUniqueStr: true}
+	for _, svc := range firstNonNilValue(nodeSpec.Services, m.Spec.Services).([]v1.Service) {
+		serviceNames[getServiceName(svc.ObjectMeta.Name, nodeSpecUniqueStr)] = true
+	}
+
+	var dnsNames []string
+	for name := range serviceNames {
+		for _, domain := range []string{"", "." + m.Namespace, "." + m.Namespace + ".svc", "." + m.Namespace + ".svc.cluster.local"} {
+			dnsNames = append(dnsNames, name+domain, "*."+name+domain)
+		}
+	}
+	dnsNames = append(dnsNames, m.Spec.TLS.DNSNames...)
+	sort.Strings(dnsNames)
+	return dnsNames
+}
+
+// getTLSIssuer reads the certificate referenced by certificateSecretName or the CA referenced by caSecretName,
+// or generates the CA of the cluster in the secret named by getTLSCASecretName.
+func getTLSIssuer(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, secretNames map[string]bool, emitEvents EventEmitter) (*tlsIssuer, error) {
+	if m.Spec.TLS.CertificateSecretName != "" {
+		secret, err := getSecret(ctx, sdk, m.Spec.TLS.CertificateSecretName, m.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range []string{v1.TLSCertKey, v1.TLSPrivateKeyKey, v1.ServiceAccountRootCAKey} {
+			if len(secret.Data[key]) == 0 {
+				return nil, fmt.Errorf("key %q not found in secret %s/%s", key, m.Namespace, secret.Name)
+			}
+		}
+		return &tlsIssuer{
+			caPEM:   secret.Data[v1.ServiceAccountRootCAKey],
+			certPEM: secret.Data[v1.TLSCertKey],
+			keyPEM:  secret.Data[v1.TLSPrivateKeyKey],
+		}, nil
+	}
+
+	if m.Spec.TLS.CASecretName != "" {
+		secret, err := getSecret(ctx, sdk, m.Spec.TLS.CASecretName, m.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		issuer, err := parseTLSIssuer(secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, err
+		}
+		// the previous CA can be kept in ca.crt while the certificates are issued again by a new one
+		trusted, err := parseCertificates(secret.Data[v1.ServiceAccountRootCAKey])
+		if err != nil {
+			return nil, err
+		}
+		issuer.caPEM = makeCABundle(append([]*x509.Certificate{issuer.caCert}, trusted...)...)
+		return issuer, nil
+	}
+
+	var issuer *tlsIssuer
+	prev := &v1.Secret{}
+	if err := sdk.Get(ctx, *namespacedName(getTLSCASecretName(m), m.Namespace), prev); err == nil {
+		if issuer, err = renewTLSIssuer(m, prev.Data); err != nil {
+			return nil, err
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if issuer == nil {
+		var err error
+		if issuer, err = generateTLSIssuer(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := sdkCreateOrUpdateAsNeeded(ctx, sdk,
+		func() (object, error) {
+			keyPEM, err := encodePrivateKey(issuer.caKey)
+			if err != nil {
+				return nil, err
+			}
+			data := map[string][]byte{
+				v1.TLSCertKey:              encodeCertificate(issuer.caCert),
+				v1.TLSPrivateKeyKey:        keyPEM,
+				v1.ServiceAccountRootCAKey: issuer.caPEM,
+			}
+			if issuer.next != nil {
+				nextKeyPEM, err := encodePrivateKey(issuer.next.caKey)
+				if err != nil {
+					return nil, err
+				}
+				data[tlsNextCACertKey] = encodeCertificate(issuer.next.caCert)
+				data[tlsNextCAKeyKey] = nextKeyPEM
+			}
+			return makeSecret(getTLSCASecretName(m), m.Namespace, makeLabelsForTLS(m), data), nil
+		},
+		func() object { return &v1.Secret{} },
+		alwaysTrueIsEqualsFn, noopUpdaterFn, m, secretNames, emitEvents); err != nil {
+		return nil, err
+	}
+	return issuer, nil
+}
+
+// renewTLSIssuer returns the issuer of the data of the generated CA secret, or nil when it holds no CA. The CA is
+// renewed in two steps so that the nodes keep trusting each other while they roll: once half of its validity
+// elapsed, the next CA is generated and only added to the truststores, it issues the certificates once two thirds
+// elapsed. The previous CA is then kept in the truststores until it expires, as the nodes not rolled yet still
+// present certificates issued by it.
+func renewTLSIssuer(m *v1alpha1.Druid, data map[string][]byte) (*tlsIssuer, error) {
+	current, err := parseTLSIssuer(data[v1.TLSCertKey], data[v1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, nil
+	}
+	next, err := parseTLSIssuer(data[tlsNextCACertKey], data[tlsNextCAKeyKey])
+	if err != nil {
+		next = nil
+	}
+	trusted, err := parseCertificates(data[v1.ServiceAccountRootCAKey])
+	if err != nil {
+		trusted = nil
+	}
+
+	remaining := time.Until(current.caCert.NotAfter)
+	if remaining <= caCertificateDuration/2 && next == nil {
+		if next, err = generateTLSIssuer(m); err != nil {
+			return nil, err
+		}
+	}
+	if remaining <= caCertificateDuration/3 {
+		trusted = append([]*x509.Certificate{current.caCert}, trusted...)
+		current, next = next, nil
+	}
+
+	cas := []*x509.Certificate{current.caCert}
+	if next != nil {
+		cas = append(cas, next.caCert)
+	}
+	for _, ca := range trusted {
+		if time.Now().Before(ca.NotAfter) {
+			cas = append(cas, ca)
+		}
+	}
+	current.caPEM = makeCABundle(cas...)
+	current.next = next
+	return current, nil
+}
+
+// makeTLSSecret returns the secret holding the keystore and truststore of a node spec. The certificate is kept
+// as long as it is issued by the current issuer, names the node spec and is not up for renewal, and the keystores
+// as long as the trusted CAs don't change either, so that the pods only roll when they change.
+func makeTLSSecret(ctx context.Context, sdk client.Client, m *v1alpha1.Druid, issuer *tlsIssuer, nodeSpec *v1alpha1.DruidNodeSpec, nodeSpecUniqueStr string) (*v1.Secret, error) {
+	name := getTLSSecretName(nodeSpecUniqueStr)
+	labels := makeLabelsForTLS(m)
+	labels["nodeSpecUniqueStr"] = nodeSpecUniqueStr
+	dnsNames := getTLSDNSNames(nodeSpec, m, nodeSpecUniqueStr)
+
+	var certPEM, keyPEM []byte
+	prev := &v1.Secret{}
+	if err := sdk.Get(ctx, *namespacedName(name, m.Namespace), prev); err == nil {
+		if issuer.isIssued(prev.Data, dnsNames, getCertificateDuration(m)) {
+			if hasKeyStores(prev.Data) && bytes.Equal(prev.Data[v1.ServiceAccountRootCAKey], issuer.caPEM) {
+				return makeSecret(name, m.Namespace, labels, prev.Data), nil
+			}
+			certPEM, keyPEM = prev.Data[v1.TLSCertKey], prev.Data[v1.TLSPrivateKeyKey]
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if certPEM == nil {
+		certPEM, keyPEM = issuer.certPEM, issuer.keyPEM
+		if issuer.caCert != nil {
+			var err error
+			if certPEM, keyPEM, err = issuer.issue(nodeSpecUniqueStr, dnsNames, getCertificateDuration(m)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	password := string(prev.Data[tlsStorePasswordKey])
+	if password == "" {
+		random := make([]byte, 24)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		password = base64.RawURLEncoding.EncodeToString(random)
+	}
+
+	keyStore, trustStore, err := makeKeyStores(certPEM, keyPEM, issuer.caPEM, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeSecret(name, m.Namespace, labels, map[string][]byte{
+		v1.TLSCertKey:              certPEM,
+		v1.TLSPrivateKeyKey:        keyPEM,
+		v1.ServiceAccountRootCAKey: issuer.caPEM,
+		tlsKeyStoreKey:             keyStore,
+		tlsTrustStoreKey:           trustStore,
+		tlsStorePasswordKey:        []byte(password),
+	}), nil
+}
+
+// hasKeyStores returns true when the data of a node secret holds its keystores.
+func hasKeyStores(data map[string][]byte) bool {
+	for _, key := range []string{tlsKeyStoreKey, tlsTrustStoreKey, tlsStorePasswordKey} {
+		if len(data[key]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isIssued returns true when the data of a node secret holds the current certificate.
+func (issuer *tlsIssuer) isIssued(data map[string][]byte, dnsNames []string, duration time.Duration) bool {
+	if len(data[v1.TLSCertKey]) == 0 || len(data[v1.TLSPrivateKeyKey]) == 0 {
+		return false
+	}
+
+	if issuer.caCert == nil {
+		return bytes.Equal(data[v1.TLSCertKey], issuer.certPEM) && bytes.Equal(data[v1.TLSPrivateKeyKey], issuer.keyPEM)
+	}
+
+	cert, err := parseCertificate(data[v1.TLSCertKey])
+	if err != nil || cert.CheckSignatureFrom(issuer.caCert) != nil {
+		return false
+	}
+	names := append([]string{}, cert.DNSNames...)
+	sort.Strings(names)
+	return reflect.DeepEqual(names, dnsNames) && time.Until(cert.NotAfter) > duration/3
+}
+
+// issue returns a certificate and its key for the names, issued by the CA.
+func (issuer *tlsIssuer) issue(commonName string, dnsNames []string, duration time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-5 * time.Minute),
+		NotAfter:     time.Now().Add(duration),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer.caCert, key.Public(), issuer.caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+func generateTLSIssuer(m *v1alpha1.Druid) (*tlsIssuer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("%s-druid-ca", m.Name)},
+		NotBefore:             time.Now().Add(-5 * time.Minute),
+		NotAfter:              time.Now().Add(caCertificateDuration),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tlsIssuer{
+		caPEM:  encodeCertificate(cert),
+		caCert: cert,
+		caKey:  key,
+	}, nil
+}
+
+func parseTLSIssuer(certPEM, keyPEM []byte) (*tlsIssuer, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, errors.New("the CA certificate is not a CA")
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported CA private key")
+	}
+
+	return &tlsIssuer{
+		caPEM:  encodeCertificate(cert),
+		caCert: cert,
+		caKey:  key,
+	}, nil
+}
+
+// makeCABundle returns the PEM bundle of the CAs, in order and without duplicates.
+func makeCABundle(cas ...*x509.Certificate) []byte {
+	var bundle []byte
+	for i, ca := range cas {
+		duplicate := false
+		for _, prev := range cas[:i] {
+			duplicate = duplicate || prev.Equal(ca)
+		}
+		if !duplicate {
+			bundle = append(bundle, encodeCertificate(ca)...)
+		}
+	}
+	return bundle
+}
+
+// makeKeyStores converts the certificate, its key and the CA bundle into the PKCS12 keystore and truststore
+// read by Druid.
+func makeKeyStores(certPEM, keyPEM, caPEM []byte, password string) ([]byte, []byte, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	var chain []*x509.Certificate
+	for _, der := range pair.Certificate {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, err
+		}
+		chain = append(chain, cert)
+	}
+
+	cas, err := parseCertificates(caPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cas) == 0 {
+		return nil, nil, errors.New("no certificate found in the CA bundle")
+	}
+
+	keyStore, err := pkcs12.Modern.Encode(pair.PrivateKey, chain[0], chain[1:], password)
+	if err != nil {
+		return nil, nil, err
+	}
+	trustStore, err := pkcs12.Modern.EncodeTrustStore(cas, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyStore, trustStore, nil
+}
+
+// parseCertificates returns the certificates of a PEM bundle.
+func parseCertificates(bundlePEM []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for rest := bundlePEM; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func parseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("no certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func encodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func encodePrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// getTLSVolume returns the volume of the keystores of a node spec.
+func getTLSVolume(nodeSpecUniqueStr string) v1.Volume {
+	return v1.Volume{
+		Name: tlsVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Secret: &v1.SecretVolumeSource{
+				SecretName: getTLSSecretName(nodeSpecUniqueStr),
+				Items: []v1.KeyToPath{
+					{Key: tlsKeyStoreKey, Path: tlsKeyStoreKey},
+					{Key: tlsTrustStoreKey, Path: tlsTrustStoreKey},
+				},
+			},
+		},
+	}
+}
+
+// getTLSEnv returns the environment variable of the password of the keystores of a node spec.
+func getTLSEnv(nodeSpecUniqueStr string) v1.EnvVar {
+	return v1.EnvVar{
+		Name: tlsStorePasswordEnv,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: getTLSSecretName(nodeSpecUniqueStr)},
+				Key:                  tlsStorePasswordKey,
+			},
+		},
+	}
+}
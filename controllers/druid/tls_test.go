@@ -0,0 +1,314 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druid
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestMakeTLSSecret(t *testing.T) {
+	drd := &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid", UID: "uid"},
+		Spec: druidv1alpha1.DruidSpec{
+			TLS: &druidv1alpha1.DruidTLSSpec{DNSNames: []string{"druid.example.com"}},
+		},
+	}
+	nodeSpec := &druidv1alpha1.DruidNodeSpec{NodeType: broker}
+	sdk := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	ctx := context.TODO()
+	emitEvent := EmitEventFuncs{record.NewFakeRecorder(100)}
+
+	issuer, err := getTLSIssuer(ctx, sdk, drd, map[string]bool{}, emitEvent)
+	if err != nil {
+		t.Fatalf("failed to get the issuer: %v", err)
+	}
+	ca := &v1.Secret{}
+	if err := sdk.Get(ctx, *namespacedName("tiny-cluster-druid-tls-ca", "druid"), ca); err != nil {
+		t.Fatalf("expected the CA secret to be created: %v", err)
+	}
+
+	secret, err := makeTLSSecret(ctx, sdk, drd, issuer, nodeSpec, "druid-tiny-cluster-brokers")
+	if err != nil {
+		t.Fatalf("failed to make the TLS secret: %v", err)
+	}
+	password := string(secret.Data[tlsStorePasswordKey])
+	if password == "" {
+		t.Fatalf("expected a keystore password")
+	}
+
+	_, cert, caCerts, err := pkcs12.DecodeChain(secret.Data[tlsKeyStoreKey], password)
+	if err != nil {
+		t.Fatalf("failed to decode the keystore: %v", err)
+	}
+	if len(caCerts) != 0 {
+		t.Errorf("expected no chain, got %d certificates", len(caCerts))
+	}
+	if err := cert.VerifyHostname("druid-tiny-cluster-brokers.druid.svc"); err != nil {
+		t.Errorf("expected the certificate to name the service: %v", err)
+	}
+	if err := cert.VerifyHostname("druid.example.com"); err != nil {
+		t.Errorf("expected the certificate to name the extra DNS name: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(issuer.caCert); err != nil {
+		t.Errorf("expected the certificate to be issued by the CA: %v", err)
+	}
+	trusted, err := pkcs12.DecodeTrustStore(secret.Data[tlsTrustStoreKey], password)
+	if err != nil || len(trusted) != 1 || !trusted[0].Equal(issuer.caCert) {
+		t.Errorf("expected the truststore to hold the CA, got %v, %v", trusted, err)
+	}
+
+	if err := sdk.Create(ctx, secret); err != nil {
+		t.Fatalf("failed to create the TLS secret: %v", err)
+	}
+
+	// the certificate is kept as long as it is valid, so that the pods don't roll
+	kept, err := makeTLSSecret(ctx, sdk, drd, issuer, nodeSpec, "druid-tiny-cluster-brokers")
+	if err != nil {
+		t.Fatalf("failed to make the TLS secret: %v", err)
+	}
+	if !reflect.DeepEqual(kept.Data, secret.Data) {
+		t.Errorf("expected the TLS secret to be kept")
+	}
+
+	// a certificate up for renewal is issued again, with the same password
+	drd.Spec.TLS.CertificateDuration = &metav1.Duration{Duration: 3 * 365 * 24 * time.Hour}
+	renewed, err := makeTLSSecret(ctx, sdk, drd, issuer, nodeSpec, "druid-tiny-cluster-brokers")
+	if err != nil {
+		t.Fatalf("failed to make the TLS secret: %v", err)
+	}
+	if reflect.DeepEqual(renewed.Data[v1.TLSCertKey], secret.Data[v1.TLSCertKey]) {
+		t.Errorf("expected the certificate to be renewed")
+	}
+	if string(renewed.Data[tlsStorePasswordKey]) != password {
+		t.Errorf("expected the password to be kept")
+	}
+
+	// the generated CA is kept
+	again, err := getTLSIssuer(ctx, sdk, drd, map[string]bool{}, emitEvent)
+	if err != nil {
+		t.Fatalf("failed to get the issuer: %v", err)
+	}
+	if !again.caCert.Equal(issuer.caCert) {
+		t.Errorf("expected the CA to be kept")
+	}
+}
+
+func TestMakeTLSProperties(t *testing.T) {
+	drd := &druidv1alpha1.Druid{
+		Spec: druidv1alpha1.DruidSpec{
+			TLS: &druidv1alpha1.DruidTLSSpec{DisablePlaintextPort: true},
+		},
+	}
+
+	props := makeTLSProperties(drd)
+	for _, expected := range []string{
+		"druid.enableTlsPort=true",
+		"druid.enablePlaintextPort=false",
+		"druid.server.https.keyStorePath=/druid/tls/keystore.p12",
+		`druid.server.https.keyStorePassword={"type": "environment", "variable": "DRUID_TLS_STORE_PASSWORD"}`,
+		"druid.client.https.trustStorePath=/druid/tls/truststore.p12",
+	} {
+		if !strings.Contains(props, expected) {
+			t.Errorf("expected %q in the properties:\n%s", expected, props)
+		}
+	}
+
+	if !strings.Contains(props, "druid.client.https.validateHostnames=false") {
+		t.Errorf("expected the hostnames not to be validated by default:\n%s", props)
+	}
+	drd.Spec.TLS.ValidateHostnames = true
+	if props := makeTLSProperties(drd); strings.Contains(props, "validateHostnames") {
+		t.Errorf("expected the hostnames to be validated:\n%s", props)
+	}
+
+	podSpec := makePodSpec(&druidv1alpha1.DruidNodeSpec{NodeType: broker}, drd, "druid-tiny-cluster-brokers", "sha")
+	if podSpec.Volumes[len(podSpec.Volumes)-1].Secret.SecretName != "druid-tiny-cluster-brokers-tls" {
+		t.Errorf("expected the keystores to be mounted, got volumes %v", podSpec.Volumes)
+	}
+	env := podSpec.Containers[0].Env
+	if env[len(env)-1].Name != tlsStorePasswordEnv {
+		t.Errorf("expected the keystore password in the environment, got %v", env)
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	drd := &druidv1alpha1.Druid{
+		Spec: druidv1alpha1.DruidSpec{
+			DefaultProbes: true,
+			TLS:           &druidv1alpha1.DruidTLSSpec{},
+		},
+	}
+	if err := validateTLS(drd); err != nil {
+		t.Errorf("expected the default probes to be allowed with the plaintext port: %v", err)
+	}
+
+	drd.Spec.TLS.DisablePlaintextPort = true
+	if err := validateTLS(drd); err == nil {
+		t.Errorf("expected the default probes to be rejected without the plaintext port")
+	}
+
+	drd.Spec.DefaultProbes = false
+	if err := validateTLS(drd); err != nil {
+		t.Errorf("expected the plaintext port to be disabled without the default probes: %v", err)
+	}
+}
+
+// newTestCASecret returns the generated CA secret of the cluster holding a CA expiring in validity.
+func newTestCASecret(t *testing.T, drd *druidv1alpha1.Druid, validity time.Duration) *v1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "old-ca"},
+		NotBefore:             time.Now().Add(-caCertificateDuration + validity),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create the CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse the CA: %v", err)
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode the CA key: %v", err)
+	}
+	return makeSecret(getTLSCASecretName(drd), drd.Namespace, makeLabelsForTLS(drd), map[string][]byte{
+		v1.TLSCertKey:              encodeCertificate(cert),
+		v1.TLSPrivateKeyKey:        keyPEM,
+		v1.ServiceAccountRootCAKey: encodeCertificate(cert),
+	})
+}
+
+func TestRenewTLSIssuer(t *testing.T) {
+	drd := &druidv1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "druid", UID: "uid"},
+		Spec: druidv1alpha1.DruidSpec{
+			TLS: &druidv1alpha1.DruidTLSSpec{},
+		},
+	}
+	nodeSpec := &druidv1alpha1.DruidNodeSpec{NodeType: broker}
+	caSecret := newTestCASecret(t, drd, caCertificateDuration*2/5)
+	sdk := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(caSecret).Build()
+	ctx := context.TODO()
+	emitEvent := EmitEventFuncs{record.NewFakeRecorder(100)}
+
+	trustedCAs := func(secret *v1.Secret) []*x509.Certificate {
+		trusted, err := pkcs12.DecodeTrustStore(secret.Data[tlsTrustStoreKey], string(secret.Data[tlsStorePasswordKey]))
+		if err != nil {
+			t.Fatalf("failed to decode the truststore: %v", err)
+		}
+		return trusted
+	}
+
+	// half of the validity elapsed, the next CA is only trusted
+	oldCA, err := parseCertificate(caSecret.Data[v1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("failed to parse the CA: %v", err)
+	}
+	issuer, err := getTLSIssuer(ctx, sdk, drd, map[string]bool{}, emitEvent)
+	if err != nil {
+		t.Fatalf("failed to get the issuer: %v", err)
+	}
+	if !issuer.caCert.Equal(oldCA) || issuer.next == nil {
+		t.Fatalf("expected the CA to keep issuing the certificates along with a next CA")
+	}
+	nextCA := issuer.next.caCert
+
+	secret, err := makeTLSSecret(ctx, sdk, drd, issuer, nodeSpec, "druid-tiny-cluster-brokers")
+	if err != nil {
+		t.Fatalf("failed to make the TLS secret: %v", err)
+	}
+	if trusted := trustedCAs(secret); len(trusted) != 2 || !trusted[0].Equal(oldCA) || !trusted[1].Equal(nextCA) {
+		t.Errorf("expected the truststore to hold the CA and the next CA, got %d certificates", len(trusted))
+	}
+	if err := sdk.Create(ctx, secret); err != nil {
+		t.Fatalf("failed to create the TLS secret: %v", err)
+	}
+
+	// the next CA is kept
+	again, err := getTLSIssuer(ctx, sdk, drd, map[string]bool{}, emitEvent)
+	if err != nil {
+		t.Fatalf("failed to get the issuer: %v", err)
+	}
+	if again.next == nil || !again.next.caCert.Equal(nextCA) || !reflect.DeepEqual(again.caPEM, issuer.caPEM) {
+		t.Errorf("expected the next CA to be kept")
+	}
+
+	// two thirds of the validity elapsed, the next CA issues the certificates and the previous one is still trusted
+	renewing := newTestCASecret(t, drd, caCertificateDuration/4)
+	current := &v1.Secret{}
+	if err := sdk.Get(ctx, *namespacedName(getTLSCASecretName(drd), drd.Namespace), current); err != nil {
+		t.Fatalf("failed to get the CA secret: %v", err)
+	}
+	current.Data[v1.TLSCertKey] = renewing.Data[v1.TLSCertKey]
+	current.Data[v1.TLSPrivateKeyKey] = renewing.Data[v1.TLSPrivateKeyKey]
+	current.Data[v1.ServiceAccountRootCAKey] = append(append([]byte{}, renewing.Data[v1.TLSCertKey]...), current.Data[tlsNextCACertKey]...)
+	if err := sdk.Update(ctx, current); err != nil {
+		t.Fatalf("failed to update the CA secret: %v", err)
+	}
+	oldCA, err = parseCertificate(renewing.Data[v1.TLSCertKey])
+	if err != nil {
+		t.Fatalf("failed to parse the CA: %v", err)
+	}
+
+	renewed, err := getTLSIssuer(ctx, sdk, drd, map[string]bool{}, emitEvent)
+	if err != nil {
+		t.Fatalf("failed to get the issuer: %v", err)
+	}
+	if !renewed.caCert.Equal(nextCA) || renewed.next != nil {
+		t.Fatalf("expected the next CA to issue the certificates")
+	}
+	secret, err = makeTLSSecret(ctx, sdk, drd, renewed, nodeSpec, "druid-tiny-cluster-brokers")
+	if err != nil {
+		t.Fatalf("failed to make the TLS secret: %v", err)
+	}
+	if trusted := trustedCAs(secret); len(trusted) != 2 || !trusted[0].Equal(nextCA) || !trusted[1].Equal(oldCA) {
+		t.Errorf("expected the truststore to hold the CA and the previous CA, got %d certificates", len(trusted))
+	}
+	cert, err := parseCertificate(secret.Data[v1.TLSCertKey])
+	if err != nil || cert.CheckSignatureFrom(nextCA) != nil {
+		t.Errorf("expected the certificate to be issued by the next CA: %v", err)
+	}
+}
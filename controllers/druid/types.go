@@ -29,3 +29,6 @@ const (
 	historical    = "historical"
 	router        = "router"
 )
+
+// druidNodeTypes lists every value accepted in DruidNodeSpec.NodeType.
+var druidNodeTypes = []string{broker, coordinator, overlord, middleManager, indexer, historical, router}
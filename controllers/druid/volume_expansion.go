@@ -53,6 +53,42 @@ func validateNodeVolumeClaimTemplateSpec(nodeSpec *v1alpha1.DruidNodeSpec) error
 	return nil
 }
 
+// validateVolumeClaimTemplateShrink rejects updates that decrease the requested storage
+// of an existing VolumeClaimTemplate. StatefulSet PVCs can only be expanded, never shrunk.
+func validateVolumeClaimTemplateShrink(oldDrd, newDrd *v1alpha1.Druid) error {
+	if err := validateVolumeClaimTemplatesShrink("cluster", oldDrd.Spec.VolumeClaimTemplates, newDrd.Spec.VolumeClaimTemplates); err != nil {
+		return err
+	}
+
+	for key, newNodeSpec := range newDrd.Spec.Nodes {
+		oldNodeSpec, ok := oldDrd.Spec.Nodes[key]
+		if !ok {
+			continue
+		}
+		if err := validateVolumeClaimTemplatesShrink(key, oldNodeSpec.VolumeClaimTemplates, newNodeSpec.VolumeClaimTemplates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateVolumeClaimTemplatesShrink(owner string, oldVCTs, newVCTs []v1.PersistentVolumeClaim) error {
+	for _, newVCT := range newVCTs {
+		for _, oldVCT := range oldVCTs {
+			if oldVCT.Name != newVCT.Name {
+				continue
+			}
+			oldSize := oldVCT.Spec.Resources.Requests[v1.ResourceStorage]
+			newSize := newVCT.Spec.Resources.Requests[v1.ResourceStorage]
+			if newSize.Cmp(oldSize) < 0 {
+				return fmt.Errorf("node group %s can not shrink volume claim template %s from %s to %s",
+					owner, newVCT.Name, oldSize.String(), newSize.String())
+			}
+		}
+	}
+	return nil
+}
+
 func expandStatefulSetVolumes(ctx context.Context, sdk client.Client, m *v1alpha1.Druid,
 	nodeSpec *v1alpha1.DruidNodeSpec, emitEvent EventEmitter, nodeSpecUniqueStr string) error {
 
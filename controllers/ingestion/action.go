@@ -0,0 +1,210 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DruidIngestionControllerActionSuccess = "DruidIngestionControllerActionSuccess"
+	DruidIngestionControllerActionFail    = "DruidIngestionControllerActionFail"
+)
+
+// isActionPending returns true when spec.action has not been run for its generation yet.
+func isActionPending(di *v1alpha1.DruidIngestion) bool {
+	if di.Spec.Action == nil {
+		return false
+	}
+	last := di.Status.LastAction
+	return last == nil || last.Generation != di.Spec.Action.Generation || last.Type != di.Spec.Action.Type
+}
+
+// getStream returns the Kafka topic or Kinesis stream read by the supervisor.
+func getStream(di *v1alpha1.DruidIngestion) (string, error) {
+	spec, err := getSpec(di)
+	if err != nil {
+		return "", err
+	}
+
+	streamKey := "topic"
+	if di.Spec.Ingestion.Type == v1alpha1.Kinesis {
+		streamKey = "stream"
+	}
+
+	if specSection, ok := spec["spec"].(map[string]interface{}); ok {
+		if ioConfig, ok := specSection["ioConfig"].(map[string]interface{}); ok {
+			if stream, ok := ioConfig[streamKey].(string); ok {
+				return stream, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s not found in spec", streamKey)
+}
+
+// getActionRequest returns the path and body of the supervisor API call that runs the action.
+func getActionRequest(di *v1alpha1.DruidIngestion, svcName string) (string, []byte, error) {
+	action := di.Spec.Action
+	supervisorId := di.Status.TaskId
+
+	switch action.Type {
+	case v1alpha1.SupervisorActionReset:
+		return getSupervisorActionPath(svcName, supervisorId, "reset"), []byte{}, nil
+	case v1alpha1.SupervisorActionTerminate:
+		return getSupervisorActionPath(svcName, supervisorId, "terminate"), []byte{}, nil
+	case v1alpha1.SupervisorActionHandoff:
+		body, err := json.Marshal(map[string]interface{}{"taskGroupIds": action.TaskGroupIds})
+		if err != nil {
+			return "", nil, err
+		}
+		return getSupervisorActionPath(svcName, supervisorId, "taskGroups/handoff"), body, nil
+	case v1alpha1.SupervisorActionResetOffsets:
+		stream, err := getStream(di)
+		if err != nil {
+			return "", nil, err
+		}
+
+		partitions := map[string]interface{}{
+			"type":   "end",
+			"stream": stream,
+		}
+		if di.Spec.Ingestion.Type == v1alpha1.Kafka {
+			// kafka offsets are numbers
+			offsets := make(map[string]int64, len(action.PartitionOffsets))
+			for partition, offset := range action.PartitionOffsets {
+				n, err := strconv.ParseInt(offset, 10, 64)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid offset [%s] for partition [%s]: %v", offset, partition, err)
+				}
+				offsets[partition] = n
+			}
+			partitions["partitionOffsetMap"] = offsets
+		} else {
+			partitions["partitionSequenceNumberMap"] = action.PartitionOffsets
+		}
+
+		body, err := json.Marshal(map[string]interface{}{
+			"type":       string(di.Spec.Ingestion.Type),
+			"partitions": partitions,
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		return getSupervisorActionPath(svcName, supervisorId, "resetOffsets"), body, nil
+	}
+
+	return "", nil, fmt.Errorf("unknown supervisor action [%s]", action.Type)
+}
+
+// recordExistingAction records spec.action as handled when the supervisor is first submitted. An action already
+// set on creation, such as one re-applied by GitOps or carried over by a cluster migration, is not run on the new
+// supervisor, only the actions set or bumped afterwards are.
+func (r *DruidIngestionReconciler) recordExistingAction(di *v1alpha1.DruidIngestion) error {
+	if !isSupervisor(di.Spec.Ingestion.Type) || di.Spec.Action == nil || di.Status.LastAction != nil {
+		return nil
+	}
+
+	action := di.Spec.Action
+	_, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.LastAction = &v1alpha1.SupervisorActionStatus{
+			Type:       action.Type,
+			Generation: action.Generation,
+			Message:    "not run, the action was set before the supervisor was submitted",
+			Time:       metav1.Time{Time: time.Now()},
+		}
+		return in
+	})
+	return err
+}
+
+// RunSupervisorAction runs spec.action once per generation on the supervisor and records its outcome
+// in status.lastAction. A failed action is not retried until its generation is bumped.
+func (r *DruidIngestionReconciler) RunSupervisorAction(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	if !isSupervisor(di.Spec.Ingestion.Type) || di.Status.TaskId == "" || !isActionPending(di) {
+		return nil
+	}
+
+	action := di.Spec.Action
+	succeeded := false
+	var msg string
+
+	path, body, err := getActionRequest(di, svcName)
+	if err != nil {
+		msg = err.Error()
+	} else {
+		postHttp := internalhttp.NewHTTPClient(
+			&http.Client{},
+			&auth,
+		)
+
+		resp, err := postHttp.Do(http.MethodPost, path, body)
+		if err != nil {
+			return err
+		}
+		succeeded = resp.StatusCode == 200
+		msg = fmt.Sprintf("Resp [%s], StatusCode [%d]", string(resp.ResponseBody), resp.StatusCode)
+	}
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.LastAction = &v1alpha1.SupervisorActionStatus{
+			Type:       action.Type,
+			Generation: action.Generation,
+			Succeeded:  succeeded,
+			Message:    msg,
+			Time:       metav1.Time{Time: time.Now()},
+		}
+		if succeeded && action.Type == v1alpha1.SupervisorActionTerminate {
+			// a terminated supervisor is gone, it is only submitted again on the next spec change
+			in.Status.TaskId = ""
+			in.Status.TaskStatus = "TERMINATED"
+		}
+		return in
+	}); err != nil {
+		return err
+	}
+
+	eventMsg := fmt.Sprintf("supervisor action [%s] generation [%d]: %s", action.Type, action.Generation, msg)
+	if succeeded {
+		build.Recorder.GenericEvent(di, v1.EventTypeNormal, eventMsg, DruidIngestionControllerActionSuccess)
+	} else {
+		build.Recorder.GenericEvent(di, v1.EventTypeWarning, eventMsg, DruidIngestionControllerActionFail)
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRunSupervisorAction(t *testing.T) {
+	tests := []struct {
+		name           string
+		ingestionType  v1alpha1.DruidIngestionMethod
+		spec           string
+		action         v1alpha1.SupervisorAction
+		expectedPath   string
+		expectedBody   string
+		expectedTaskId string
+	}{
+		{
+			name:           "reset",
+			ingestionType:  v1alpha1.Kafka,
+			action:         v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionReset, Generation: 1},
+			expectedPath:   "/druid/indexer/v1/supervisor/metrics/reset",
+			expectedTaskId: "metrics",
+		},
+		{
+			name:          "kafka resetOffsets",
+			ingestionType: v1alpha1.Kafka,
+			action: v1alpha1.SupervisorAction{
+				Type:             v1alpha1.SupervisorActionResetOffsets,
+				Generation:       1,
+				PartitionOffsets: map[string]string{"0": "100", "1": "250"},
+			},
+			expectedPath:   "/druid/indexer/v1/supervisor/metrics/resetOffsets",
+			expectedBody:   `{"partitions":{"partitionOffsetMap":{"0":100,"1":250},"stream":"metrics-topic","type":"end"},"type":"kafka"}`,
+			expectedTaskId: "metrics",
+		},
+		{
+			name:          "kinesis resetOffsets",
+			ingestionType: v1alpha1.Kinesis,
+			action: v1alpha1.SupervisorAction{
+				Type:             v1alpha1.SupervisorActionResetOffsets,
+				Generation:       1,
+				PartitionOffsets: map[string]string{"shardId-000000000000": "49590338271490256608559692538361571095921575989136588898"},
+			},
+			expectedPath:   "/druid/indexer/v1/supervisor/metrics/resetOffsets",
+			expectedBody:   `{"partitions":{"partitionSequenceNumberMap":{"shardId-000000000000":"49590338271490256608559692538361571095921575989136588898"},"stream":"metrics-stream","type":"end"},"type":"kinesis"}`,
+			expectedTaskId: "metrics",
+		},
+		{
+			name:           "handoff",
+			ingestionType:  v1alpha1.Kafka,
+			action:         v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionHandoff, Generation: 1, TaskGroupIds: []int{0, 2}},
+			expectedPath:   "/druid/indexer/v1/supervisor/metrics/taskGroups/handoff",
+			expectedBody:   `{"taskGroupIds":[0,2]}`,
+			expectedTaskId: "metrics",
+		},
+		{
+			name:           "terminate",
+			ingestionType:  v1alpha1.Kafka,
+			action:         v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionTerminate, Generation: 1},
+			expectedPath:   "/druid/indexer/v1/supervisor/metrics/terminate",
+			expectedTaskId: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+
+			// Stand-in for the overlord supervisor API
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, tt.expectedPath, r.URL.Path)
+				if tt.expectedBody != "" {
+					assert.JSONEq(t, tt.expectedBody, string(body))
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"id": "metrics"}`))
+			}))
+			defer server.Close()
+
+			action := tt.action
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Name: "stream", Namespace: "default"},
+				Spec: v1alpha1.DruidIngestionSpec{
+					Ingestion: v1alpha1.IngestionSpec{
+						Type: tt.ingestionType,
+						Spec: `{"spec": {"dataSchema": {"dataSource": "metrics"}, "ioConfig": {"topic": "metrics-topic", "stream": "metrics-stream"}}}`,
+					},
+					Action: &action,
+				},
+				Status: v1alpha1.DruidIngestionStatus{TaskId: "metrics"},
+			}
+
+			r := newTestReconciler(t, di)
+			build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+			assert.NoError(t, r.RunSupervisorAction(di, server.URL, build, internalhttp.Auth{}))
+			assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+			assert.Equal(t, 1, calls)
+			assert.True(t, di.Status.LastAction.Succeeded)
+			assert.Equal(t, int64(1), di.Status.LastAction.Generation)
+			assert.Equal(t, tt.expectedTaskId, di.Status.TaskId)
+
+			// the action runs once per generation
+			di.Status.TaskId = "metrics"
+			assert.NoError(t, r.RunSupervisorAction(di, server.URL, build, internalhttp.Auth{}))
+			assert.Equal(t, 1, calls)
+
+			di.Spec.Action.Generation = 2
+			assert.NoError(t, r.RunSupervisorAction(di, server.URL, build, internalhttp.Auth{}))
+			assert.Equal(t, 2, calls)
+		})
+	}
+}
+
+func TestRunSupervisorActionFailure(t *testing.T) {
+	var calls int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "Cannot find any supervisor with id: [metrics]"}`))
+	}))
+	defer server.Close()
+
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka},
+			Action:    &v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionReset, Generation: 1},
+		},
+		Status: v1alpha1.DruidIngestionStatus{TaskId: "metrics"},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+	assert.NoError(t, r.RunSupervisorAction(di, server.URL, build, internalhttp.Auth{}))
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.False(t, di.Status.LastAction.Succeeded)
+	assert.Contains(t, di.Status.LastAction.Message, "Cannot find any supervisor")
+
+	// a failed action is not retried
+	assert.NoError(t, r.RunSupervisorAction(di, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestCreateOrUpdateRecordsExistingAction(t *testing.T) {
+	var paths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id": "metrics"}`))
+	}))
+	defer server.Close()
+
+	// a terminate re-applied along with the CR, for example by GitOps
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default", Generation: 1},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.Kafka,
+				Spec: `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics"}, "ioConfig": {"topic": "metrics-topic"}}}`,
+			},
+			Action: &v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionTerminate, Generation: 3},
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+	_, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, internalhttp.Auth{})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, "metrics", di.Status.TaskId)
+	if assert.NotNil(t, di.Status.LastAction) {
+		assert.Equal(t, int64(3), di.Status.LastAction.Generation)
+		assert.False(t, di.Status.LastAction.Succeeded)
+	}
+
+	// the action set before the submission is not run
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, internalhttp.Auth{})
+	assert.NoError(t, err)
+	assert.NotContains(t, paths, "POST /druid/indexer/v1/supervisor/metrics/terminate")
+
+	// an action bumped afterwards is
+	di.Spec.Action.Generation = 4
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, internalhttp.Auth{})
+	assert.NoError(t, err)
+	assert.Contains(t, paths, "POST /druid/indexer/v1/supervisor/metrics/terminate")
+}
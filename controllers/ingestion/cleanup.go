@@ -0,0 +1,254 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/datainfrahq/druid-operator/controllers/druid"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DruidIngestionControllerCleanupSuccess = "DruidIngestionControllerCleanupSuccess"
+	DruidIngestionControllerCleanupFail    = "DruidIngestionControllerCleanupFail"
+)
+
+// getCurrentDataSource returns the dataSource of the last submitted ingestion spec.
+func getCurrentDataSource(di *v1alpha1.DruidIngestion) (string, error) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal([]byte(di.Status.CurrentIngestionSpec), &spec); err != nil {
+		return "", err
+	}
+	return getSpecDataSource(spec)
+}
+
+func appendUnique(list []string, s string) []string {
+	if s == "" {
+		return list
+	}
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func removeAll(list []string, remove []string) []string {
+	var kept []string
+	for _, item := range list {
+		if !contains(remove, item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// trackResources records the dataSource and supervisor about to be replaced by a submission, as well as the
+// submitted ones, so that the replaced ones can be cleaned up.
+func trackResources(in *v1alpha1.DruidIngestion, dataSource, taskId string) {
+	if in.Status.CurrentIngestionSpec != "" {
+		if currentDataSource, err := getCurrentDataSource(in); err == nil {
+			in.Status.DataSources = appendUnique(in.Status.DataSources, currentDataSource)
+		}
+	}
+	in.Status.DataSources = appendUnique(in.Status.DataSources, dataSource)
+
+	if isSupervisor(in.Spec.Ingestion.Type) {
+		in.Status.Supervisors = appendUnique(in.Status.Supervisors, in.Status.TaskId)
+		in.Status.Supervisors = appendUnique(in.Status.Supervisors, taskId)
+	}
+}
+
+// isCleanedUp returns true for the responses of a successful cleanup, including of an already deleted resource.
+func isCleanedUp(resp *internalhttp.Response) bool {
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound
+}
+
+// Cleanup runs the cleanup selected in spec.cleanup on the supervisors and dataSources no longer ingested into.
+// Once the DruidIngestion is being deleted the remaining replaced ones are cleaned up, the current supervisor is
+// shut down by ShutDownTask and the current dataSource is kept along with its data. A failed cleanup is reported
+// in an event and retried on the next reconcile, except on deletion where it is best-effort: the finalizer is
+// removed anyway, only a failure to reach Druid keeps it. The supervisors are terminated through the indexer API at svcName, and the dataSources
+// cleaned up through the coordinator API at coordinatorSvcName.
+func (r *DruidIngestionReconciler) Cleanup(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	coordinatorSvcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	policy := di.Spec.Cleanup
+	if policy == nil {
+		return nil
+	}
+
+	deleting := !di.DeletionTimestamp.IsZero()
+	var dataSource string
+	if deleting {
+		// the dataSource last ingested into is not replaced by the deletion
+		if di.Status.CurrentIngestionSpec != "" {
+			var err error
+			if dataSource, err = getCurrentDataSource(di); err != nil {
+				return err
+			}
+		}
+	} else {
+		// until the current spec is submitted, the previous dataSource is still ingested into
+		specJson, err := getSpecJson(di)
+		if err != nil {
+			return err
+		}
+		if ok, err := druid.IsEqualJson(di.Status.CurrentIngestionSpec, specJson); err != nil || !ok {
+			return err
+		}
+		if dataSource, err = getDataSource(di); err != nil {
+			return err
+		}
+	}
+
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	var cleanedSupervisors, cleanedDataSources []string
+
+	if policy.ShutdownSupervisor {
+		for _, supervisorId := range di.Status.Supervisors {
+			if supervisorId == di.Status.TaskId {
+				continue
+			}
+			resp, err := httpClient.Do(http.MethodPost, getSupervisorActionPath(svcName, supervisorId, "terminate"), []byte{})
+			if err != nil {
+				return err
+			}
+			if r.recordCleanup(di, build, resp, fmt.Sprintf("supervisor [%s] terminated", supervisorId)) {
+				cleanedSupervisors = append(cleanedSupervisors, supervisorId)
+			}
+		}
+	}
+
+	if policy.DeleteCompaction || policy.ResetRules || policy.MarkSegmentsUnused {
+		for _, staleDataSource := range di.Status.DataSources {
+			if staleDataSource == dataSource {
+				continue
+			}
+			ok, err := r.cleanupDataSource(di, policy, staleDataSource, coordinatorSvcName, build, httpClient)
+			if err != nil {
+				return err
+			}
+			if ok {
+				cleanedDataSources = append(cleanedDataSources, staleDataSource)
+			}
+		}
+	}
+
+	if len(cleanedSupervisors) == 0 && len(cleanedDataSources) == 0 {
+		return nil
+	}
+
+	_, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.Supervisors = removeAll(in.Status.Supervisors, cleanedSupervisors)
+		in.Status.DataSources = removeAll(in.Status.DataSources, cleanedDataSources)
+		return in
+	})
+	return err
+}
+
+// cleanupDataSource resets the rules, deletes the compaction config and marks the segments of a dataSource
+// unused, as selected in the policy. It returns true once all of them succeeded.
+func (r *DruidIngestionReconciler) cleanupDataSource(
+	di *v1alpha1.DruidIngestion,
+	policy *v1alpha1.CleanupPolicy,
+	dataSource, svcName string,
+	build builder.Builder,
+	httpClient internalhttp.DruidHTTP,
+) (bool, error) {
+	if policy.ResetRules {
+		resp, err := resetRules(httpClient, svcName, dataSource)
+		if err != nil {
+			return false, err
+		}
+		if !r.recordCleanup(di, build, resp, fmt.Sprintf("rules of dataSource [%s] reset", dataSource)) {
+			return false, nil
+		}
+	}
+
+	if policy.DeleteCompaction {
+		resp, err := deleteCompaction(httpClient, svcName, dataSource)
+		if err != nil {
+			return false, err
+		}
+		if !r.recordCleanup(di, build, resp, fmt.Sprintf("compaction config of dataSource [%s] deleted", dataSource)) {
+			return false, nil
+		}
+	}
+
+	if policy.MarkSegmentsUnused {
+		resp, err := httpClient.Do(http.MethodDelete, druidapi.MakePath(svcName, "coordinator", "datasources", dataSource), nil)
+		if err != nil {
+			return false, err
+		}
+		if !r.recordCleanup(di, build, resp, fmt.Sprintf("segments of dataSource [%s] marked unused", dataSource)) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (r *DruidIngestionReconciler) recordCleanup(
+	di *v1alpha1.DruidIngestion,
+	build builder.Builder,
+	resp *internalhttp.Response,
+	msg string,
+) bool {
+	if !isCleanedUp(resp) {
+		build.Recorder.GenericEvent(
+			di,
+			v1.EventTypeWarning,
+			fmt.Sprintf("Resp [%s], StatusCode [%d]", string(resp.ResponseBody), resp.StatusCode),
+			DruidIngestionControllerCleanupFail,
+		)
+		return false
+	}
+	build.Recorder.GenericEvent(di, v1.EventTypeNormal, msg, DruidIngestionControllerCleanupSuccess)
+	return true
+}
@@ -0,0 +1,188 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCleanup(t *testing.T) {
+	newSpec := `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics-new"}}}`
+
+	tests := []struct {
+		name                string
+		policy              *v1alpha1.CleanupPolicy
+		currentSpec         string
+		deleting            bool
+		expectedCalls       []string
+		expectedDataSources []string
+		expectedSupervisors []string
+	}{
+		{
+			name:                "no policy",
+			currentSpec:         newSpec,
+			expectedDataSources: []string{"metrics-old", "metrics-new"},
+			expectedSupervisors: []string{"metrics-old", "metrics-new"},
+		},
+		{
+			name: "all cleanups",
+			policy: &v1alpha1.CleanupPolicy{
+				ShutdownSupervisor: true,
+				DeleteCompaction:   true,
+				ResetRules:         true,
+				MarkSegmentsUnused: true,
+			},
+			currentSpec: newSpec,
+			expectedCalls: []string{
+				"POST /druid/indexer/v1/supervisor/metrics-old/terminate",
+				"POST /druid/coordinator/v1/rules/metrics-old []",
+				"DELETE /druid/coordinator/v1/config/compaction/metrics-old",
+				"DELETE /druid/coordinator/v1/datasources/metrics-old",
+			},
+			expectedDataSources: []string{"metrics-new"},
+			expectedSupervisors: []string{"metrics-new"},
+		},
+		{
+			name:        "supervisor only",
+			policy:      &v1alpha1.CleanupPolicy{ShutdownSupervisor: true},
+			currentSpec: newSpec,
+			expectedCalls: []string{
+				"POST /druid/indexer/v1/supervisor/metrics-old/terminate",
+			},
+			expectedDataSources: []string{"metrics-old", "metrics-new"},
+			expectedSupervisors: []string{"metrics-new"},
+		},
+		{
+			name:                "spec not submitted yet",
+			policy:              &v1alpha1.CleanupPolicy{ShutdownSupervisor: true, DeleteCompaction: true},
+			currentSpec:         `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics-old"}}}`,
+			expectedDataSources: []string{"metrics-old", "metrics-new"},
+			expectedSupervisors: []string{"metrics-old", "metrics-new"},
+		},
+		{
+			name: "deleting",
+			policy: &v1alpha1.CleanupPolicy{
+				DeleteCompaction:   true,
+				ResetRules:         true,
+				MarkSegmentsUnused: true,
+			},
+			currentSpec: newSpec,
+			deleting:    true,
+			// the current dataSource is kept along with its data
+			expectedCalls: []string{
+				"POST /druid/coordinator/v1/rules/metrics-old []",
+				"DELETE /druid/coordinator/v1/config/compaction/metrics-old",
+				"DELETE /druid/coordinator/v1/datasources/metrics-old",
+			},
+			expectedDataSources: []string{"metrics-new"},
+			expectedSupervisors: []string{"metrics-old", "metrics-new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+
+			// Stand-in for the overlord and coordinator APIs
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := r.Method + " " + r.URL.Path
+				if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+					call += " " + string(body)
+				}
+				calls = append(calls, call)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+				Spec: v1alpha1.DruidIngestionSpec{
+					Ingestion: v1alpha1.IngestionSpec{
+						Type: v1alpha1.Kafka,
+						Spec: newSpec,
+					},
+					Cleanup: tt.policy,
+				},
+				Status: v1alpha1.DruidIngestionStatus{
+					TaskId:               "metrics-new",
+					CurrentIngestionSpec: tt.currentSpec,
+					DataSources:          []string{"metrics-old", "metrics-new"},
+					Supervisors:          []string{"metrics-old", "metrics-new"},
+				},
+			}
+			if tt.deleting {
+				di.Finalizers = []string{DruidIngestionControllerFinalizer}
+				di.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+			}
+
+			r := newTestReconciler(t, di)
+			build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+			assert.NoError(t, r.Cleanup(di, server.URL, server.URL, build, internalhttp.Auth{}))
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+			assert.Equal(t, tt.expectedDataSources, di.Status.DataSources)
+			assert.Equal(t, tt.expectedSupervisors, di.Status.Supervisors)
+		})
+	}
+}
+
+func TestTrackResources(t *testing.T) {
+	di := &v1alpha1.DruidIngestion{
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka},
+		},
+		Status: v1alpha1.DruidIngestionStatus{
+			TaskId:               "metrics-old",
+			CurrentIngestionSpec: `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics-old"}}}`,
+		},
+	}
+
+	// an ingestion created before the tracking records the replaced dataSource and supervisor too
+	trackResources(di, "metrics-new", "metrics-new")
+	assert.Equal(t, []string{"metrics-old", "metrics-new"}, di.Status.DataSources)
+	assert.Equal(t, []string{"metrics-old", "metrics-new"}, di.Status.Supervisors)
+
+	trackResources(di, "metrics-new", "metrics-new")
+	assert.Equal(t, []string{"metrics-old", "metrics-new"}, di.Status.DataSources)
+
+	sql := &v1alpha1.DruidIngestion{
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.QueryControllerSQL},
+		},
+		Status: v1alpha1.DruidIngestionStatus{
+			TaskId:               "query-1",
+			CurrentIngestionSpec: `{"query": "INSERT INTO wikipedia SELECT * FROM ext"}`,
+		},
+	}
+	trackResources(sql, "wikipedia_v2", "query-2")
+	assert.Equal(t, []string{"wikipedia", "wikipedia_v2"}, sql.Status.DataSources)
+	assert.Empty(t, sql.Status.Supervisors)
+}
@@ -20,20 +20,19 @@ package ingestion
 
 import (
 	"context"
-	"os"
 	"time"
 
-	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
 	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/datainfrahq/druid-operator/pkg/util"
 )
 
 // IngestionReconciler
@@ -58,42 +57,23 @@ func NewDruidIngestionReconciler(mgr ctrl.Manager) *DruidIngestionReconciler {
 // +kubebuilder:rbac:groups=druid.apache.org,resources=ingestions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=druid.apache.org,resources=ingestion/status,verbs=get;update;patch
 func (r *DruidIngestionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logr := log.FromContext(ctx)
-
 	druidIngestionCR := &v1alpha1.DruidIngestion{}
-	err := r.Get(ctx, req.NamespacedName, druidIngestionCR)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return ctrl.Result{}, nil
-		}
-		return ctrl.Result{}, err
-	}
-
-	if err := r.do(ctx, druidIngestionCR); err != nil {
-		logr.Error(err, err.Error())
-		return ctrl.Result{}, err
-	} else {
-		return ctrl.Result{RequeueAfter: LookupReconcileTime()}, nil
-	}
-
-}
-
-func LookupReconcileTime() time.Duration {
-	val, exists := os.LookupEnv("RECONCILE_WAIT")
-	if !exists {
-		return time.Second * 10
-	} else {
-		v, err := time.ParseDuration(val)
-		if err != nil {
-			// Exit Program if not valid
-			os.Exit(1)
-		}
-		return v
+	result, err := util.ReconcileObject(ctx, r.Client, req, druidIngestionCR, func(ctx context.Context) error {
+		return r.do(ctx, druidIngestionCR)
+	})
+	if err != nil || result.RequeueAfter == 0 {
+		return result, err
 	}
+	// A failed submission is retried after its backoff rather than after the next resync
+	return ctrl.Result{RequeueAfter: getRequeueAfter(druidIngestionCR, time.Now(), result.RequeueAfter)}, nil
 }
 
+// SetupWithManager reconciles the DruidIngestions on the changes of their spec and on their deletion. The updates
+// of their status only, such as the lag of a supervisor, are refreshed on the requeue instead, they would otherwise
+// trigger a reconcile and poll the overlord in a loop.
 func (r *DruidIngestionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&druidv1alpha1.DruidIngestion{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
 		Complete(r)
 }
@@ -0,0 +1,162 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DruidIngestionWebhook validates DruidIngestion objects at admission time, so a spec the
+// controller can't submit is rejected on apply instead of failing on every reconcile.
+type DruidIngestionWebhook struct{}
+
+var _ admission.CustomValidator = &DruidIngestionWebhook{}
+
+func (w *DruidIngestionWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.DruidIngestion{}).
+		WithValidator(w).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-druid-apache-org-v1alpha1-druidingestion,mutating=false,failurePolicy=fail,sideEffects=None,groups=druid.apache.org,resources=druidingestions,verbs=create;update,versions=v1alpha1,name=vdruidingestion.kb.io,admissionReviewVersions=v1
+
+// ValidateCreate validates the ingestion spec, compaction and rules.
+func (w *DruidIngestionWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	di, ok := obj.(*v1alpha1.DruidIngestion)
+	if !ok {
+		return nil, fmt.Errorf("expected a DruidIngestion but got a %T", obj)
+	}
+
+	return nil, validateIngestion(di)
+}
+
+// ValidateUpdate validates the updated ingestion spec, compaction and rules. The updates of a DruidIngestion
+// being deleted, or of its metadata only, are allowed whatever its spec, so that the finalizer of a DruidIngestion
+// accepted before the webhook can still be removed.
+func (w *DruidIngestionWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldDi, ok := oldObj.(*v1alpha1.DruidIngestion)
+	if !ok {
+		return nil, fmt.Errorf("expected a DruidIngestion but got a %T", oldObj)
+	}
+	di, ok := newObj.(*v1alpha1.DruidIngestion)
+	if !ok {
+		return nil, fmt.Errorf("expected a DruidIngestion but got a %T", newObj)
+	}
+
+	if di.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldDi.Spec, di.Spec) {
+		return nil, nil
+	}
+	return nil, validateIngestion(di)
+}
+
+// ValidateDelete allows every delete, cleanup is handled by the finalizer.
+func (w *DruidIngestionWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateIngestion checks the DruidIngestion the same way the reconciler reads it before submitting it.
+func validateIngestion(di *v1alpha1.DruidIngestion) error {
+	if err := verifyIngestion(di); err != nil {
+		return fmt.Errorf("invalid DruidIngestion[%s] due to [%s]", di.Name, err.Error())
+	}
+	return nil
+}
+
+func verifyIngestion(di *v1alpha1.DruidIngestion) error {
+	// the service name doesn't matter, only whether a submit path exists for the type
+	if getPath(di.Spec.Ingestion.Type, "druid", http.MethodPost, "", false) == "" {
+		return fmt.Errorf("ingestion type [%s] is not supported", di.Spec.Ingestion.Type)
+	}
+
+	if (di.Spec.Ingestion.Type == v1alpha1.QueryControllerSQL) != (di.Spec.Ingestion.SQL != nil) {
+		return fmt.Errorf("sql must be set if and only if the ingestion type is [%s]", v1alpha1.QueryControllerSQL)
+	}
+
+	if _, err := getSpec(di); err != nil {
+		return err
+	}
+
+	dataSource, err := getDataSource(di)
+	if err != nil {
+		return err
+	}
+
+	if len(di.Spec.Ingestion.Compaction.Raw) > 0 {
+		var compaction map[string]interface{}
+		if err := json.Unmarshal(di.Spec.Ingestion.Compaction.Raw, &compaction); err != nil {
+			return fmt.Errorf("error unmarshalling compaction: %v", err)
+		}
+		if compactionDataSource, ok := compaction["dataSource"]; ok && compactionDataSource != dataSource {
+			return fmt.Errorf("compaction dataSource [%v] does not match ingestion dataSource [%s]", compactionDataSource, dataSource)
+		}
+	}
+
+	rules, err := getRules(di)
+	if err != nil {
+		return err
+	}
+
+	if err := druidapi.ValidateRules(rules); err != nil {
+		return err
+	}
+
+	return verifySupervisorAction(di)
+}
+
+func verifySupervisorAction(di *v1alpha1.DruidIngestion) error {
+	action := di.Spec.Action
+	if action == nil {
+		return nil
+	}
+
+	if !isSupervisor(di.Spec.Ingestion.Type) {
+		return fmt.Errorf("action is only supported by supervisors, not by ingestion type [%s]", di.Spec.Ingestion.Type)
+	}
+
+	switch action.Type {
+	case v1alpha1.SupervisorActionResetOffsets:
+		if len(action.PartitionOffsets) == 0 {
+			return fmt.Errorf("action [%s] requires partitionOffsets", action.Type)
+		}
+		if _, err := getStream(di); err != nil {
+			return err
+		}
+	case v1alpha1.SupervisorActionHandoff:
+		if len(action.TaskGroupIds) == 0 {
+			return fmt.Errorf("action [%s] requires taskGroupIds", action.Type)
+		}
+	}
+
+	// builds the request, so that invalid offsets are rejected too
+	if _, _, err := getActionRequest(di, ""); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDruidIngestionWebhookValidate(t *testing.T) {
+	const spec = `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics-kafka"}}}`
+
+	tests := []struct {
+		name      string
+		ingestion v1alpha1.IngestionSpec
+		action    *v1alpha1.SupervisorAction
+		expectErr string
+	}{
+		{
+			name: "valid ingestion",
+			ingestion: v1alpha1.IngestionSpec{
+				Type:       v1alpha1.Kafka,
+				Spec:       spec,
+				Compaction: runtime.RawExtension{Raw: []byte(`{"dataSource": "metrics-kafka", "skipOffsetFromLatest": "PT1H"}`)},
+				Rules: []runtime.RawExtension{
+					{Raw: []byte(`{"type": "loadByPeriod", "period": "P1M", "tieredReplicants": {"_default_tier": 1}}`)},
+					{Raw: []byte(`{"type": "dropForever"}`)},
+				},
+			},
+		},
+		{
+			name: "valid sql ingestion",
+			ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.QueryControllerSQL,
+				SQL:  &v1alpha1.SQLSpec{Query: `REPLACE INTO "metrics-sql" OVERWRITE ALL SELECT * FROM ext PARTITIONED BY DAY`},
+			},
+		},
+		{
+			name:      "sql without sql type",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: spec, SQL: &v1alpha1.SQLSpec{Query: "INSERT INTO metrics SELECT 1"}},
+			expectErr: "sql must be set",
+		},
+		{
+			name:      "sql that is not an INSERT or REPLACE",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.QueryControllerSQL, SQL: &v1alpha1.SQLSpec{Query: "SELECT 1"}},
+			expectErr: "dataSource not found",
+		},
+		{
+			name:      "valid action",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: spec},
+			action:    &v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionReset, Generation: 1},
+		},
+		{
+			name:      "action on a batch ingestion",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.NativeBatchIndexParallel, Spec: spec},
+			action:    &v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionReset, Generation: 1},
+			expectErr: "only supported by supervisors",
+		},
+		{
+			name:      "resetOffsets without offsets",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: spec},
+			action:    &v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionResetOffsets, Generation: 1},
+			expectErr: "requires partitionOffsets",
+		},
+		{
+			name:      "handoff without task groups",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: spec},
+			action:    &v1alpha1.SupervisorAction{Type: v1alpha1.SupervisorActionHandoff, Generation: 1},
+			expectErr: "requires taskGroupIds",
+		},
+		{
+			name:      "unsupported type",
+			ingestion: v1alpha1.IngestionSpec{Type: "spark", Spec: spec},
+			expectErr: "not supported",
+		},
+		{
+			name:      "unparsable spec",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: `{"type": `},
+			expectErr: "error unmarshalling Spec",
+		},
+		{
+			name:      "missing dataSource",
+			ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: `{"type": "kafka", "spec": {}}`},
+			expectErr: "dataSource not found",
+		},
+		{
+			name: "compaction names another dataSource",
+			ingestion: v1alpha1.IngestionSpec{
+				Type:       v1alpha1.Kafka,
+				Spec:       spec,
+				Compaction: runtime.RawExtension{Raw: []byte(`{"dataSource": "other"}`)},
+			},
+			expectErr: "does not match",
+		},
+		{
+			name: "malformed rule",
+			ingestion: v1alpha1.IngestionSpec{
+				Type:  v1alpha1.Kafka,
+				Spec:  spec,
+				Rules: []runtime.RawExtension{{Raw: []byte(`{"type": "loadByInterval"}`)}},
+			},
+			expectErr: "requires field",
+		},
+	}
+
+	webhook := &DruidIngestionWebhook{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			di := &v1alpha1.DruidIngestion{
+				Spec: v1alpha1.DruidIngestionSpec{Ingestion: tt.ingestion, Action: tt.action},
+			}
+
+			_, err := webhook.ValidateCreate(context.TODO(), di)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, err = webhook.ValidateUpdate(context.TODO(), &v1alpha1.DruidIngestion{}, di)
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDruidIngestionWebhookValidateInvalidUpdate(t *testing.T) {
+	// an invalid DruidIngestion accepted before the webhook
+	old := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1", Finalizers: []string{DruidIngestionControllerFinalizer}},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka, Spec: `{"type": "kafka", "spec": {}}`},
+		},
+	}
+	webhook := &DruidIngestionWebhook{}
+
+	updated := old.DeepCopy()
+	updated.Labels = map[string]string{"team": "metrics"}
+	_, err := webhook.ValidateUpdate(context.TODO(), old, updated)
+	assert.NoError(t, err, "expected the metadata to be updated whatever the spec")
+
+	now := metav1.Now()
+	deleting := old.DeepCopy()
+	deleting.DeletionTimestamp = &now
+	removed := deleting.DeepCopy()
+	removed.Finalizers = nil
+	_, err = webhook.ValidateUpdate(context.TODO(), deleting, removed)
+	assert.NoError(t, err, "expected the finalizer to be removed whatever the spec")
+
+	changed := old.DeepCopy()
+	changed.Spec.Ingestion.Spec = `{"type": "kafka", "spec": {"ioConfig": {}}}`
+	_, err = webhook.ValidateUpdate(context.TODO(), old, changed)
+	assert.ErrorContains(t, err, "dataSource not found")
+}
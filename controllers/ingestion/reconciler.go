@@ -24,7 +24,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
@@ -41,39 +42,57 @@ import (
 )
 
 const (
-	DruidIngestionControllerCreateSuccess      = "DruidIngestionControllerCreateSuccess"
-	DruidIngestionControllerCreateFail         = "DruidIngestionControllerCreateFail"
-	DruidIngestionControllerGetSuccess         = "DruidIngestionControllerGetSuccess"
-	DruidIngestionControllerGetFail            = "DruidIngestionControllerGetFail"
-	DruidIngestionControllerUpdateSuccess      = "DruidIngestionControllerUpdateSuccess"
-	DruidIngestionControllerUpdateFail         = "DruidIngestionControllerUpdateFail"
-	DruidIngestionControllerShutDownSuccess    = "DruidIngestionControllerShutDownSuccess"
-	DruidIngestionControllerShutDownFail       = "DruidIngestionControllerShutDownFail"
-	DruidIngestionControllerPatchStatusSuccess = "DruidIngestionControllerPatchStatusSuccess"
-	DruidIngestionControllerPatchStatusFail    = "DruidIngestionControllerPatchStatusFail"
-	DruidIngestionControllerFinalizer          = "druidingestion.datainfra.io/finalizer"
+	DruidIngestionControllerCreateSuccess       = "DruidIngestionControllerCreateSuccess"
+	DruidIngestionControllerCreateFail          = "DruidIngestionControllerCreateFail"
+	DruidIngestionControllerGetSuccess          = "DruidIngestionControllerGetSuccess"
+	DruidIngestionControllerGetFail             = "DruidIngestionControllerGetFail"
+	DruidIngestionControllerUpdateSuccess       = "DruidIngestionControllerUpdateSuccess"
+	DruidIngestionControllerUpdateFail          = "DruidIngestionControllerUpdateFail"
+	DruidIngestionControllerShutDownSuccess     = "DruidIngestionControllerShutDownSuccess"
+	DruidIngestionControllerShutDownFail        = "DruidIngestionControllerShutDownFail"
+	DruidIngestionControllerPatchStatusSuccess  = "DruidIngestionControllerPatchStatusSuccess"
+	DruidIngestionControllerPatchStatusFail     = "DruidIngestionControllerPatchStatusFail"
+	DruidIngestionControllerTaskSuccess         = "DruidIngestionControllerTaskSuccess"
+	DruidIngestionControllerTaskFail            = "DruidIngestionControllerTaskFail"
+	DruidIngestionControllerSupervisorHealthy   = "DruidIngestionControllerSupervisorHealthy"
+	DruidIngestionControllerSupervisorUnhealthy = "DruidIngestionControllerSupervisorUnhealthy"
+	DruidIngestionControllerFinalizer           = "druidingestion.datainfra.io/finalizer"
 )
 
 func (r *DruidIngestionReconciler) do(ctx context.Context, di *v1alpha1.DruidIngestion) error {
-	basicAuth, err := druidapi.GetAuthCreds(
+	auth, err := druidapi.GetAuth(
 		ctx,
 		r.Client,
+		di.Namespace,
 		di.Spec.Auth,
 	)
 	if err != nil {
 		return err
 	}
 
-	svcName, err := druidapi.GetRouterSvcUrl(di.Namespace, di.Spec.DruidClusterName, r.Client)
+	svcName, err := druidapi.GetRouterSvcUrl(di.Namespace, di.Spec.DruidClusterName, di.Spec.APIEndpoint, druidapi.IndexerAPI, di.Spec.Auth.TLS != nil, r.Client)
 	if err != nil {
 		return err
 	}
 
+	coordinatorSvcName, err := druidapi.GetRouterSvcUrl(di.Namespace, di.Spec.DruidClusterName, di.Spec.APIEndpoint, druidapi.CoordinatorAPI, di.Spec.Auth.TLS != nil, r.Client)
+	if err != nil {
+		return err
+	}
+
+	// the sql tasks are submitted to the broker
+	sqlSvcName := svcName
+	if di.Spec.Ingestion.Type == v1alpha1.QueryControllerSQL {
+		if sqlSvcName, err = druidapi.GetRouterSvcUrl(di.Namespace, di.Spec.DruidClusterName, di.Spec.APIEndpoint, druidapi.SQLAPI, di.Spec.Auth.TLS != nil, r.Client); err != nil {
+			return err
+		}
+	}
+
 	build := builder.NewBuilder(
 		builder.ToNewBuilderRecorder(builder.BuilderRecorder{Recorder: r.Recorder, ControllerName: "DruidIngestionController"}),
 	)
 
-	_, err = r.CreateOrUpdate(di, svcName, *build, internalhttp.Auth{BasicAuth: basicAuth})
+	_, err = r.CreateOrUpdate(di, svcName, coordinatorSvcName, sqlSvcName, *build, auth)
 	if err != nil {
 		return err
 	}
@@ -91,38 +110,14 @@ func (r *DruidIngestionReconciler) do(ctx context.Context, di *v1alpha1.DruidIng
 	} else {
 		if controllerutil.ContainsFinalizer(di, DruidIngestionControllerFinalizer) {
 			// our finalizer is present, so lets handle any external dependency
-			svcName, err := druidapi.GetRouterSvcUrl(di.Namespace, di.Spec.DruidClusterName, r.Client)
-			if err != nil {
+			if err := r.ShutDownTask(di, svcName, *build, auth); err != nil {
 				return err
 			}
-
-			posthttp := internalhttp.NewHTTPClient(
-				&http.Client{},
-				&internalhttp.Auth{BasicAuth: basicAuth},
-			)
-
-			respShutDownTask, err := posthttp.Do(
-				http.MethodPost,
-				getPath(di.Spec.Ingestion.Type, svcName, http.MethodPost, di.Status.TaskId, true),
-				[]byte{},
-			)
-			if err != nil {
+			if err := r.RemoveCompactionAndRules(di, coordinatorSvcName, *build, auth); err != nil {
 				return err
 			}
-			if respShutDownTask.StatusCode != 200 {
-				build.Recorder.GenericEvent(
-					di,
-					v1.EventTypeWarning,
-					fmt.Sprintf("Resp [%s], StatusCode [%d]", string(respShutDownTask.ResponseBody), respShutDownTask.StatusCode),
-					DruidIngestionControllerShutDownFail,
-				)
-			} else {
-				build.Recorder.GenericEvent(
-					di,
-					v1.EventTypeNormal,
-					fmt.Sprintf("Resp [%s], StatusCode [%d]", string(respShutDownTask.ResponseBody), respShutDownTask.StatusCode),
-					DruidIngestionControllerShutDownSuccess,
-				)
+			if err := r.Cleanup(di, svcName, coordinatorSvcName, *build, auth); err != nil {
+				return err
 			}
 
 			// remove our finalizer from the list and update it.
@@ -137,9 +132,12 @@ func (r *DruidIngestionReconciler) do(ctx context.Context, di *v1alpha1.DruidIng
 }
 
 // getSpec extracts the current ingestion spec from the DruidIngestion object.
-// It first attempts to extract the nativeSpec, and if that is not available, it falls back to the Spec.
+// A sql ingestion is turned into the SQL task API payload, otherwise it first attempts to extract
+// the nativeSpec, and if that is not available, it falls back to the Spec.
 func getSpec(di *v1alpha1.DruidIngestion) (map[string]interface{}, error) {
-	if di.Spec.Ingestion.NativeSpec.Size() > 0 {
+	if di.Spec.Ingestion.SQL != nil {
+		return getSQLSpec(di.Spec.Ingestion.SQL)
+	} else if di.Spec.Ingestion.NativeSpec.Size() > 0 {
 		var nativeSpecMap map[string]interface{}
 		if err := json.Unmarshal(di.Spec.Ingestion.NativeSpec.Raw, &nativeSpecMap); err != nil {
 			return nil, fmt.Errorf("error unmarshalling nativeSpec: %v", err)
@@ -159,6 +157,21 @@ func getSpec(di *v1alpha1.DruidIngestion) (map[string]interface{}, error) {
 	}
 }
 
+// getSQLSpec builds the SQL task API payload, the query and its context.
+func getSQLSpec(sql *v1alpha1.SQLSpec) (map[string]interface{}, error) {
+	spec := map[string]interface{}{
+		"query": sql.Query,
+	}
+	if sql.Context.Size() > 0 {
+		var queryContext map[string]interface{}
+		if err := json.Unmarshal(sql.Context.Raw, &queryContext); err != nil {
+			return nil, fmt.Errorf("error unmarshalling sql context: %v", err)
+		}
+		spec["context"] = queryContext
+	}
+	return spec, nil
+}
+
 // getSpecJson extracts the current ingestion spec from the DruidIngestion object and returns it as a string.
 func getSpecJson(di *v1alpha1.DruidIngestion) (string, error) {
 	specData, err := getSpec(di)
@@ -197,11 +210,23 @@ func getRulesJson(di *v1alpha1.DruidIngestion) (string, error) {
 
 // extractDataSourceFromSpec extracts the dataSource from the spec map
 func getDataSource(di *v1alpha1.DruidIngestion) (string, error) {
+	if di.Spec.Ingestion.SQL != nil {
+		return getSQLDataSource(di.Spec.Ingestion.SQL.Query)
+	}
+
 	// Get the current ingestion spec
 	spec, err := getSpec(di)
 	if err != nil {
 		return "", err
 	}
+	return getSpecDataSource(spec)
+}
+
+// getSpecDataSource extracts the dataSource from a native ingestion spec, or from the query of a sql one.
+func getSpecDataSource(spec map[string]interface{}) (string, error) {
+	if query, ok := spec["query"].(string); ok {
+		return getSQLDataSource(query)
+	}
 
 	// Navigate through the nested structure to find dataSource
 	if specSection, ok := spec["spec"].(map[string]interface{}); ok {
@@ -214,6 +239,23 @@ func getDataSource(di *v1alpha1.DruidIngestion) (string, error) {
 	return "", fmt.Errorf("dataSource not found in spec")
 }
 
+// sqlTargetRegex matches the target table of an INSERT or REPLACE statement, quoted or not.
+var sqlTargetRegex = regexp.MustCompile(`(?is)^\s*(?:INSERT|REPLACE)\s+INTO\s+("(?:[^"]|"")+"|[^\s(]+)`)
+
+// getSQLDataSource extracts the dataSource written by an INSERT or REPLACE statement.
+func getSQLDataSource(query string) (string, error) {
+	match := sqlTargetRegex.FindStringSubmatch(query)
+	if match == nil {
+		return "", fmt.Errorf("dataSource not found in sql, expected an INSERT or REPLACE statement")
+	}
+
+	dataSource := match[1]
+	if strings.HasPrefix(dataSource, `"`) {
+		dataSource = strings.ReplaceAll(dataSource[1:len(dataSource)-1], `""`, `"`)
+	}
+	return dataSource, nil
+}
+
 func getCompaction(di *v1alpha1.DruidIngestion) (map[string]interface{}, error) {
 	compaction := di.Spec.Ingestion.Compaction
 
@@ -361,9 +403,13 @@ func (r *DruidIngestionReconciler) UpdateRules(
 	return false, fmt.Errorf("failed to update rules, status code: %d, response body: %s", respUpdateRules.StatusCode, respUpdateRules.ResponseBody)
 }
 
+// CreateOrUpdate submits the ingestion to the indexer API at svcName, or the sql task to the SQL API at sqlSvcName,
+// and applies its compaction config and rules through the coordinator API at coordinatorSvcName.
 func (r *DruidIngestionReconciler) CreateOrUpdate(
 	di *v1alpha1.DruidIngestion,
 	svcName string,
+	coordinatorSvcName string,
+	sqlSvcName string,
 	build builder.Builder,
 	auth internalhttp.Auth,
 ) (controllerutil.OperationResult, error) {
@@ -374,8 +420,31 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 		return controllerutil.OperationResultNone, err
 	}
 
+	submitSvcName := svcName
+	if di.Spec.Ingestion.Type == v1alpha1.QueryControllerSQL {
+		submitSvcName = sqlSvcName
+	}
+
+	// nothing is submitted while the ingestion is suspended
+	suspended, err := r.UpdateSuspend(di, svcName, build, auth)
+	if err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if suspended {
+		return controllerutil.OperationResultNone, nil
+	}
+
 	// check if task id does not exist in status
 	if di.Status.TaskId == "" && di.Status.CurrentIngestionSpec == "" {
+		// wait for the backoff after a failed submission
+		if !canSubmit(di, time.Now()) {
+			return controllerutil.OperationResultNone, nil
+		}
+
+		if err := r.verifyHadoopConfig(di, build); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+
 		// if does not exist create task
 		postHttp := internalhttp.NewHTTPClient(
 			&http.Client{},
@@ -385,7 +454,7 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 		// Create ingestion task
 		respCreateTask, err := postHttp.Do(
 			http.MethodPost,
-			getPath(di.Spec.Ingestion.Type, svcName, http.MethodPost, "", false),
+			getPath(di.Spec.Ingestion.Type, submitSvcName, http.MethodPost, "", false),
 			[]byte(specJson),
 		)
 
@@ -393,22 +462,26 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 			return controllerutil.OperationResultNone, err
 		}
 
-		compactionOk, err := r.UpdateCompaction(di, svcName, auth)
-		if err != nil {
+		if _, err := r.UpdateCompaction(di, coordinatorSvcName, auth); err != nil {
 			return controllerutil.OperationResultNone, err
 		}
 
-		rulesOk, err := r.UpdateRules(di, svcName, auth)
-		if err != nil {
+		if _, err := r.UpdateRules(di, coordinatorSvcName, auth); err != nil {
 			return controllerutil.OperationResultNone, err
 		}
 
 		// If the task creation was successful, patch the status with the new task ID.
-		if respCreateTask.StatusCode == 200 && compactionOk && rulesOk {
+		if respCreateTask.StatusCode == 200 {
 			taskId, err := getTaskIdFromResponse(respCreateTask.ResponseBody)
 			if err != nil {
 				return controllerutil.OperationResultNone, err
 			}
+			if err := r.resetSubmitAttempts(di); err != nil {
+				return controllerutil.OperationResultNone, err
+			}
+			if err := r.recordExistingAction(di); err != nil {
+				return controllerutil.OperationResultNone, err
+			}
 			result, err := r.makePatchDruidIngestionStatus(
 				di,
 				taskId,
@@ -433,32 +506,18 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 				DruidIngestionControllerPatchStatusSuccess)
 			return controllerutil.OperationResultCreated, nil
 		} else {
-			// If task creation failed, patch the status to reflect the failure.
-			taskId, err := getTaskIdFromResponse(respCreateTask.ResponseBody)
-			if err != nil {
-				return controllerutil.OperationResultNone, err
-			}
-			_, err = r.makePatchDruidIngestionStatus(
-				di,
-				taskId,
-				DruidIngestionControllerCreateFail,
-				string(respCreateTask.ResponseBody),
-				v1.ConditionTrue,
-				DruidIngestionControllerCreateFail,
-			)
-			if err != nil {
+			// If task creation failed, record the failure, the task is submitted again once the backoff elapsed.
+			if err := r.recordSubmitFailure(di, build, respCreateTask, DruidIngestionControllerCreateFail); err != nil {
 				return controllerutil.OperationResultNone, err
 			}
-			build.Recorder.GenericEvent(
-				di,
-				v1.EventTypeWarning,
-				fmt.Sprintf("Resp [%s], Status", string(respCreateTask.ResponseBody)),
-				DruidIngestionControllerCreateFail,
-			)
-			return controllerutil.OperationResultCreated, nil
+			return controllerutil.OperationResultNone, nil
 		}
 	} else {
 
+		if err := r.RunSupervisorAction(di, svcName, build, auth); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+
 		currentIngestionSpec, err := getSpecJson(di)
 		if err != nil {
 			return controllerutil.OperationResultNone, err
@@ -470,53 +529,74 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 		}
 
 		if !ok {
-			postHttp := internalhttp.NewHTTPClient(
-				&http.Client{},
-				&auth,
-			)
+			// a changed spec that failed to submit is retried once the backoff elapsed
+			if canSubmit(di, time.Now()) {
+				if err := r.verifyHadoopConfig(di, build); err != nil {
+					return controllerutil.OperationResultNone, err
+				}
 
-			respUpdateSpec, err := postHttp.Do(
-				http.MethodPost,
-				getPath(di.Spec.Ingestion.Type, svcName, http.MethodPost, "", false),
-				[]byte(specJson),
-			)
-			if err != nil {
-				return controllerutil.OperationResultNone, err
-			}
+				postHttp := internalhttp.NewHTTPClient(
+					&http.Client{},
+					&auth,
+				)
 
-			if respUpdateSpec.StatusCode == 200 {
-				// patch status to store the current valid ingestion spec json
-				taskId, err := getTaskIdFromResponse(respUpdateSpec.ResponseBody)
+				respUpdateSpec, err := postHttp.Do(
+					http.MethodPost,
+					getPath(di.Spec.Ingestion.Type, submitSvcName, http.MethodPost, "", false),
+					[]byte(specJson),
+				)
 				if err != nil {
 					return controllerutil.OperationResultNone, err
 				}
-				result, err := r.makePatchDruidIngestionStatus(
-					di,
-					taskId,
-					DruidIngestionControllerUpdateSuccess,
-					string(respUpdateSpec.ResponseBody),
-					v1.ConditionTrue,
-					DruidIngestionControllerUpdateSuccess,
-				)
-				if err != nil {
+
+				if respUpdateSpec.StatusCode == 200 {
+					// patch status to store the current valid ingestion spec json
+					taskId, err := getTaskIdFromResponse(respUpdateSpec.ResponseBody)
+					if err != nil {
+						return controllerutil.OperationResultNone, err
+					}
+					if err := r.resetSubmitAttempts(di); err != nil {
+						return controllerutil.OperationResultNone, err
+					}
+					result, err := r.makePatchDruidIngestionStatus(
+						di,
+						taskId,
+						DruidIngestionControllerUpdateSuccess,
+						string(respUpdateSpec.ResponseBody),
+						v1.ConditionTrue,
+						DruidIngestionControllerUpdateSuccess,
+					)
+					if err != nil {
+						return controllerutil.OperationResultNone, err
+					}
+					build.Recorder.GenericEvent(
+						di,
+						v1.EventTypeNormal,
+						fmt.Sprintf("Resp [%s]", string(respUpdateSpec.ResponseBody)),
+						DruidIngestionControllerUpdateSuccess,
+					)
+					build.Recorder.GenericEvent(
+						di,
+						v1.EventTypeNormal,
+						fmt.Sprintf("Resp [%s], Result [%s]", string(respUpdateSpec.ResponseBody), result),
+						DruidIngestionControllerPatchStatusSuccess)
+				} else if err := r.recordSubmitFailure(di, build, respUpdateSpec, DruidIngestionControllerUpdateFail); err != nil {
 					return controllerutil.OperationResultNone, err
 				}
-				build.Recorder.GenericEvent(
-					di,
-					v1.EventTypeNormal,
-					fmt.Sprintf("Resp [%s]", string(respUpdateSpec.ResponseBody)),
-					DruidIngestionControllerUpdateSuccess,
-				)
-				build.Recorder.GenericEvent(
-					di,
-					v1.EventTypeNormal,
-					fmt.Sprintf("Resp [%s], Result [%s]", string(respUpdateSpec.ResponseBody), result),
-					DruidIngestionControllerPatchStatusSuccess)
 			}
-
+		} else if isSupervisor(di.Spec.Ingestion.Type) {
+			// poll the supervisor on every reconcile
+			if err := r.UpdateSupervisorStatus(di, svcName, build, auth); err != nil {
+				return controllerutil.OperationResultNone, err
+			}
+		} else if !isTaskComplete(di.Status.TaskStatus) {
+			// poll the task until it completes
+			if err := r.UpdateTaskStatus(di, svcName, build, auth); err != nil {
+				return controllerutil.OperationResultNone, err
+			}
 		}
 
-		compactionOk, err := r.UpdateCompaction(di, svcName, auth)
+		compactionOk, err := r.UpdateCompaction(di, coordinatorSvcName, auth)
 		if err != nil {
 			return controllerutil.OperationResultNone, err
 		}
@@ -542,34 +622,18 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 			)
 		}
 
-		// compare the rules state
-		rulesEqual := reflect.DeepEqual(di.Status.CurrentRules, di.Spec.Ingestion.Rules)
+		// apply changed rules, and check them for drift on the coordinator
+		if err := r.SyncRules(di, coordinatorSvcName, build, auth); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
 
-		if !rulesEqual {
-			rulesOk, err := r.UpdateRules(di, svcName, auth)
-			if err != nil {
+		// compaction, rules, replaced supervisors and dataSources are cleaned up by the finalizer on deletion
+		if di.DeletionTimestamp.IsZero() {
+			if err := r.RemoveCompactionAndRules(di, coordinatorSvcName, build, auth); err != nil {
 				return controllerutil.OperationResultNone, err
 			}
-
-			if rulesOk {
-				// patch status to store the current valid rules json
-				_, err := r.makePatchDruidIngestionStatus(
-					di,
-					di.Status.TaskId,
-					DruidIngestionControllerUpdateSuccess,
-					"rules updated",
-					v1.ConditionTrue,
-					DruidIngestionControllerUpdateSuccess,
-				)
-				if err != nil {
-					return controllerutil.OperationResultNone, err
-				}
-				build.Recorder.GenericEvent(
-					di,
-					v1.EventTypeNormal,
-					"rules updated",
-					DruidIngestionControllerUpdateSuccess,
-				)
+			if err := r.Cleanup(di, svcName, coordinatorSvcName, build, auth); err != nil {
+				return controllerutil.OperationResultNone, err
 			}
 		}
 
@@ -578,6 +642,79 @@ func (r *DruidIngestionReconciler) CreateOrUpdate(
 	}
 }
 
+// verifyHadoopConfig checks, for index-hadoop ingestions, that the referenced Druid cluster mounts the
+// hdfs-site.xml and core-site.xml the Hadoop task reads from the common config.
+func (r *DruidIngestionReconciler) verifyHadoopConfig(di *v1alpha1.DruidIngestion, build builder.Builder) error {
+	if di.Spec.Ingestion.Type != v1alpha1.HadoopIndexHadoop {
+		return nil
+	}
+
+	drd := &v1alpha1.Druid{}
+	if err := r.Get(context.Background(), types.NamespacedName{Namespace: di.Namespace, Name: di.Spec.DruidClusterName}, drd); err != nil {
+		return err
+	}
+
+	var missing []string
+	if drd.Spec.HdfsSite == "" {
+		missing = append(missing, "hdfs-site.xml")
+	}
+	if drd.Spec.CoreSite == "" {
+		missing = append(missing, "core-site.xml")
+	}
+
+	if len(missing) > 0 {
+		err := fmt.Errorf("druid cluster [%s] is missing %s required by index-hadoop ingestion", di.Spec.DruidClusterName, strings.Join(missing, ", "))
+		build.Recorder.GenericEvent(di, v1.EventTypeWarning, err.Error(), DruidIngestionControllerCreateFail)
+		return err
+	}
+
+	return nil
+}
+
+// ShutDownTask shuts down the task or supervisor recorded in the status of the DruidIngestion.
+func (r *DruidIngestionReconciler) ShutDownTask(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	if di.Status.TaskId == "" {
+		// nothing was submitted, or the task was cancelled on suspend
+		return nil
+	}
+
+	posthttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	respShutDownTask, err := posthttp.Do(
+		http.MethodPost,
+		getPath(di.Spec.Ingestion.Type, svcName, http.MethodPost, di.Status.TaskId, true),
+		[]byte{},
+	)
+	if err != nil {
+		return err
+	}
+	if respShutDownTask.StatusCode != 200 {
+		build.Recorder.GenericEvent(
+			di,
+			v1.EventTypeWarning,
+			fmt.Sprintf("Resp [%s], StatusCode [%d]", string(respShutDownTask.ResponseBody), respShutDownTask.StatusCode),
+			DruidIngestionControllerShutDownFail,
+		)
+	} else {
+		build.Recorder.GenericEvent(
+			di,
+			v1.EventTypeNormal,
+			fmt.Sprintf("Resp [%s], StatusCode [%d]", string(respShutDownTask.ResponseBody), respShutDownTask.StatusCode),
+			DruidIngestionControllerShutDownSuccess,
+		)
+	}
+
+	return nil
+}
+
 func (r *DruidIngestionReconciler) makePatchDruidIngestionStatus(
 	di *v1alpha1.DruidIngestion,
 	taskId string,
@@ -594,9 +731,17 @@ func (r *DruidIngestionReconciler) makePatchDruidIngestionStatus(
 		return controllerutil.OperationResultNone, specErr
 	}
 
-	if _, _, err := patchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+	// a spec without dataSource is not tracked for cleanup
+	dataSource, _ := getDataSource(di)
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
 
 		in := obj.(*v1alpha1.DruidIngestion)
+		trackResources(in, dataSource, taskId)
+		if in.Status.TaskId != taskId {
+			// a new task is not polled yet
+			in.Status.TaskStatus = ""
+		}
 		in.Status.CurrentIngestionSpec = ingestionSpec
 		in.Status.CurrentRules = di.Spec.Ingestion.Rules
 		in.Status.TaskId = taskId
@@ -619,7 +764,7 @@ func getPath(
 	shutDownTask bool) string {
 
 	switch ingestionType {
-	case v1alpha1.NativeBatchIndexParallel:
+	case v1alpha1.NativeBatchIndexParallel, v1alpha1.HadoopIndexHadoop:
 		if httpMethod == http.MethodGet {
 			// get task
 			return druidapi.MakePath(svcName, "indexer", "task", taskId)
@@ -630,8 +775,7 @@ func getPath(
 			// shutdown task
 			return druidapi.MakePath(svcName, "indexer", "task", taskId, "shutdown")
 		}
-	case v1alpha1.HadoopIndexHadoop:
-	case v1alpha1.Kafka:
+	case v1alpha1.Kafka, v1alpha1.Kinesis:
 		if httpMethod == http.MethodGet {
 			// get supervisor task
 			return druidapi.MakePath(svcName, "indexer", "supervisor", taskId)
@@ -642,8 +786,17 @@ func getPath(
 			// shut down supervisor
 			return druidapi.MakePath(svcName, "indexer", "supervisor", taskId, "shutdown")
 		}
-	case v1alpha1.Kinesis:
 	case v1alpha1.QueryControllerSQL:
+		if httpMethod == http.MethodGet {
+			// get task
+			return druidapi.MakePath(svcName, "indexer", "task", taskId)
+		} else if httpMethod == http.MethodPost && !shutDownTask {
+			// submit sql task, every change of the query runs a new task
+			return druidapi.MakeSQLTaskPath(svcName)
+		} else if shutDownTask {
+			// shutdown task
+			return druidapi.MakePath(svcName, "indexer", "task", taskId, "shutdown")
+		}
 	default:
 		return ""
 	}
@@ -652,8 +805,9 @@ func getPath(
 }
 
 type taskHolder struct {
-	Task string `json:"task"` // tasks
-	ID   string `json:"id"`   // supervisor
+	Task   string `json:"task"`   // tasks
+	ID     string `json:"id"`     // supervisor
+	TaskID string `json:"taskId"` // sql tasks
 }
 
 func getTaskIdFromResponse(resp string) (string, error) {
@@ -670,41 +824,9 @@ func getTaskIdFromResponse(resp string) (string, error) {
 	if task.ID != "" {
 		return task.ID, nil
 	}
-
-	return "", errors.New("task id not found")
-}
-
-type VerbType string
-
-type (
-	TransformStatusFunc func(obj client.Object) client.Object
-)
-
-const (
-	VerbPatched   VerbType = "Patched"
-	VerbUnchanged VerbType = "Unchanged"
-)
-
-func patchStatus(ctx context.Context, c client.Client, obj client.Object, transform TransformStatusFunc, opts ...client.SubResourcePatchOption) (client.Object, VerbType, error) {
-	key := types.NamespacedName{
-		Namespace: obj.GetNamespace(),
-		Name:      obj.GetName(),
-	}
-	err := c.Get(ctx, key, obj)
-	if err != nil {
-		return nil, VerbUnchanged, err
+	if task.TaskID != "" {
+		return task.TaskID, nil
 	}
 
-	// The body of the request was in an unknown format -
-	// accepted media types include:
-	//   - application/json-patch+json,
-	//   - application/merge-patch+json,
-	//   - application/apply-patch+yaml
-	patch := client.MergeFrom(obj)
-	obj = transform(obj.DeepCopyObject().(client.Object))
-	err = c.Status().Patch(ctx, obj, patch, opts...)
-	if err != nil {
-		return nil, VerbUnchanged, err
-	}
-	return obj, VerbPatched, nil
+	return "", errors.New("task id not found")
 }
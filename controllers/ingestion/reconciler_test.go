@@ -19,16 +19,25 @@ under the License.
 package ingestion
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
 
 	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 func TestGetRules(t *testing.T) {
@@ -433,6 +442,23 @@ func TestGetPath(t *testing.T) {
 			shutDownTask:  true,
 			expected:      "http://example-druid-service/druid/indexer/v1/task/task1/shutdown",
 		},
+		{
+			name:          "HadoopCreateTask",
+			ingestionType: v1alpha1.HadoopIndexHadoop,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodPost,
+			shutDownTask:  false,
+			expected:      "http://example-druid-service/druid/indexer/v1/task",
+		},
+		{
+			name:          "HadoopShutdownTask",
+			ingestionType: v1alpha1.HadoopIndexHadoop,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodPost,
+			taskId:        "index_hadoop_1",
+			shutDownTask:  true,
+			expected:      "http://example-druid-service/druid/indexer/v1/task/index_hadoop_1/shutdown",
+		},
 		{
 			name:          "KafkaGetSupervisorTask",
 			ingestionType: v1alpha1.Kafka,
@@ -459,9 +485,59 @@ func TestGetPath(t *testing.T) {
 			expected:      "http://example-druid-service/druid/indexer/v1/supervisor/supervisor1/shutdown",
 		},
 		{
-			name:          "UnsupportedIngestionType",
+			name:          "KinesisGetSupervisorTask",
+			ingestionType: v1alpha1.Kinesis,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodGet,
+			taskId:        "supervisor1",
+			expected:      "http://example-druid-service/druid/indexer/v1/supervisor/supervisor1",
+		},
+		{
+			name:          "KinesisCreateUpdateSupervisorTask",
+			ingestionType: v1alpha1.Kinesis,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodPost,
+			shutDownTask:  false,
+			expected:      "http://example-druid-service/druid/indexer/v1/supervisor",
+		},
+		{
+			name:          "KinesisShutdownSupervisor",
 			ingestionType: v1alpha1.Kinesis,
 			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodPost,
+			taskId:        "supervisor1",
+			shutDownTask:  true,
+			expected:      "http://example-druid-service/druid/indexer/v1/supervisor/supervisor1/shutdown",
+		},
+		{
+			name:          "SQLGetTask",
+			ingestionType: v1alpha1.QueryControllerSQL,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodGet,
+			taskId:        "query-1",
+			expected:      "http://example-druid-service/druid/indexer/v1/task/query-1",
+		},
+		{
+			name:          "SQLSubmitTask",
+			ingestionType: v1alpha1.QueryControllerSQL,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodPost,
+			shutDownTask:  false,
+			expected:      "http://example-druid-service/druid/v2/sql/task",
+		},
+		{
+			name:          "SQLShutdownTask",
+			ingestionType: v1alpha1.QueryControllerSQL,
+			svcName:       "http://example-druid-service",
+			httpMethod:    http.MethodPost,
+			taskId:        "query-1",
+			shutDownTask:  true,
+			expected:      "http://example-druid-service/druid/indexer/v1/task/query-1/shutdown",
+		},
+		{
+			name:          "UnsupportedIngestionType",
+			ingestionType: "unknown",
+			svcName:       "http://example-druid-service",
 			httpMethod:    http.MethodGet,
 			expected:      "",
 		},
@@ -630,3 +706,272 @@ func TestGetDataSource(t *testing.T) {
 		})
 	}
 }
+
+// newTestReconciler returns a DruidIngestionReconciler backed by a fake client holding the given objects.
+func newTestReconciler(t *testing.T, objs ...client.Object) *DruidIngestionReconciler {
+	return &DruidIngestionReconciler{
+		Client:   testutil.NewFakeClient(t, objs...),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+func TestKinesisSupervisorLifecycle(t *testing.T) {
+	var (
+		submittedSpecs []string
+		shutDownPaths  []string
+	)
+
+	// Stand-in for the overlord supervisor API and the coordinator compaction and rules API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/druid/coordinator/v1/config/compaction/metrics-kinesis":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet && r.URL.Path == "/druid/coordinator/v1/rules/metrics-kinesis":
+			_, _ = w.Write([]byte(`[{"type": "loadForever"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/druid/coordinator/v1/config/compaction",
+			r.Method == http.MethodPost && r.URL.Path == "/druid/coordinator/v1/rules/metrics-kinesis":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodPost && r.URL.Path == "/druid/indexer/v1/supervisor":
+			body, _ := io.ReadAll(r.Body)
+			submittedSpecs = append(submittedSpecs, string(body))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"id": "metrics-kinesis"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/druid/indexer/v1/supervisor/metrics-kinesis/shutdown":
+			shutDownPaths = append(shutDownPaths, r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"id": "metrics-kinesis"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kinesis", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{
+				Type:       v1alpha1.Kinesis,
+				Spec:       `{"type": "kinesis", "spec": {"dataSchema": {"dataSource": "metrics-kinesis"}, "ioConfig": {"stream": "metrics"}}}`,
+				Compaction: runtime.RawExtension{Raw: []byte(`{"skipOffsetFromLatest": "PT1H"}`)},
+				Rules:      []runtime.RawExtension{{Raw: []byte(`{"type": "loadForever"}`)}},
+			},
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+	auth := internalhttp.Auth{}
+
+	// submit
+	result, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultCreated, result)
+	assert.Len(t, submittedSpecs, 1)
+
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, "metrics-kinesis", di.Status.TaskId)
+	assert.Equal(t, DruidIngestionControllerCreateSuccess, di.Status.Type)
+
+	// update
+	di.Spec.Ingestion.Spec = `{"type": "kinesis", "spec": {"dataSchema": {"dataSource": "metrics-kinesis"}, "ioConfig": {"stream": "metrics-v2"}}}`
+	result, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, result)
+	assert.Len(t, submittedSpecs, 2)
+	assert.Contains(t, submittedSpecs[1], "metrics-v2")
+
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, DruidIngestionControllerUpdateSuccess, di.Status.Type)
+
+	// shutdown on delete
+	assert.NoError(t, r.ShutDownTask(di, server.URL, build, auth))
+	assert.Equal(t, []string{"/druid/indexer/v1/supervisor/metrics-kinesis/shutdown"}, shutDownPaths)
+}
+
+func TestGetSQLDataSource(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		expected     string
+		expectingErr bool
+	}{
+		{
+			name:     "Insert",
+			query:    "INSERT INTO wikipedia SELECT * FROM ext PARTITIONED BY DAY",
+			expected: "wikipedia",
+		},
+		{
+			name:     "Quoted replace over multiple lines",
+			query:    "\n  replace into \"wiki\"\"pedia\"\n  OVERWRITE ALL\n  SELECT * FROM ext PARTITIONED BY DAY",
+			expected: `wiki"pedia`,
+		},
+		{
+			name:         "Select",
+			query:        "SELECT * FROM wikipedia",
+			expectingErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataSource, err := getSQLDataSource(tt.query)
+			if tt.expectingErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, dataSource)
+			}
+		})
+	}
+}
+
+func TestSQLTaskLifecycle(t *testing.T) {
+	var (
+		submittedQueries []string
+		taskStatus       = "RUNNING"
+		statusPolls      int
+	)
+
+	// Stand-in for the SQL task API and the overlord task API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/druid/v2/sql/task":
+			body, _ := io.ReadAll(r.Body)
+			submittedQueries = append(submittedQueries, string(body))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"taskId": "query-%d", "state": "RUNNING"}`, len(submittedQueries))))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/status"):
+			statusPolls++
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"task": "query-1", "status": {"id": "query-1", "status": "%s"}}`, taskStatus)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "sql", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.QueryControllerSQL,
+				SQL: &v1alpha1.SQLSpec{
+					Query:   "INSERT INTO wikipedia SELECT * FROM ext PARTITIONED BY DAY",
+					Context: runtime.RawExtension{Raw: []byte(`{"maxNumTasks": 3}`)},
+				},
+			},
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+	auth := internalhttp.Auth{}
+
+	reconcile := func() {
+		_, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	}
+
+	// submit
+	reconcile()
+	assert.Len(t, submittedQueries, 1)
+	assert.Contains(t, submittedQueries[0], `"maxNumTasks":3`)
+	assert.Equal(t, "query-1", di.Status.TaskId)
+
+	// poll while running
+	reconcile()
+	assert.Equal(t, "RUNNING", di.Status.TaskStatus)
+
+	// poll until completion
+	taskStatus = "SUCCESS"
+	reconcile()
+	assert.Equal(t, "SUCCESS", di.Status.TaskStatus)
+	assert.Equal(t, 2, statusPolls)
+
+	reconcile()
+	assert.Equal(t, 2, statusPolls, "a completed task is not polled")
+	assert.Len(t, submittedQueries, 1)
+
+	// resubmit when the query changes
+	di.Spec.Ingestion.SQL.Query = "REPLACE INTO wikipedia OVERWRITE ALL SELECT * FROM ext PARTITIONED BY DAY"
+	reconcile()
+	assert.Len(t, submittedQueries, 2)
+	assert.Equal(t, "query-2", di.Status.TaskId)
+	assert.Empty(t, di.Status.TaskStatus)
+}
+
+func TestHadoopTaskLifecycle(t *testing.T) {
+	var (
+		submittedSpecs []string
+		shutDownPaths  []string
+	)
+
+	// Stand-in for the overlord task API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/druid/indexer/v1/task":
+			body, _ := io.ReadAll(r.Body)
+			submittedSpecs = append(submittedSpecs, string(body))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"task": "index_hadoop_wikipedia"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/druid/indexer/v1/task/index_hadoop_wikipedia/status":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"task": "index_hadoop_wikipedia", "status": {"status": "FAILED", "errorMsg": "input path not found"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/druid/indexer/v1/task/index_hadoop_wikipedia/shutdown":
+			shutDownPaths = append(shutDownPaths, r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"task": "index_hadoop_wikipedia"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	drd := &v1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "default"},
+		Spec: v1alpha1.DruidSpec{
+			CoreSite: "<configuration></configuration>",
+		},
+	}
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "hadoop", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			DruidClusterName: "tiny-cluster",
+			Ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.HadoopIndexHadoop,
+				Spec: `{"type": "index_hadoop", "spec": {"dataSchema": {"dataSource": "wikipedia"}, "ioConfig": {"type": "hadoop"}}}`,
+			},
+		},
+	}
+
+	r := newTestReconciler(t, drd, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+	auth := internalhttp.Auth{}
+
+	// hdfs-site.xml is missing on the druid cluster
+	_, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.ErrorContains(t, err, "hdfs-site.xml")
+	assert.Empty(t, submittedSpecs)
+
+	drd.Spec.HdfsSite = "<configuration></configuration>"
+	assert.NoError(t, r.Update(context.TODO(), drd))
+
+	// submit
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Len(t, submittedSpecs, 1)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, "index_hadoop_wikipedia", di.Status.TaskId)
+
+	// status tracking
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, "FAILED", di.Status.TaskStatus)
+	assert.Contains(t, di.Status.Message, "input path not found")
+
+	// cancellation
+	assert.NoError(t, r.ShutDownTask(di, server.URL, build, auth))
+	assert.Equal(t, []string{"/druid/indexer/v1/task/index_hadoop_wikipedia/shutdown"}, shutDownPaths)
+}
@@ -0,0 +1,184 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultInitialBackoff = 10 * time.Second
+	defaultMaxBackoff     = 10 * time.Minute
+
+	retriesExhaustedReason = "RetriesExhausted"
+)
+
+// getBackoff returns the wait after the given number of failed submissions.
+func getBackoff(policy *v1alpha1.RetryPolicy, attempts int32) time.Duration {
+	initialBackoff, maxBackoff := defaultInitialBackoff, defaultMaxBackoff
+	if policy != nil && policy.InitialBackoff != nil {
+		initialBackoff = policy.InitialBackoff.Duration
+	}
+	if policy != nil && policy.MaxBackoff != nil {
+		maxBackoff = policy.MaxBackoff.Duration
+	}
+
+	backoff := initialBackoff
+	for i := int32(1); i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// getSubmitAttempts returns the number of failed submissions of the current generation of the spec.
+func getSubmitAttempts(di *v1alpha1.DruidIngestion) int32 {
+	if di.Status.FailedGeneration != di.Generation {
+		return 0
+	}
+	return di.Status.SubmitAttempts
+}
+
+func isRetryExhausted(policy *v1alpha1.RetryPolicy, attempts int32) bool {
+	return policy != nil && policy.MaxAttempts > 0 && attempts >= policy.MaxAttempts
+}
+
+// canSubmit returns false while backing off after a failed submission, and once retries are given up.
+func canSubmit(di *v1alpha1.DruidIngestion, now time.Time) bool {
+	attempts := getSubmitAttempts(di)
+	if attempts == 0 {
+		return true
+	}
+
+	policy := di.Spec.RetryPolicy
+	if isRetryExhausted(policy, attempts) && policy.GiveUp {
+		return false
+	}
+
+	return di.Status.NextSubmitTime == nil || !now.Before(di.Status.NextSubmitTime.Time)
+}
+
+// getRequeueAfter returns the wait before the next reconcile: the resync period, or the remaining backoff when
+// a retry of the submission is scheduled sooner.
+func getRequeueAfter(di *v1alpha1.DruidIngestion, now time.Time, resync time.Duration) time.Duration {
+	if getSubmitAttempts(di) == 0 || di.Status.NextSubmitTime == nil {
+		return resync
+	}
+	remaining := di.Status.NextSubmitTime.Sub(now)
+	if remaining <= 0 {
+		// the retry is already due
+		return time.Second
+	}
+	if resync > 0 && resync < remaining {
+		return resync
+	}
+	return remaining
+}
+
+// recordSubmitFailure counts a submission rejected by Druid and schedules its retry. The Failed condition
+// is set once the retry policy is exhausted.
+func (r *DruidIngestionReconciler) recordSubmitFailure(
+	di *v1alpha1.DruidIngestion,
+	build builder.Builder,
+	resp *internalhttp.Response,
+	reason string,
+) error {
+	policy := di.Spec.RetryPolicy
+	attempts := getSubmitAttempts(di) + 1
+	exhausted := isRetryExhausted(policy, attempts)
+
+	now := time.Now()
+	var nextSubmitTime *metav1.Time
+	msg := fmt.Sprintf("submission failed %d times, Resp [%s], StatusCode [%d]", attempts, string(resp.ResponseBody), resp.StatusCode)
+	if exhausted && policy.GiveUp {
+		msg = fmt.Sprintf("%s, giving up until the spec changes", msg)
+	} else {
+		backoff := getBackoff(policy, attempts)
+		nextSubmitTime = &metav1.Time{Time: now.Add(backoff)}
+		msg = fmt.Sprintf("%s, retrying in %s", msg, backoff)
+	}
+
+	patched, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.SubmitAttempts = attempts
+		in.Status.FailedGeneration = in.Generation
+		in.Status.NextSubmitTime = nextSubmitTime
+		in.Status.LastUpdateTime = metav1.Time{Time: now}
+		in.Status.Message = msg
+		in.Status.Reason = reason
+		in.Status.Status = v1.ConditionTrue
+		in.Status.Type = reason
+		if exhausted {
+			meta.SetStatusCondition(&in.Status.Conditions, metav1.Condition{
+				Type:               v1alpha1.DruidIngestionConditionFailed,
+				Status:             metav1.ConditionTrue,
+				Reason:             retriesExhaustedReason,
+				Message:            msg,
+				ObservedGeneration: in.Generation,
+			})
+		}
+		return in
+	})
+	if err != nil {
+		return err
+	}
+	// the reconcile is requeued after the backoff recorded in the status
+	di.Status = patched.(*v1alpha1.DruidIngestion).Status
+
+	build.Recorder.GenericEvent(di, v1.EventTypeWarning, msg, reason)
+	return nil
+}
+
+// resetSubmitAttempts clears the failed submissions once a submission succeeded.
+func (r *DruidIngestionReconciler) resetSubmitAttempts(di *v1alpha1.DruidIngestion) error {
+	if di.Status.SubmitAttempts == 0 && di.Status.NextSubmitTime == nil {
+		return nil
+	}
+
+	_, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.SubmitAttempts = 0
+		in.Status.FailedGeneration = 0
+		in.Status.NextSubmitTime = nil
+		if failed := meta.FindStatusCondition(in.Status.Conditions, v1alpha1.DruidIngestionConditionFailed); failed != nil && failed.Reason == retriesExhaustedReason {
+			meta.SetStatusCondition(&in.Status.Conditions, metav1.Condition{
+				Type:               v1alpha1.DruidIngestionConditionFailed,
+				Status:             metav1.ConditionFalse,
+				Reason:             "Submitted",
+				Message:            "submission succeeded",
+				ObservedGeneration: in.Generation,
+			})
+		}
+		return in
+	})
+	return err
+}
@@ -0,0 +1,284 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *v1alpha1.RetryPolicy
+		attempts int32
+		expected time.Duration
+	}{
+		{
+			name:     "default first attempt",
+			attempts: 1,
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "default doubles",
+			attempts: 3,
+			expected: 40 * time.Second,
+		},
+		{
+			name:     "default capped",
+			attempts: 20,
+			expected: 10 * time.Minute,
+		},
+		{
+			name: "custom policy",
+			policy: &v1alpha1.RetryPolicy{
+				InitialBackoff: &metav1.Duration{Duration: time.Minute},
+				MaxBackoff:     &metav1.Duration{Duration: 3 * time.Minute},
+			},
+			attempts: 3,
+			expected: 3 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, getBackoff(tt.policy, tt.attempts))
+		})
+	}
+}
+
+func TestCanSubmit(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		policy   *v1alpha1.RetryPolicy
+		status   v1alpha1.DruidIngestionStatus
+		expected bool
+	}{
+		{
+			name:     "no failure",
+			expected: true,
+		},
+		{
+			name: "backing off",
+			status: v1alpha1.DruidIngestionStatus{
+				SubmitAttempts:   1,
+				FailedGeneration: 1,
+				NextSubmitTime:   &metav1.Time{Time: now.Add(time.Minute)},
+			},
+			expected: false,
+		},
+		{
+			name: "backoff elapsed",
+			status: v1alpha1.DruidIngestionStatus{
+				SubmitAttempts:   1,
+				FailedGeneration: 1,
+				NextSubmitTime:   &metav1.Time{Time: now.Add(-time.Second)},
+			},
+			expected: true,
+		},
+		{
+			name: "failure of a previous generation",
+			status: v1alpha1.DruidIngestionStatus{
+				SubmitAttempts:   1,
+				FailedGeneration: 0,
+				NextSubmitTime:   &metav1.Time{Time: now.Add(time.Minute)},
+			},
+			expected: true,
+		},
+		{
+			name:   "given up",
+			policy: &v1alpha1.RetryPolicy{MaxAttempts: 2, GiveUp: true},
+			status: v1alpha1.DruidIngestionStatus{
+				SubmitAttempts:   2,
+				FailedGeneration: 1,
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec:       v1alpha1.DruidIngestionSpec{RetryPolicy: tt.policy},
+				Status:     tt.status,
+			}
+			assert.Equal(t, tt.expected, canSubmit(di, now))
+		})
+	}
+}
+
+func TestCreateRetry(t *testing.T) {
+	var calls int
+	statusCode := http.StatusBadRequest
+
+	// Stand-in for the overlord task API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/druid/indexer/v1/task" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		calls++
+		w.WriteHeader(statusCode)
+		if statusCode == http.StatusOK {
+			_, _ = w.Write([]byte(`{"task": "index_parallel_1"}`))
+		} else {
+			_, _ = w.Write([]byte(`{"error": "Cannot construct instance of IndexTask"}`))
+		}
+	}))
+	defer server.Close()
+
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default", Generation: 1},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.NativeBatchIndexParallel,
+				Spec: `{"type": "index_parallel", "spec": {"dataSchema": {"dataSource": "wikipedia"}}}`,
+			},
+			RetryPolicy: &v1alpha1.RetryPolicy{MaxAttempts: 2, GiveUp: true},
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+	auth := internalhttp.Auth{}
+
+	// the rejected task is backed off
+	_, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, int32(1), di.Status.SubmitAttempts)
+	assert.NotNil(t, di.Status.NextSubmitTime)
+	assert.Empty(t, di.Status.TaskId)
+	assert.False(t, meta.IsStatusConditionTrue(di.Status.Conditions, v1alpha1.DruidIngestionConditionFailed))
+
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// retried once the backoff elapsed, then given up
+	di.Status.NextSubmitTime = &metav1.Time{Time: time.Now().Add(-time.Second)}
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, int32(2), di.Status.SubmitAttempts)
+	assert.Nil(t, di.Status.NextSubmitTime)
+	assert.True(t, meta.IsStatusConditionTrue(di.Status.Conditions, v1alpha1.DruidIngestionConditionFailed))
+
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// a new generation of the spec is submitted again
+	statusCode = http.StatusOK
+	di.Generation = 2
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.Equal(t, "index_parallel_1", di.Status.TaskId)
+	assert.Equal(t, int32(0), di.Status.SubmitAttempts)
+	assert.False(t, meta.IsStatusConditionTrue(di.Status.Conditions, v1alpha1.DruidIngestionConditionFailed))
+}
+
+func TestGetRequeueAfter(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(d)} }
+
+	tests := []struct {
+		name     string
+		status   v1alpha1.DruidIngestionStatus
+		resync   time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "no failed submission",
+			resync:   10 * time.Second,
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "backoff shorter than the resync",
+			status:   v1alpha1.DruidIngestionStatus{SubmitAttempts: 1, FailedGeneration: 1, NextSubmitTime: at(2 * time.Second)},
+			resync:   10 * time.Second,
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "backoff longer than the resync",
+			status:   v1alpha1.DruidIngestionStatus{SubmitAttempts: 5, FailedGeneration: 1, NextSubmitTime: at(time.Minute)},
+			resync:   10 * time.Second,
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "backoff without resync",
+			status:   v1alpha1.DruidIngestionStatus{SubmitAttempts: 5, FailedGeneration: 1, NextSubmitTime: at(time.Minute)},
+			expected: time.Minute,
+		},
+		{
+			name:     "retry due",
+			status:   v1alpha1.DruidIngestionStatus{SubmitAttempts: 1, FailedGeneration: 1, NextSubmitTime: at(-time.Second)},
+			resync:   10 * time.Second,
+			expected: time.Second,
+		},
+		{
+			name:     "failure of a previous generation",
+			status:   v1alpha1.DruidIngestionStatus{SubmitAttempts: 1, FailedGeneration: 0, NextSubmitTime: at(2 * time.Second)},
+			resync:   10 * time.Second,
+			expected: 10 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			di := &v1alpha1.DruidIngestion{ObjectMeta: metav1.ObjectMeta{Generation: 1}, Status: tt.status}
+			assert.Equal(t, tt.expected, getRequeueAfter(di, now, tt.resync))
+		})
+	}
+}
+
+func TestRecordSubmitFailureSchedulesRequeue(t *testing.T) {
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default", Generation: 1},
+		Spec: v1alpha1.DruidIngestionSpec{
+			RetryPolicy: &v1alpha1.RetryPolicy{InitialBackoff: &metav1.Duration{Duration: 2 * time.Second}},
+		},
+	}
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+	resp := &internalhttp.Response{StatusCode: http.StatusBadRequest, ResponseBody: `{"error": "invalid spec"}`}
+	assert.NoError(t, r.recordSubmitFailure(di, build, resp, DruidIngestionControllerCreateFail))
+
+	// the backoff below the resync period is the requeue
+	requeueAfter := getRequeueAfter(di, time.Now(), 10*time.Second)
+	assert.True(t, requeueAfter > 0 && requeueAfter <= 2*time.Second, "unexpected requeue %s", requeueAfter)
+}
@@ -0,0 +1,178 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DruidIngestionControllerRulesDrift = "DruidIngestionControllerRulesDrift"
+
+	rulesInSyncReason       = "InSync"
+	rulesDriftedReason      = "Drifted"
+	rulesUpdateFailedReason = "UpdateFailed"
+)
+
+// getClusterRulesJson returns the rules of the dataSource on the coordinator.
+func getClusterRulesJson(dataSource, svcName string, auth internalhttp.Auth) (string, error) {
+	getHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	resp, err := getHttp.Do(
+		http.MethodGet,
+		druidapi.MakePath(svcName, "coordinator", "rules", dataSource),
+		nil,
+	)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("failed to get rules, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+	return resp.ResponseBody, nil
+}
+
+// rulesInSync reads the rules back from the coordinator and checks that they include the rules of the spec,
+// in the same order.
+func rulesInSync(di *v1alpha1.DruidIngestion, svcName string, auth internalhttp.Auth) (bool, error) {
+	dataSource, err := getDataSource(di)
+	if err != nil {
+		return false, err
+	}
+
+	currentRulesJson, err := getClusterRulesJson(dataSource, svcName, auth)
+	if err != nil {
+		return false, err
+	}
+
+	desiredRulesJson, err := getRulesJson(di)
+	if err != nil {
+		return false, err
+	}
+
+	return util.IncludesJsonList(currentRulesJson, desiredRulesJson)
+}
+
+// SyncRules sets the rules of the spec when they changed, or when they drifted on the coordinator, and reports
+// whether they are in sync in the RulesInSync condition. With the ObserveOnly drift policy a drift is only reported.
+func (r *DruidIngestionReconciler) SyncRules(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	if len(di.Spec.Ingestion.Rules) == 0 {
+		return nil
+	}
+
+	// a change of the spec is always applied, a drift on the coordinator only with the Reapply policy
+	specChanged := !reflect.DeepEqual(di.Status.CurrentRules, di.Spec.Ingestion.Rules)
+	if !specChanged {
+		inSync, err := rulesInSync(di, svcName, auth)
+		if err != nil {
+			return err
+		}
+		if inSync {
+			return r.setRulesInSync(di, true, rulesInSyncReason, "rules match the coordinator")
+		}
+
+		msg := "rules on the coordinator drifted from the spec"
+		if di.Spec.Ingestion.RulesDriftPolicy == v1alpha1.RulesDriftObserveOnly {
+			if !isRulesDrifted(di) {
+				build.Recorder.GenericEvent(di, v1.EventTypeWarning, msg, DruidIngestionControllerRulesDrift)
+			}
+			return r.setRulesInSync(di, false, rulesDriftedReason, msg)
+		}
+		build.Recorder.GenericEvent(di, v1.EventTypeWarning, msg+", reapplying", DruidIngestionControllerRulesDrift)
+	}
+
+	rulesOk, err := r.UpdateRules(di, svcName, auth)
+	if err != nil {
+		if condErr := r.setRulesInSync(di, false, rulesUpdateFailedReason, err.Error()); condErr != nil {
+			return condErr
+		}
+		return err
+	}
+
+	if rulesOk {
+		// patch status to store the current valid rules json
+		_, err := r.makePatchDruidIngestionStatus(
+			di,
+			di.Status.TaskId,
+			DruidIngestionControllerUpdateSuccess,
+			"rules updated",
+			v1.ConditionTrue,
+			DruidIngestionControllerUpdateSuccess,
+		)
+		if err != nil {
+			return err
+		}
+		build.Recorder.GenericEvent(
+			di,
+			v1.EventTypeNormal,
+			"rules updated",
+			DruidIngestionControllerUpdateSuccess,
+		)
+	}
+
+	return r.setRulesInSync(di, true, rulesInSyncReason, "rules updated")
+}
+
+func isRulesDrifted(di *v1alpha1.DruidIngestion) bool {
+	condition := meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionRulesInSync)
+	return condition != nil && condition.Reason == rulesDriftedReason
+}
+
+// setRulesInSync sets the RulesInSync condition, the status is only patched when the condition changes.
+func (r *DruidIngestionReconciler) setRulesInSync(di *v1alpha1.DruidIngestion, inSync bool, reason, msg string) error {
+	condition := meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionRulesInSync)
+	if condition != nil && condition.Status == conditionStatus(inSync) && condition.Reason == reason &&
+		condition.ObservedGeneration == di.Generation {
+		return nil
+	}
+
+	_, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		meta.SetStatusCondition(&in.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.DruidIngestionConditionRulesInSync,
+			Status:             conditionStatus(inSync),
+			Reason:             reason,
+			Message:            msg,
+			ObservedGeneration: in.Generation,
+		})
+		return in
+	})
+	return err
+}
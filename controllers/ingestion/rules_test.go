@@ -0,0 +1,165 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSyncRules(t *testing.T) {
+	rules := []runtime.RawExtension{
+		{Raw: []byte(`{"type": "loadByPeriod", "period": "P7D", "tieredReplicants": {"_default_tier": 2}}`)},
+		{Raw: []byte(`{"type": "dropForever"}`)},
+	}
+
+	tests := []struct {
+		name            string
+		policy          v1alpha1.RulesDriftPolicy
+		currentRules    []runtime.RawExtension
+		clusterRules    string
+		expectedPosts   int
+		expectedInSync  bool
+		expectedReason  string
+		expectedCurrent int
+	}{
+		{
+			name:            "in sync with defaults added by the coordinator",
+			currentRules:    rules,
+			clusterRules:    `[{"type": "loadByPeriod", "period": "P7D", "includeFuture": true, "tieredReplicants": {"_default_tier": 2}}, {"type": "dropForever"}]`,
+			expectedInSync:  true,
+			expectedReason:  rulesInSyncReason,
+			expectedCurrent: 2,
+		},
+		{
+			name:            "drift is reapplied",
+			currentRules:    rules,
+			clusterRules:    `[{"type": "loadForever", "tieredReplicants": {"_default_tier": 1}}]`,
+			expectedPosts:   1,
+			expectedInSync:  true,
+			expectedReason:  rulesInSyncReason,
+			expectedCurrent: 2,
+		},
+		{
+			name:            "drift is observed only",
+			policy:          v1alpha1.RulesDriftObserveOnly,
+			currentRules:    rules,
+			clusterRules:    `[{"type": "loadForever", "tieredReplicants": {"_default_tier": 1}}]`,
+			expectedInSync:  false,
+			expectedReason:  rulesDriftedReason,
+			expectedCurrent: 2,
+		},
+		{
+			name:            "changed spec is applied while observing only",
+			policy:          v1alpha1.RulesDriftObserveOnly,
+			currentRules:    rules[:1],
+			clusterRules:    `[]`,
+			expectedPosts:   1,
+			expectedInSync:  true,
+			expectedReason:  rulesInSyncReason,
+			expectedCurrent: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts int
+
+			// Stand-in for the coordinator rules API
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/druid/coordinator/v1/rules/wikipedia", r.URL.Path)
+				if r.Method == http.MethodPost {
+					posts++
+					body, _ := io.ReadAll(r.Body)
+					assert.JSONEq(t, `[{"type": "loadByPeriod", "period": "P7D", "tieredReplicants": {"_default_tier": 2}}, {"type": "dropForever"}]`, string(body))
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				_, _ = w.Write([]byte(tt.clusterRules))
+			}))
+			defer server.Close()
+
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"},
+				Spec: v1alpha1.DruidIngestionSpec{
+					Ingestion: v1alpha1.IngestionSpec{
+						Type:             v1alpha1.NativeBatchIndexParallel,
+						Spec:             `{"type": "index_parallel", "spec": {"dataSchema": {"dataSource": "wikipedia"}}}`,
+						Rules:            rules,
+						RulesDriftPolicy: tt.policy,
+					},
+				},
+				Status: v1alpha1.DruidIngestionStatus{
+					TaskId:       "index_parallel_1",
+					CurrentRules: tt.currentRules,
+				},
+			}
+
+			r := newTestReconciler(t, di)
+			build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+			assert.NoError(t, r.SyncRules(di, server.URL, build, internalhttp.Auth{}))
+			assert.Equal(t, tt.expectedPosts, posts)
+
+			assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+			condition := meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionRulesInSync)
+			assert.NotNil(t, condition)
+			assert.Equal(t, conditionStatus(tt.expectedInSync), condition.Status)
+			assert.Equal(t, tt.expectedReason, condition.Reason)
+			assert.Len(t, di.Status.CurrentRules, tt.expectedCurrent)
+		})
+	}
+}
+
+func TestSyncRulesUpdateFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Ingestion: v1alpha1.IngestionSpec{
+				Type:  v1alpha1.NativeBatchIndexParallel,
+				Spec:  `{"type": "index_parallel", "spec": {"dataSchema": {"dataSource": "wikipedia"}}}`,
+				Rules: []runtime.RawExtension{{Raw: []byte(`{"type": "dropForever"}`)}},
+			},
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+	assert.Error(t, r.SyncRules(di, server.URL, build, internalhttp.Auth{}))
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.True(t, meta.IsStatusConditionFalse(di.Status.Conditions, v1alpha1.DruidIngestionConditionRulesInSync))
+}
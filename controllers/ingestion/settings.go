@@ -0,0 +1,148 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RemoveCompactionAndRules records the dataSource the compaction config and rules of the spec are set on. Once
+// they are removed from the spec, or the DruidIngestion is deleted, the compaction config is deleted and the
+// rules are reset to the cluster default rules. A failure on deletion is only reported in an event.
+func (r *DruidIngestionReconciler) RemoveCompactionAndRules(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	deleting := !di.DeletionTimestamp.IsZero()
+	compactionDataSource := di.Status.CompactionDataSource
+	rulesDataSource := di.Status.RulesDataSource
+
+	var dataSource string
+	if !deleting {
+		var err error
+		if dataSource, err = getDataSource(di); err != nil {
+			return err
+		}
+	}
+
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	if !deleting && di.Spec.Ingestion.Compaction.Size() > 0 {
+		compactionDataSource = dataSource
+	} else if compactionDataSource != "" {
+		resp, err := deleteCompaction(httpClient, svcName, compactionDataSource)
+		if err != nil {
+			return err
+		}
+		if !isCleanedUp(resp) {
+			// a failure is retried on the next reconcile, it does not block the deletion
+			if err := r.recordRemoveFailure(di, build, resp, "failed to delete compaction"); err != nil && !deleting {
+				return err
+			}
+		} else {
+			build.Recorder.GenericEvent(
+				di,
+				v1.EventTypeNormal,
+				fmt.Sprintf("compaction of dataSource [%s] deleted", compactionDataSource),
+				DruidIngestionControllerUpdateSuccess,
+			)
+			compactionDataSource = ""
+		}
+	}
+
+	if !deleting && len(di.Spec.Ingestion.Rules) > 0 {
+		rulesDataSource = dataSource
+	} else if rulesDataSource != "" {
+		resp, err := resetRules(httpClient, svcName, rulesDataSource)
+		if err != nil {
+			return err
+		}
+		if !isCleanedUp(resp) {
+			// a failure is retried on the next reconcile, it does not block the deletion
+			if err := r.recordRemoveFailure(di, build, resp, "failed to reset rules"); err != nil && !deleting {
+				return err
+			}
+		} else {
+			build.Recorder.GenericEvent(
+				di,
+				v1.EventTypeNormal,
+				fmt.Sprintf("rules of dataSource [%s] reset to the cluster default rules", rulesDataSource),
+				DruidIngestionControllerUpdateSuccess,
+			)
+			rulesDataSource = ""
+		}
+	}
+
+	if compactionDataSource == di.Status.CompactionDataSource && rulesDataSource == di.Status.RulesDataSource {
+		return nil
+	}
+
+	_, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.CompactionDataSource = compactionDataSource
+		in.Status.RulesDataSource = rulesDataSource
+		if rulesDataSource == "" {
+			in.Status.CurrentRules = nil
+			meta.RemoveStatusCondition(&in.Status.Conditions, v1alpha1.DruidIngestionConditionRulesInSync)
+		}
+		return in
+	})
+	return err
+}
+
+// deleteCompaction deletes the compaction config of the dataSource.
+func deleteCompaction(httpClient internalhttp.DruidHTTP, svcName, dataSource string) (*internalhttp.Response, error) {
+	return httpClient.Do(http.MethodDelete, druidapi.MakePath(svcName, "coordinator", "config", "compaction", dataSource), nil)
+}
+
+// resetRules resets the rules of the dataSource, an empty list of rules falls back to the cluster default rules.
+func resetRules(httpClient internalhttp.DruidHTTP, svcName, dataSource string) (*internalhttp.Response, error) {
+	return httpClient.Do(http.MethodPost, druidapi.MakePath(svcName, "coordinator", "rules", dataSource), []byte("[]"))
+}
+
+func (r *DruidIngestionReconciler) recordRemoveFailure(
+	di *v1alpha1.DruidIngestion,
+	build builder.Builder,
+	resp *internalhttp.Response,
+	msg string,
+) error {
+	build.Recorder.GenericEvent(
+		di,
+		v1.EventTypeWarning,
+		fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode),
+		DruidIngestionControllerUpdateFail,
+	)
+	return fmt.Errorf("%s, status code: %d, response body: %s", msg, resp.StatusCode, resp.ResponseBody)
+}
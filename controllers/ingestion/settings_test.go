@@ -0,0 +1,163 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRemoveCompactionAndRules(t *testing.T) {
+	compaction := runtime.RawExtension{Raw: []byte(`{"skipOffsetFromLatest": "PT1H"}`)}
+	rules := []runtime.RawExtension{{Raw: []byte(`{"type": "dropForever"}`)}}
+
+	tests := []struct {
+		name                         string
+		compaction                   runtime.RawExtension
+		rules                        []runtime.RawExtension
+		compactionDataSource         string
+		rulesDataSource              string
+		deleting                     bool
+		statusCode                   int
+		expectErr                    bool
+		expectedCalls                []string
+		expectedCompactionDataSource string
+		expectedRulesDataSource      string
+	}{
+		{
+			name:                         "set on the dataSource",
+			compaction:                   compaction,
+			rules:                        rules,
+			expectedCompactionDataSource: "wikipedia",
+			expectedRulesDataSource:      "wikipedia",
+		},
+		{
+			name:                 "removed from the spec",
+			compactionDataSource: "wikipedia",
+			rulesDataSource:      "wikipedia",
+			expectedCalls: []string{
+				"DELETE /druid/coordinator/v1/config/compaction/wikipedia",
+				"POST /druid/coordinator/v1/rules/wikipedia []",
+			},
+		},
+		{
+			name:                    "compaction removed from the spec",
+			rules:                   rules,
+			compactionDataSource:    "wikipedia",
+			rulesDataSource:         "wikipedia",
+			expectedCalls:           []string{"DELETE /druid/coordinator/v1/config/compaction/wikipedia"},
+			expectedRulesDataSource: "wikipedia",
+		},
+		{
+			name:                 "deleting",
+			compaction:           compaction,
+			rules:                rules,
+			compactionDataSource: "wikipedia",
+			rulesDataSource:      "wikipedia",
+			deleting:             true,
+			expectedCalls: []string{
+				"DELETE /druid/coordinator/v1/config/compaction/wikipedia",
+				"POST /druid/coordinator/v1/rules/wikipedia []",
+			},
+		},
+		{
+			name:                         "failure is retried",
+			compactionDataSource:         "wikipedia",
+			statusCode:                   http.StatusInternalServerError,
+			expectErr:                    true,
+			expectedCalls:                []string{"DELETE /druid/coordinator/v1/config/compaction/wikipedia"},
+			expectedCompactionDataSource: "wikipedia",
+		},
+		{
+			name:                         "failure does not block the deletion",
+			compactionDataSource:         "wikipedia",
+			deleting:                     true,
+			statusCode:                   http.StatusInternalServerError,
+			expectedCalls:                []string{"DELETE /druid/coordinator/v1/config/compaction/wikipedia"},
+			expectedCompactionDataSource: "wikipedia",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+
+			// Stand-in for the coordinator API
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := r.Method + " " + r.URL.Path
+				if body, _ := io.ReadAll(r.Body); len(body) > 0 {
+					call += " " + string(body)
+				}
+				calls = append(calls, call)
+				if tt.statusCode != 0 {
+					w.WriteHeader(tt.statusCode)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"},
+				Spec: v1alpha1.DruidIngestionSpec{
+					Ingestion: v1alpha1.IngestionSpec{
+						Type:       v1alpha1.NativeBatchIndexParallel,
+						Spec:       `{"type": "index_parallel", "spec": {"dataSchema": {"dataSource": "wikipedia"}}}`,
+						Compaction: tt.compaction,
+						Rules:      tt.rules,
+					},
+				},
+				Status: v1alpha1.DruidIngestionStatus{
+					TaskId:               "index_parallel_1",
+					CompactionDataSource: tt.compactionDataSource,
+					RulesDataSource:      tt.rulesDataSource,
+				},
+			}
+			if tt.deleting {
+				di.Finalizers = []string{DruidIngestionControllerFinalizer}
+				di.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+			}
+
+			r := newTestReconciler(t, di)
+			build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+
+			err := r.RemoveCompactionAndRules(di, server.URL, build, internalhttp.Auth{})
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+			assert.Equal(t, tt.expectedCompactionDataSource, di.Status.CompactionDataSource)
+			assert.Equal(t, tt.expectedRulesDataSource, di.Status.RulesDataSource)
+		})
+	}
+}
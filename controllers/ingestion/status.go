@@ -0,0 +1,279 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Task statuses reported by the overlord task status API.
+const (
+	taskStatusRunning = "RUNNING"
+	taskStatusSuccess = "SUCCESS"
+	taskStatusFailed  = "FAILED"
+)
+
+// taskStatusHolder holds the response of the task status API, statusCode is returned by older Druid versions.
+type taskStatusHolder struct {
+	Status struct {
+		Status     string `json:"status"`
+		StatusCode string `json:"statusCode"`
+		ErrorMsg   string `json:"errorMsg"`
+	} `json:"status"`
+}
+
+// supervisorStatusHolder holds the response of the supervisor status API.
+type supervisorStatusHolder struct {
+	Payload struct {
+		State         string `json:"state"`
+		DetailedState string `json:"detailedState"`
+		Healthy       bool   `json:"healthy"`
+		Suspended     bool   `json:"suspended"`
+		AggregateLag  *int64 `json:"aggregateLag"`
+		RecentErrors  []struct {
+			ExceptionClass string `json:"exceptionClass"`
+			Message        string `json:"message"`
+		} `json:"recentErrors"`
+	} `json:"payload"`
+}
+
+// isSupervisor returns true for the streaming ingestion types, which run as supervisors instead of tasks.
+func isSupervisor(ingestionType v1alpha1.DruidIngestionMethod) bool {
+	return ingestionType == v1alpha1.Kafka || ingestionType == v1alpha1.Kinesis
+}
+
+func isTaskComplete(taskStatus string) bool {
+	return taskStatus == taskStatusSuccess || taskStatus == taskStatusFailed
+}
+
+// UpdateTaskStatus polls the status of the task recorded in the status of the DruidIngestion
+// and records it as conditions, along with an event once the task completes.
+func (r *DruidIngestionReconciler) UpdateTaskStatus(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	if di.Status.TaskId == "" {
+		return nil
+	}
+
+	body, ok, err := r.getStatus(di, druidapi.MakePath(svcName, "indexer", "task", di.Status.TaskId, "status"), build, auth)
+	if err != nil || !ok {
+		return err
+	}
+
+	var task taskStatusHolder
+	if err := json.Unmarshal([]byte(body), &task); err != nil {
+		return err
+	}
+
+	taskStatus := task.Status.Status
+	if taskStatus == "" {
+		taskStatus = task.Status.StatusCode
+	}
+	if taskStatus == di.Status.TaskStatus {
+		return nil
+	}
+
+	msg := fmt.Sprintf("task [%s] is %s", di.Status.TaskId, taskStatus)
+	if task.Status.ErrorMsg != "" {
+		msg = fmt.Sprintf("%s: %s", msg, task.Status.ErrorMsg)
+	}
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.TaskStatus = taskStatus
+		in.Status.Message = msg
+		in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		setTaskConditions(in, taskStatus, msg)
+		return in
+	}); err != nil {
+		return err
+	}
+
+	switch taskStatus {
+	case taskStatusSuccess:
+		build.Recorder.GenericEvent(di, v1.EventTypeNormal, msg, DruidIngestionControllerTaskSuccess)
+	case taskStatusFailed:
+		build.Recorder.GenericEvent(di, v1.EventTypeWarning, msg, DruidIngestionControllerTaskFail)
+	}
+
+	return nil
+}
+
+// UpdateSupervisorStatus polls the status of the supervisor recorded in the status of the DruidIngestion
+// and records its state, lag and recent errors, along with an event when its health changes.
+func (r *DruidIngestionReconciler) UpdateSupervisorStatus(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	if di.Status.TaskId == "" {
+		return nil
+	}
+
+	body, ok, err := r.getStatus(di, druidapi.MakePath(svcName, "indexer", "supervisor", di.Status.TaskId, "status"), build, auth)
+	if err != nil || !ok {
+		return err
+	}
+
+	var supervisor supervisorStatusHolder
+	if err := json.Unmarshal([]byte(body), &supervisor); err != nil {
+		return err
+	}
+	payload := supervisor.Payload
+
+	state := payload.State
+	if state == "" {
+		state = "Unknown"
+	}
+	detailedState := payload.DetailedState
+	if detailedState == "" {
+		detailedState = state
+	}
+
+	runningMsg := fmt.Sprintf("supervisor [%s] is %s", di.Status.TaskId, detailedState)
+	if payload.AggregateLag != nil {
+		runningMsg = fmt.Sprintf("%s, aggregate lag [%d]", runningMsg, *payload.AggregateLag)
+	}
+
+	healthyMsg := fmt.Sprintf("supervisor [%s] is healthy", di.Status.TaskId)
+	if !payload.Healthy {
+		healthyMsg = fmt.Sprintf("supervisor [%s] is unhealthy", di.Status.TaskId)
+		if n := len(payload.RecentErrors); n > 0 {
+			recentError := payload.RecentErrors[n-1]
+			healthyMsg = fmt.Sprintf("%s: %s %s", healthyMsg, recentError.ExceptionClass, recentError.Message)
+		}
+	}
+
+	wasHealthy := meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionHealthy)
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		if in.Status.TaskStatus != state {
+			in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		}
+		in.Status.TaskStatus = state
+		in.Status.AggregateLag = payload.AggregateLag
+		meta.SetStatusCondition(&in.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.DruidIngestionConditionRunning,
+			Status:             conditionStatus(state == "RUNNING" || state == "IDLE"),
+			Reason:             state,
+			Message:            runningMsg,
+			ObservedGeneration: in.Generation,
+		})
+		meta.SetStatusCondition(&in.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.DruidIngestionConditionHealthy,
+			Status:             conditionStatus(payload.Healthy),
+			Reason:             detailedState,
+			Message:            healthyMsg,
+			ObservedGeneration: in.Generation,
+		})
+		return in
+	}); err != nil {
+		return err
+	}
+
+	if wasHealthy == nil || (wasHealthy.Status == metav1.ConditionTrue) != payload.Healthy {
+		if payload.Healthy {
+			build.Recorder.GenericEvent(di, v1.EventTypeNormal, healthyMsg, DruidIngestionControllerSupervisorHealthy)
+		} else {
+			build.Recorder.GenericEvent(di, v1.EventTypeWarning, healthyMsg, DruidIngestionControllerSupervisorUnhealthy)
+		}
+	}
+
+	return nil
+}
+
+// getStatus GETs a status API, returning false along with a warning event when it does not answer 200.
+func (r *DruidIngestionReconciler) getStatus(
+	di *v1alpha1.DruidIngestion,
+	path string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) (string, bool, error) {
+	getHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	resp, err := getHttp.Do(http.MethodGet, path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	if resp.StatusCode != 200 {
+		build.Recorder.GenericEvent(
+			di,
+			v1.EventTypeWarning,
+			fmt.Sprintf("Resp [%s], StatusCode [%d]", string(resp.ResponseBody), resp.StatusCode),
+			DruidIngestionControllerGetFail,
+		)
+		return "", false, nil
+	}
+
+	return resp.ResponseBody, true, nil
+}
+
+// setTaskConditions maps the status of a task onto the Running, Succeeded and Failed conditions.
+func setTaskConditions(di *v1alpha1.DruidIngestion, taskStatus, msg string) {
+	meta.SetStatusCondition(&di.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.DruidIngestionConditionRunning,
+		Status:             conditionStatus(taskStatus == taskStatusRunning),
+		Reason:             taskStatus,
+		Message:            msg,
+		ObservedGeneration: di.Generation,
+	})
+	meta.SetStatusCondition(&di.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.DruidIngestionConditionSucceeded,
+		Status:             conditionStatus(taskStatus == taskStatusSuccess),
+		Reason:             taskStatus,
+		Message:            msg,
+		ObservedGeneration: di.Generation,
+	})
+	meta.SetStatusCondition(&di.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.DruidIngestionConditionFailed,
+		Status:             conditionStatus(taskStatus == taskStatusFailed),
+		Reason:             taskStatus,
+		Message:            msg,
+		ObservedGeneration: di.Generation,
+	})
+}
+
+func conditionStatus(b bool) metav1.ConditionStatus {
+	if b {
+		return metav1.ConditionTrue
+	}
+	return metav1.ConditionFalse
+}
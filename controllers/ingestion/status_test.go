@@ -0,0 +1,177 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestUpdateTaskStatus(t *testing.T) {
+	tests := []struct {
+		name              string
+		response          string
+		expectedStatus    string
+		expectedRunning   metav1.ConditionStatus
+		expectedSucceeded metav1.ConditionStatus
+		expectedFailed    metav1.ConditionStatus
+	}{
+		{
+			name:              "running",
+			response:          `{"task": "index_parallel_1", "status": {"status": "RUNNING"}}`,
+			expectedStatus:    "RUNNING",
+			expectedRunning:   metav1.ConditionTrue,
+			expectedSucceeded: metav1.ConditionFalse,
+			expectedFailed:    metav1.ConditionFalse,
+		},
+		{
+			name:              "succeeded",
+			response:          `{"task": "index_parallel_1", "status": {"status": "SUCCESS"}}`,
+			expectedStatus:    "SUCCESS",
+			expectedRunning:   metav1.ConditionFalse,
+			expectedSucceeded: metav1.ConditionTrue,
+			expectedFailed:    metav1.ConditionFalse,
+		},
+		{
+			name:              "failed, reported by an older Druid",
+			response:          `{"task": "index_parallel_1", "status": {"statusCode": "FAILED", "errorMsg": "no input"}}`,
+			expectedStatus:    "FAILED",
+			expectedRunning:   metav1.ConditionFalse,
+			expectedSucceeded: metav1.ConditionFalse,
+			expectedFailed:    metav1.ConditionTrue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/druid/indexer/v1/task/index_parallel_1/status", r.URL.Path)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"},
+				Spec: v1alpha1.DruidIngestionSpec{
+					Ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.NativeBatchIndexParallel},
+				},
+				Status: v1alpha1.DruidIngestionStatus{TaskId: "index_parallel_1"},
+			}
+			r := newTestReconciler(t, di)
+
+			assert.NoError(t, r.UpdateTaskStatus(di, server.URL, testutil.NewBuilder(r.Recorder, "DruidIngestionController"), internalhttp.Auth{}))
+			assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+
+			assert.Equal(t, tt.expectedStatus, di.Status.TaskStatus)
+			assert.Equal(t, tt.expectedRunning, meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionRunning).Status)
+			assert.Equal(t, tt.expectedSucceeded, meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionSucceeded).Status)
+			assert.Equal(t, tt.expectedFailed, meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionFailed).Status)
+		})
+	}
+}
+
+func TestUpdateSupervisorStatus(t *testing.T) {
+	tests := []struct {
+		name            string
+		response        string
+		expectedState   string
+		expectedLag     *int64
+		expectedRunning metav1.ConditionStatus
+		expectedHealthy metav1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "running",
+			response:        `{"id": "metrics-kafka", "payload": {"state": "RUNNING", "detailedState": "RUNNING", "healthy": true, "aggregateLag": 42}}`,
+			expectedState:   "RUNNING",
+			expectedLag:     int64Ptr(42),
+			expectedRunning: metav1.ConditionTrue,
+			expectedHealthy: metav1.ConditionTrue,
+			expectedReason:  "RUNNING",
+			expectedMessage: "healthy",
+		},
+		{
+			name: "lost contact with stream",
+			response: `{"id": "metrics-kafka", "payload": {"state": "UNHEALTHY_SUPERVISOR", "detailedState": "LOST_CONTACT_WITH_STREAM", "healthy": false,
+				"recentErrors": [{"exceptionClass": "org.apache.kafka.common.errors.TimeoutException", "message": "Timeout expired while fetching topic metadata"}]}}`,
+			expectedState:   "UNHEALTHY_SUPERVISOR",
+			expectedRunning: metav1.ConditionFalse,
+			expectedHealthy: metav1.ConditionFalse,
+			expectedReason:  "LOST_CONTACT_WITH_STREAM",
+			expectedMessage: "Timeout expired while fetching topic metadata",
+		},
+		{
+			name:            "unhealthy tasks",
+			response:        `{"id": "metrics-kafka", "payload": {"state": "UNHEALTHY_TASKS", "healthy": false}}`,
+			expectedState:   "UNHEALTHY_TASKS",
+			expectedRunning: metav1.ConditionFalse,
+			expectedHealthy: metav1.ConditionFalse,
+			expectedReason:  "UNHEALTHY_TASKS",
+			expectedMessage: "unhealthy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/druid/indexer/v1/supervisor/metrics-kafka/status", r.URL.Path)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			di := &v1alpha1.DruidIngestion{
+				ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+				Spec: v1alpha1.DruidIngestionSpec{
+					Ingestion: v1alpha1.IngestionSpec{Type: v1alpha1.Kafka},
+				},
+				Status: v1alpha1.DruidIngestionStatus{TaskId: "metrics-kafka"},
+			}
+			r := newTestReconciler(t, di)
+
+			assert.NoError(t, r.UpdateSupervisorStatus(di, server.URL, testutil.NewBuilder(r.Recorder, "DruidIngestionController"), internalhttp.Auth{}))
+			assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+
+			assert.Equal(t, tt.expectedState, di.Status.TaskStatus)
+			assert.Equal(t, tt.expectedLag, di.Status.AggregateLag)
+			assert.Equal(t, tt.expectedRunning, meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionRunning).Status)
+
+			healthy := meta.FindStatusCondition(di.Status.Conditions, v1alpha1.DruidIngestionConditionHealthy)
+			assert.Equal(t, tt.expectedHealthy, healthy.Status)
+			assert.Equal(t, tt.expectedReason, healthy.Reason)
+			assert.Contains(t, healthy.Message, tt.expectedMessage)
+		})
+	}
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
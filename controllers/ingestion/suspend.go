@@ -0,0 +1,168 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	DruidIngestionControllerSuspendSuccess = "DruidIngestionControllerSuspendSuccess"
+	DruidIngestionControllerSuspendFail    = "DruidIngestionControllerSuspendFail"
+	DruidIngestionControllerResumeSuccess  = "DruidIngestionControllerResumeSuccess"
+	DruidIngestionControllerResumeFail     = "DruidIngestionControllerResumeFail"
+)
+
+// getSupervisorActionPath returns the path of an action on a supervisor, such as suspend or resume.
+func getSupervisorActionPath(svcName, supervisorId, action string) string {
+	return druidapi.MakePath(svcName, "indexer", "supervisor", supervisorId, action)
+}
+
+// UpdateSuspend applies spec.suspend. A supervisor is suspended and resumed through the supervisor API,
+// a batch task that is still running is cancelled and resubmitted once resumed. It returns true while
+// the ingestion is suspended, in which case nothing must be submitted.
+func (r *DruidIngestionReconciler) UpdateSuspend(
+	di *v1alpha1.DruidIngestion,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) (bool, error) {
+	if di.Spec.Suspend == di.Status.Suspended {
+		return di.Spec.Suspend, nil
+	}
+
+	postHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	if di.Spec.Suspend {
+		msg := "ingestion suspended"
+		cancelled := false
+
+		if di.Status.TaskId != "" {
+			var path string
+			if isSupervisor(di.Spec.Ingestion.Type) {
+				path = getSupervisorActionPath(svcName, di.Status.TaskId, "suspend")
+				msg = fmt.Sprintf("supervisor [%s] suspended", di.Status.TaskId)
+			} else if !isTaskComplete(di.Status.TaskStatus) {
+				path = getPath(di.Spec.Ingestion.Type, svcName, http.MethodPost, di.Status.TaskId, true)
+				msg = fmt.Sprintf("task [%s] cancelled", di.Status.TaskId)
+				cancelled = true
+			}
+
+			if path != "" {
+				resp, err := postHttp.Do(http.MethodPost, path, []byte{})
+				if err != nil {
+					return true, err
+				}
+				if resp.StatusCode != 200 {
+					build.Recorder.GenericEvent(
+						di,
+						v1.EventTypeWarning,
+						fmt.Sprintf("Resp [%s], StatusCode [%d]", string(resp.ResponseBody), resp.StatusCode),
+						DruidIngestionControllerSuspendFail,
+					)
+					return true, fmt.Errorf("failed to suspend ingestion, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+				}
+			}
+		}
+
+		if err := r.patchSuspended(di, true, msg, func(in *v1alpha1.DruidIngestion) {
+			if cancelled {
+				// forget the cancelled task, so that it is submitted again once resumed
+				in.Status.TaskId = ""
+				in.Status.TaskStatus = ""
+				in.Status.CurrentIngestionSpec = ""
+			}
+		}); err != nil {
+			return true, err
+		}
+		build.Recorder.GenericEvent(di, v1.EventTypeNormal, msg, DruidIngestionControllerSuspendSuccess)
+		return true, nil
+	}
+
+	msg := "ingestion resumed"
+	if isSupervisor(di.Spec.Ingestion.Type) && di.Status.TaskId != "" {
+		resp, err := postHttp.Do(http.MethodPost, getSupervisorActionPath(svcName, di.Status.TaskId, "resume"), []byte{})
+		if err != nil {
+			return true, err
+		}
+		if resp.StatusCode != 200 {
+			build.Recorder.GenericEvent(
+				di,
+				v1.EventTypeWarning,
+				fmt.Sprintf("Resp [%s], StatusCode [%d]", string(resp.ResponseBody), resp.StatusCode),
+				DruidIngestionControllerResumeFail,
+			)
+			return true, fmt.Errorf("failed to resume ingestion, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+		}
+		msg = fmt.Sprintf("supervisor [%s] resumed", di.Status.TaskId)
+	}
+
+	if err := r.patchSuspended(di, false, msg, nil); err != nil {
+		return true, err
+	}
+	build.Recorder.GenericEvent(di, v1.EventTypeNormal, msg, DruidIngestionControllerResumeSuccess)
+	return false, nil
+}
+
+func (r *DruidIngestionReconciler) patchSuspended(
+	di *v1alpha1.DruidIngestion,
+	suspended bool,
+	msg string,
+	transform func(in *v1alpha1.DruidIngestion),
+) error {
+	reason := "Resumed"
+	if suspended {
+		reason = "Suspended"
+	}
+
+	_, _, err := util.PatchStatus(context.Background(), r.Client, di, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidIngestion)
+		in.Status.Suspended = suspended
+		in.Status.Message = msg
+		in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		meta.SetStatusCondition(&in.Status.Conditions, metav1.Condition{
+			Type:               v1alpha1.DruidIngestionConditionSuspended,
+			Status:             conditionStatus(suspended),
+			Reason:             reason,
+			Message:            msg,
+			ObservedGeneration: in.Generation,
+		})
+		if transform != nil {
+			transform(in)
+		}
+		return in
+	})
+	return err
+}
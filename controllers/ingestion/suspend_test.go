@@ -0,0 +1,139 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package ingestion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSuspendSupervisor(t *testing.T) {
+	var calls []string
+
+	// Stand-in for the overlord supervisor API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id": "metrics-kafka"}`))
+	}))
+	defer server.Close()
+
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Suspend: true,
+			Ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.Kafka,
+				Spec: `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics-kafka"}}}`,
+			},
+		},
+		Status: v1alpha1.DruidIngestionStatus{
+			TaskId:               "metrics-kafka",
+			CurrentIngestionSpec: `{"type": "kafka", "spec": {"dataSchema": {"dataSource": "metrics-kafka"}}}`,
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+	auth := internalhttp.Auth{}
+
+	// suspend
+	_, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"POST /druid/indexer/v1/supervisor/metrics-kafka/suspend"}, calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.True(t, di.Status.Suspended)
+	assert.True(t, meta.IsStatusConditionTrue(di.Status.Conditions, v1alpha1.DruidIngestionConditionSuspended))
+
+	// stays suspended without calling the supervisor API
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Len(t, calls, 1)
+
+	// resume
+	calls = nil
+	di.Spec.Suspend = false
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, "POST /druid/indexer/v1/supervisor/metrics-kafka/resume", calls[0])
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.False(t, di.Status.Suspended)
+	assert.False(t, meta.IsStatusConditionTrue(di.Status.Conditions, v1alpha1.DruidIngestionConditionSuspended))
+}
+
+func TestSuspendBatchTask(t *testing.T) {
+	var calls []string
+
+	// Stand-in for the overlord task API
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"task": "index_parallel_2"}`))
+	}))
+	defer server.Close()
+
+	spec := `{"type": "index_parallel", "spec": {"dataSchema": {"dataSource": "wikipedia"}}}`
+	di := &v1alpha1.DruidIngestion{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "default"},
+		Spec: v1alpha1.DruidIngestionSpec{
+			Suspend: true,
+			Ingestion: v1alpha1.IngestionSpec{
+				Type: v1alpha1.NativeBatchIndexParallel,
+				Spec: spec,
+			},
+		},
+		Status: v1alpha1.DruidIngestionStatus{
+			TaskId:               "index_parallel_1",
+			TaskStatus:           "RUNNING",
+			CurrentIngestionSpec: spec,
+		},
+	}
+
+	r := newTestReconciler(t, di)
+	build := testutil.NewBuilder(r.Recorder, "DruidIngestionController")
+	auth := internalhttp.Auth{}
+
+	// the running task is cancelled and held
+	_, err := r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"POST /druid/indexer/v1/task/index_parallel_1/shutdown"}, calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.True(t, di.Status.Suspended)
+	assert.Empty(t, di.Status.TaskId)
+
+	// the task is submitted again once resumed
+	calls = nil
+	di.Spec.Suspend = false
+	_, err = r.CreateOrUpdate(di, server.URL, server.URL, server.URL, build, auth)
+	assert.NoError(t, err)
+	assert.Contains(t, calls, "POST /druid/indexer/v1/task")
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(di), di))
+	assert.False(t, di.Status.Suspended)
+	assert.Equal(t, "index_parallel_2", di.Status.TaskId)
+}
@@ -0,0 +1,68 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package lookup
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+)
+
+// DruidLookupReconciler manages a lookup of a tier and reports its load status on the nodes of the tier.
+type DruidLookupReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// reconcile time duration, defaults to 10s
+	ReconcileWait time.Duration
+	Recorder      record.EventRecorder
+}
+
+func NewDruidLookupReconciler(mgr ctrl.Manager) *DruidLookupReconciler {
+	return &DruidLookupReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Lookup"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("druid-lookup"),
+	}
+}
+
+// +kubebuilder:rbac:groups=druid.apache.org,resources=druidlookups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=druid.apache.org,resources=druidlookups/status,verbs=get;update;patch
+func (r *DruidLookupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	druidLookupCR := &v1alpha1.DruidLookup{}
+	return util.ReconcileObject(ctx, r.Client, req, druidLookupCR, func(ctx context.Context) error {
+		return r.do(ctx, druidLookupCR)
+	})
+}
+
+func (r *DruidLookupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.DruidLookup{}).
+		Complete(r)
+}
@@ -0,0 +1,472 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	DruidLookupControllerUpdateSuccess = "DruidLookupControllerUpdateSuccess"
+	DruidLookupControllerUpdateFail    = "DruidLookupControllerUpdateFail"
+	DruidLookupControllerDeleteSuccess = "DruidLookupControllerDeleteSuccess"
+	DruidLookupControllerDeleteFail    = "DruidLookupControllerDeleteFail"
+	DruidLookupControllerFinalizer     = "druidlookup.datainfra.io/finalizer"
+
+	defaultTier = "__default"
+	// versionLayout formats the generated versions, they sort in time order as the coordinator only
+	// replaces a lookup with a greater version.
+	versionLayout = "2006-01-02T15:04:05.000Z"
+)
+
+func (r *DruidLookupReconciler) do(ctx context.Context, dl *v1alpha1.DruidLookup) error {
+	auth, err := druidapi.GetAuth(
+		ctx,
+		r.Client,
+		dl.Namespace,
+		dl.Spec.Auth,
+	)
+	if err != nil {
+		return err
+	}
+
+	svcName, err := druidapi.GetRouterSvcUrl(dl.Namespace, dl.Spec.DruidClusterName, "", druidapi.CoordinatorAPI, dl.Spec.Auth.TLS != nil, r.Client)
+	if err != nil {
+		return err
+	}
+
+	build := builder.NewBuilder(
+		builder.ToNewBuilderRecorder(builder.BuilderRecorder{Recorder: r.Recorder, ControllerName: "DruidLookupController"}),
+	)
+
+	if dl.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(dl, DruidLookupControllerFinalizer) {
+			controllerutil.AddFinalizer(dl, DruidLookupControllerFinalizer)
+			if err := r.Update(ctx, dl.DeepCopyObject().(*v1alpha1.DruidLookup)); err != nil {
+				return err
+			}
+		}
+		return r.Sync(dl, svcName, *build, auth)
+	}
+
+	if controllerutil.ContainsFinalizer(dl, DruidLookupControllerFinalizer) {
+		// our finalizer is present, so lets delete the lookup from the cluster
+		tier, name := dl.Status.Tier, dl.Status.Name
+		if name == "" {
+			tier, name = getTier(dl), getName(dl)
+		}
+		// the lookups of a tier set in the spec of the Druid are left to it
+		managed, err := r.isManagedByDruid(ctx, dl, tier)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			if err := r.DeleteLookup(dl, tier, name, svcName, *build, auth); err != nil {
+				return err
+			}
+		}
+
+		controllerutil.RemoveFinalizer(dl, DruidLookupControllerFinalizer)
+		if err := r.Update(ctx, dl.DeepCopyObject().(*v1alpha1.DruidLookup)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTier returns the tier of the lookup, __default by default.
+func getTier(dl *v1alpha1.DruidLookup) string {
+	if dl.Spec.Tier != "" {
+		return dl.Spec.Tier
+	}
+	return defaultTier
+}
+
+// getName returns the name of the lookup in Druid, the name of the DruidLookup by default.
+func getName(dl *v1alpha1.DruidLookup) string {
+	if dl.Spec.Name != "" {
+		return dl.Spec.Name
+	}
+	return dl.Name
+}
+
+// getLookupExtractorFactoryJson returns the lookupExtractorFactory of the DruidLookup, a cachedNamespace
+// one when the extractionNamespace is set.
+func getLookupExtractorFactoryJson(dl *v1alpha1.DruidLookup) (string, error) {
+	hasNamespace, hasFactory := dl.Spec.ExtractionNamespace.Size() > 0, dl.Spec.LookupExtractorFactory.Size() > 0
+	if hasNamespace == hasFactory {
+		return "", errors.New("exactly one of extractionNamespace and lookupExtractorFactory must be set")
+	}
+
+	factory := make(map[string]interface{})
+	if hasFactory {
+		if err := json.Unmarshal(dl.Spec.LookupExtractorFactory.Raw, &factory); err != nil {
+			return "", fmt.Errorf("error unmarshalling lookupExtractorFactory: %v", err)
+		}
+	} else {
+		var namespace map[string]interface{}
+		if err := json.Unmarshal(dl.Spec.ExtractionNamespace.Raw, &namespace); err != nil {
+			return "", fmt.Errorf("error unmarshalling extractionNamespace: %v", err)
+		}
+		factory["type"] = "cachedNamespace"
+		factory["extractionNamespace"] = namespace
+	}
+	return util.ToJsonString(factory)
+}
+
+// isManagedByDruid returns true when the tier is set in the lookups of the spec of the Druid, the Druid then owns
+// the lookups of the tier and a DruidLookup of the tier is not applied.
+func (r *DruidLookupReconciler) isManagedByDruid(ctx context.Context, dl *v1alpha1.DruidLookup, tier string) (bool, error) {
+	drd := &v1alpha1.Druid{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: dl.Namespace, Name: dl.Spec.DruidClusterName}, drd); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if drd.Spec.Lookups.Size() == 0 {
+		return false, nil
+	}
+
+	var tiers map[string]json.RawMessage
+	if err := json.Unmarshal(drd.Spec.Lookups.Raw, &tiers); err != nil {
+		return false, fmt.Errorf("error unmarshalling lookups of Druid [%s]: %v", drd.Name, err)
+	}
+	_, ok := tiers[tier]
+	return ok, nil
+}
+
+// getVersion returns the version of the spec, or the version last applied while the lookup is unchanged.
+// A new version is generated otherwise.
+func getVersion(dl *v1alpha1.DruidLookup, factory string) string {
+	if dl.Spec.Version != "" {
+		return dl.Spec.Version
+	}
+	if dl.Status.Version != "" && dl.Status.CurrentLookupExtractorFactory == factory &&
+		dl.Status.Tier == getTier(dl) && dl.Status.Name == getName(dl) {
+		return dl.Status.Version
+	}
+	return time.Now().UTC().Format(versionLayout)
+}
+
+// Sync applies the lookup of the DruidLookup, deletes it from its previous tier or name when they changed,
+// and reports the outcome in the Synced condition.
+func (r *DruidLookupReconciler) Sync(
+	dl *v1alpha1.DruidLookup,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	tier, name := getTier(dl), getName(dl)
+
+	if dl.Status.Name != "" && (dl.Status.Tier != tier || dl.Status.Name != name) {
+		if err := r.DeleteLookup(dl, dl.Status.Tier, dl.Status.Name, svcName, build, auth); err != nil {
+			return err
+		}
+	}
+
+	var (
+		factory, version string
+		updated          bool
+		syncErr          error
+	)
+	if managed, err := r.isManagedByDruid(context.Background(), dl, tier); err != nil {
+		return err
+	} else if managed {
+		syncErr = fmt.Errorf("tier [%s] is managed by the lookups of Druid [%s]", tier, dl.Spec.DruidClusterName)
+	} else {
+		factory, version, updated, syncErr = r.UpdateLookup(dl, svcName, auth)
+	}
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, dl, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidLookup)
+		in.Status.Tier = tier
+		in.Status.Name = name
+		if syncErr == nil {
+			in.Status.Version = version
+			in.Status.CurrentLookupExtractorFactory = factory
+		}
+
+		condition := metav1.Condition{
+			Type:               v1alpha1.DruidLookupConditionSynced,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Synced",
+			Message:            fmt.Sprintf("version [%s] is applied", version),
+			ObservedGeneration: in.Generation,
+		}
+		if syncErr != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SyncFailed"
+			condition.Message = syncErr.Error()
+		}
+		if updated || syncErr != nil {
+			in.Status.Reason = condition.Reason
+			in.Status.Message = condition.Message
+			in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		}
+		meta.SetStatusCondition(&in.Status.Conditions, condition)
+		return in
+	}); err != nil {
+		return err
+	}
+
+	if syncErr != nil {
+		build.Recorder.GenericEvent(dl, v1.EventTypeWarning, syncErr.Error(), DruidLookupControllerUpdateFail)
+		return syncErr
+	}
+	if updated {
+		build.Recorder.GenericEvent(
+			dl,
+			v1.EventTypeNormal,
+			fmt.Sprintf("lookup [%s] of tier [%s] updated to version [%s]", name, tier, version),
+			DruidLookupControllerUpdateSuccess,
+		)
+	}
+
+	return r.UpdateNodeStatus(dl, svcName, auth)
+}
+
+// UpdateLookup sets the lookup on the cluster when its version or lookupExtractorFactory differ. It returns the
+// applied lookupExtractorFactory and version.
+func (r *DruidLookupReconciler) UpdateLookup(
+	dl *v1alpha1.DruidLookup,
+	svcName string,
+	auth internalhttp.Auth,
+) (string, string, bool, error) {
+	factory, err := getLookupExtractorFactoryJson(dl)
+	if err != nil {
+		return "", "", false, err
+	}
+	version := getVersion(dl, factory)
+	path := druidapi.MakePath(svcName, "coordinator", "lookups", "config", getTier(dl), getName(dl))
+
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	// Get the current lookup
+	currentResp, err := httpClient.Do(http.MethodGet, path, nil)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	switch currentResp.StatusCode {
+	case http.StatusOK:
+		var current struct {
+			Version                string          `json:"version"`
+			LookupExtractorFactory json.RawMessage `json:"lookupExtractorFactory"`
+		}
+		if err := json.Unmarshal([]byte(currentResp.ResponseBody), &current); err != nil {
+			return "", "", false, fmt.Errorf("error unmarshalling current lookup: %v", err)
+		}
+		equal, err := util.IncludesJson(string(current.LookupExtractorFactory), factory)
+		if err != nil {
+			return "", "", false, err
+		}
+		// an unchanged lookup keeps the version of the cluster when the version is generated
+		if equal && (current.Version == version || dl.Spec.Version == "") {
+			return factory, current.Version, false, nil
+		}
+	case http.StatusNotFound:
+		if err := initLookups(httpClient, svcName); err != nil {
+			return "", "", false, err
+		}
+	default:
+		return "", "", false, fmt.Errorf(
+			"failed to retrieve current lookup, status code: %d, response body: %s",
+			currentResp.StatusCode, currentResp.ResponseBody)
+	}
+
+	lookup, err := util.ToJsonString(map[string]interface{}{
+		"version":                version,
+		"lookupExtractorFactory": json.RawMessage(factory),
+	})
+	if err != nil {
+		return "", "", false, err
+	}
+
+	respUpdateLookup, err := httpClient.Do(http.MethodPost, path, []byte(lookup))
+	if err != nil {
+		return "", "", false, err
+	}
+	// the lookups API accepts the update asynchronously
+	if respUpdateLookup.StatusCode == http.StatusOK || respUpdateLookup.StatusCode == http.StatusAccepted {
+		return factory, version, true, nil
+	}
+
+	return "", "", false, fmt.Errorf(
+		"failed to update lookup, status code: %d, response body: %s",
+		respUpdateLookup.StatusCode, respUpdateLookup.ResponseBody)
+}
+
+// initLookups initializes the lookups configuration of a cluster that never had lookups, the coordinator
+// rejects lookups until then.
+func initLookups(httpClient internalhttp.DruidHTTP, svcName string) error {
+	path := druidapi.MakePath(svcName, "coordinator", "lookups", "config")
+
+	resp, err := httpClient.Do(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return nil
+	}
+
+	resp, err = httpClient.Do(http.MethodPost, path, []byte("{}"))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to initialize lookups, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+	return nil
+}
+
+// UpdateNodeStatus reports the load status of the lookup on the nodes of its tier in the Loaded condition.
+func (r *DruidLookupReconciler) UpdateNodeStatus(
+	dl *v1alpha1.DruidLookup,
+	svcName string,
+	auth internalhttp.Auth,
+) error {
+	getHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	condition := metav1.Condition{
+		Type:   v1alpha1.DruidLookupConditionLoaded,
+		Status: metav1.ConditionUnknown,
+		Reason: "StatusUnavailable",
+	}
+
+	var nodes []v1alpha1.DruidLookupNodeStatus
+	resp, err := getHttp.Do(
+		http.MethodGet,
+		druidapi.MakePath(svcName, "coordinator", "lookups", "nodeStatus", getTier(dl), getName(dl)),
+		nil,
+	)
+	if err != nil {
+		condition.Message = err.Error()
+	} else if resp.StatusCode != http.StatusOK {
+		condition.Message = fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode)
+	} else {
+		var nodeStatus map[string]struct {
+			Loaded bool `json:"loaded"`
+		}
+		if err := json.Unmarshal([]byte(resp.ResponseBody), &nodeStatus); err != nil {
+			condition.Message = fmt.Sprintf("error unmarshalling node status: %v", err)
+		} else {
+			var pending int
+			for host, status := range nodeStatus {
+				nodes = append(nodes, v1alpha1.DruidLookupNodeStatus{Host: host, Loaded: status.Loaded})
+				if !status.Loaded {
+					pending++
+				}
+			}
+			sort.Slice(nodes, func(i, j int) bool { return nodes[i].Host < nodes[j].Host })
+
+			switch {
+			case len(nodes) == 0:
+				condition.Status = metav1.ConditionFalse
+				condition.Reason = "NoNodes"
+				condition.Message = "no node of the tier reported the lookup"
+			case pending > 0:
+				condition.Status = metav1.ConditionFalse
+				condition.Reason = "Loading"
+				condition.Message = fmt.Sprintf("%d of %d nodes loaded the lookup", len(nodes)-pending, len(nodes))
+			default:
+				condition.Status = metav1.ConditionTrue
+				condition.Reason = "Loaded"
+				condition.Message = fmt.Sprintf("%d nodes loaded the lookup", len(nodes))
+			}
+		}
+	}
+
+	_, _, err = util.PatchStatus(context.Background(), r.Client, dl, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidLookup)
+		in.Status.Nodes = nodes
+		condition.ObservedGeneration = in.Generation
+		meta.SetStatusCondition(&in.Status.Conditions, condition)
+		return in
+	})
+	return err
+}
+
+// DeleteLookup deletes the lookup of the tier from the cluster, a lookup already deleted is not an error.
+func (r *DruidLookupReconciler) DeleteLookup(
+	dl *v1alpha1.DruidLookup,
+	tier, name string,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	deleteHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	resp, err := deleteHttp.Do(
+		http.MethodDelete,
+		druidapi.MakePath(svcName, "coordinator", "lookups", "config", tier, name),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound:
+		build.Recorder.GenericEvent(
+			dl,
+			v1.EventTypeNormal,
+			fmt.Sprintf("lookup [%s] of tier [%s] deleted", name, tier),
+			DruidLookupControllerDeleteSuccess,
+		)
+		return nil
+	default:
+		build.Recorder.GenericEvent(
+			dl,
+			v1.EventTypeWarning,
+			fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode),
+			DruidLookupControllerDeleteFail,
+		)
+		return fmt.Errorf("failed to delete lookup, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+}
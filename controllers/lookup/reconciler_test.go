@@ -0,0 +1,309 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package lookup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func newTestReconciler(t *testing.T, objs ...client.Object) *DruidLookupReconciler {
+	return &DruidLookupReconciler{
+		Client:   testutil.NewFakeClient(t, objs...),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+// lookupStub is a stand-in for the coordinator lookups API, it records the calls and their bodies.
+type lookupStub struct {
+	calls       []string
+	initialized bool
+	lookup      string
+	nodeStatus  string
+	statusCode  int
+}
+
+func (s *lookupStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	call := r.Method + " " + r.URL.Path
+	body, _ := io.ReadAll(r.Body)
+	if len(body) > 0 {
+		call += " " + string(body)
+	}
+	s.calls = append(s.calls, call)
+
+	if s.statusCode != 0 {
+		w.WriteHeader(s.statusCode)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/druid/coordinator/v1/lookups/config":
+		if r.Method == http.MethodPost {
+			s.initialized = true
+			w.WriteHeader(http.StatusAccepted)
+		} else if !s.initialized {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.URL.Path == "/druid/coordinator/v1/lookups/config/__default/country":
+		switch r.Method {
+		case http.MethodGet:
+			if s.lookup == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(s.lookup))
+		case http.MethodPost:
+			s.lookup = string(body)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			s.lookup = ""
+			w.WriteHeader(http.StatusAccepted)
+		}
+	case r.URL.Path == "/druid/coordinator/v1/lookups/nodeStatus/__default/country":
+		_, _ = w.Write([]byte(s.nodeStatus))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestLookup() *v1alpha1.DruidLookup {
+	return &v1alpha1.DruidLookup{
+		ObjectMeta: metav1.ObjectMeta{Name: "country", Namespace: "default"},
+		Spec: v1alpha1.DruidLookupSpec{
+			DruidClusterName:    "tiny-cluster",
+			ExtractionNamespace: runtime.RawExtension{Raw: []byte(`{"type": "uri", "uri": "s3://bucket/country.json", "pollPeriod": "PT30M"}`)},
+		},
+	}
+}
+
+func TestSync(t *testing.T) {
+	stub := &lookupStub{nodeStatus: `{"10.0.0.1:8083": {"loaded": true}, "10.0.0.2:8083": {"loaded": false}}`}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dl := newTestLookup()
+	r := newTestReconciler(t, dl)
+	build := testutil.NewBuilder(r.Recorder, "DruidLookupController")
+
+	// the lookups are initialized before the first lookup is set
+	assert.NoError(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.Len(t, stub.calls, 5)
+	assert.Equal(t, "GET /druid/coordinator/v1/lookups/config/__default/country", stub.calls[0])
+	assert.Equal(t, "GET /druid/coordinator/v1/lookups/config", stub.calls[1])
+	assert.Equal(t, "POST /druid/coordinator/v1/lookups/config {}", stub.calls[2])
+	assert.Contains(t, stub.calls[3], `POST /druid/coordinator/v1/lookups/config/__default/country {"lookupExtractorFactory":{"extractionNamespace":{"pollPeriod":"PT30M","type":"uri","uri":"s3://bucket/country.json"},"type":"cachedNamespace"},"version":"`)
+
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dl), dl))
+	version := dl.Status.Version
+	assert.NotEmpty(t, version)
+	assert.Equal(t, "__default", dl.Status.Tier)
+	assert.Equal(t, "country", dl.Status.Name)
+	assert.True(t, meta.IsStatusConditionTrue(dl.Status.Conditions, v1alpha1.DruidLookupConditionSynced))
+	assert.Equal(t, []v1alpha1.DruidLookupNodeStatus{
+		{Host: "10.0.0.1:8083", Loaded: true},
+		{Host: "10.0.0.2:8083", Loaded: false},
+	}, dl.Status.Nodes)
+	loaded := meta.FindStatusCondition(dl.Status.Conditions, v1alpha1.DruidLookupConditionLoaded)
+	assert.Equal(t, metav1.ConditionFalse, loaded.Status)
+	assert.Equal(t, "Loading", loaded.Reason)
+
+	// nothing changed, the version is kept
+	stub.calls = nil
+	stub.nodeStatus = `{"10.0.0.1:8083": {"loaded": true}, "10.0.0.2:8083": {"loaded": true}}`
+	assert.NoError(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /druid/coordinator/v1/lookups/config/__default/country",
+		"GET /druid/coordinator/v1/lookups/nodeStatus/__default/country",
+	}, stub.calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dl), dl))
+	assert.Equal(t, version, dl.Status.Version)
+	assert.True(t, meta.IsStatusConditionTrue(dl.Status.Conditions, v1alpha1.DruidLookupConditionLoaded))
+
+	// a change of the spec bumps the version
+	stub.calls = nil
+	dl.Spec.ExtractionNamespace = runtime.RawExtension{Raw: []byte(`{"type": "uri", "uri": "s3://bucket/country.json", "pollPeriod": "PT1H"}`)}
+	dl.Status.Version = "2000-01-01T00:00:00.000Z"
+	assert.NoError(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.Len(t, stub.calls, 3)
+	assert.Contains(t, stub.calls[1], `"pollPeriod":"PT1H"`)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dl), dl))
+	assert.Greater(t, dl.Status.Version, "2000-01-01T00:00:00.000Z")
+}
+
+func TestUpdateNodeStatusUnchanged(t *testing.T) {
+	stub := &lookupStub{nodeStatus: `{"10.0.0.1:8083": {"loaded": true}}`}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	patches := 0
+	dl := newTestLookup()
+	r := &DruidLookupReconciler{
+		Client: testutil.NewFakeClientBuilder(t, dl).WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				patches++
+				return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+			},
+		}).Build(),
+		Recorder: record.NewFakeRecorder(100),
+	}
+
+	assert.NoError(t, r.UpdateNodeStatus(dl, server.URL, internalhttp.Auth{}))
+	assert.Equal(t, 1, patches)
+
+	// the status of the nodes did not change, it is not patched again
+	assert.NoError(t, r.UpdateNodeStatus(dl, server.URL, internalhttp.Auth{}))
+	assert.Equal(t, 1, patches)
+
+	stub.nodeStatus = `{"10.0.0.1:8083": {"loaded": true}, "10.0.0.2:8083": {"loaded": true}}`
+	assert.NoError(t, r.UpdateNodeStatus(dl, server.URL, internalhttp.Auth{}))
+	assert.Equal(t, 2, patches)
+}
+
+func TestSyncVersion(t *testing.T) {
+	stub := &lookupStub{
+		initialized: true,
+		lookup:      `{"version": "v1", "lookupExtractorFactory": {"type": "map", "map": {"US": "United States"}}}`,
+		nodeStatus:  `{}`,
+	}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dl := newTestLookup()
+	dl.Spec.ExtractionNamespace = runtime.RawExtension{}
+	dl.Spec.LookupExtractorFactory = runtime.RawExtension{Raw: []byte(`{"type": "map", "map": {"US": "United States"}}`)}
+	dl.Spec.Version = "v2"
+
+	r := newTestReconciler(t, dl)
+	build := testutil.NewBuilder(r.Recorder, "DruidLookupController")
+
+	// a version set in the spec is applied as is
+	assert.NoError(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /druid/coordinator/v1/lookups/config/__default/country",
+		`POST /druid/coordinator/v1/lookups/config/__default/country {"lookupExtractorFactory":{"map":{"US":"United States"},"type":"map"},"version":"v2"}`,
+		"GET /druid/coordinator/v1/lookups/nodeStatus/__default/country",
+	}, stub.calls)
+
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dl), dl))
+	assert.Equal(t, "v2", dl.Status.Version)
+	loaded := meta.FindStatusCondition(dl.Status.Conditions, v1alpha1.DruidLookupConditionLoaded)
+	assert.Equal(t, "NoNodes", loaded.Reason)
+}
+
+func TestSyncInvalidLookup(t *testing.T) {
+	stub := &lookupStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dl := newTestLookup()
+	dl.Spec.LookupExtractorFactory = runtime.RawExtension{Raw: []byte(`{"type": "map", "map": {}}`)}
+
+	r := newTestReconciler(t, dl)
+	build := testutil.NewBuilder(r.Recorder, "DruidLookupController")
+
+	assert.Error(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.Empty(t, stub.calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dl), dl))
+	assert.True(t, meta.IsStatusConditionFalse(dl.Status.Conditions, v1alpha1.DruidLookupConditionSynced))
+}
+
+func TestSyncTierManagedByDruid(t *testing.T) {
+	stub := &lookupStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	dl := newTestLookup()
+	drd := &v1alpha1.Druid{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiny-cluster", Namespace: "default"},
+		Spec: v1alpha1.DruidSpec{
+			Lookups: runtime.RawExtension{Raw: []byte(`{"__default": {"country": {"version": "v1", "lookupExtractorFactory": {"type": "map", "map": {}}}}}`)},
+		},
+	}
+
+	r := newTestReconciler(t, dl, drd)
+	build := testutil.NewBuilder(r.Recorder, "DruidLookupController")
+
+	assert.Error(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.Empty(t, stub.calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(dl), dl))
+	synced := meta.FindStatusCondition(dl.Status.Conditions, v1alpha1.DruidLookupConditionSynced)
+	assert.Equal(t, metav1.ConditionFalse, synced.Status)
+	assert.Equal(t, "tier [__default] is managed by the lookups of Druid [tiny-cluster]", synced.Message)
+
+	// the lookups of other tiers do not prevent it from being applied
+	drd.Spec.Lookups = runtime.RawExtension{Raw: []byte(`{"hot": {}}`)}
+	assert.NoError(t, r.Update(context.TODO(), drd))
+	assert.NoError(t, r.Sync(dl, server.URL, build, internalhttp.Auth{}))
+	assert.NotEmpty(t, stub.calls)
+}
+
+func TestDeleteLookup(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		expectErr  bool
+	}{
+		{
+			name: "deleted",
+		},
+		{
+			name:       "already deleted",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "failure",
+			statusCode: http.StatusInternalServerError,
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &lookupStub{statusCode: tt.statusCode}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			dl := newTestLookup()
+			r := newTestReconciler(t, dl)
+			build := testutil.NewBuilder(r.Recorder, "DruidLookupController")
+
+			err := r.DeleteLookup(dl, "__default", "country", server.URL, build, internalhttp.Auth{})
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, []string{"DELETE /druid/coordinator/v1/lookups/config/__default/country"}, stub.calls)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	DruidRoleControllerUpdateSuccess = "DruidRoleControllerUpdateSuccess"
+	DruidRoleControllerUpdateFail    = "DruidRoleControllerUpdateFail"
+	DruidRoleControllerDeleteSuccess = "DruidRoleControllerDeleteSuccess"
+	DruidRoleControllerDeleteFail    = "DruidRoleControllerDeleteFail"
+	DruidRoleControllerFinalizer     = "druidrole.datainfra.io/finalizer"
+)
+
+func (r *DruidRoleReconciler) do(ctx context.Context, drl *v1alpha1.DruidRole) error {
+	auth, err := druidapi.GetAuth(
+		ctx,
+		r.Client,
+		drl.Namespace,
+		drl.Spec.Auth,
+	)
+	if err != nil {
+		return err
+	}
+
+	svcName, err := druidapi.GetRouterSvcUrl(drl.Namespace, drl.Spec.DruidClusterName, "", druidapi.CoordinatorAPI, drl.Spec.Auth.TLS != nil, r.Client)
+	if err != nil {
+		return err
+	}
+
+	build := builder.NewBuilder(
+		builder.ToNewBuilderRecorder(builder.BuilderRecorder{Recorder: r.Recorder, ControllerName: "DruidRoleController"}),
+	)
+
+	if drl.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(drl, DruidRoleControllerFinalizer) {
+			controllerutil.AddFinalizer(drl, DruidRoleControllerFinalizer)
+			if err := r.Update(ctx, drl.DeepCopyObject().(*v1alpha1.DruidRole)); err != nil {
+				return err
+			}
+		}
+		return r.Sync(drl, svcName, *build, auth)
+	}
+
+	if controllerutil.ContainsFinalizer(drl, DruidRoleControllerFinalizer) {
+		// our finalizer is present, so lets delete the role from the cluster
+		roleName := drl.Status.RoleName
+		if roleName == "" {
+			roleName = getRoleName(drl)
+		}
+		if err := r.DeleteRole(drl, roleName, svcName, *build, auth); err != nil {
+			return err
+		}
+
+		controllerutil.RemoveFinalizer(drl, DruidRoleControllerFinalizer)
+		if err := r.Update(ctx, drl.DeepCopyObject().(*v1alpha1.DruidRole)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getRoleName returns the name of the role in Druid, the name of the DruidRole by default.
+func getRoleName(drl *v1alpha1.DruidRole) string {
+	if drl.Spec.RoleName != "" {
+		return drl.Spec.RoleName
+	}
+	return drl.Name
+}
+
+// Sync creates the role of the DruidRole, sets its permissions, deletes it under its previous name when it was
+// renamed and reports the outcome in the Synced condition.
+func (r *DruidRoleReconciler) Sync(
+	drl *v1alpha1.DruidRole,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	roleName := getRoleName(drl)
+
+	if drl.Status.RoleName != "" && drl.Status.RoleName != roleName {
+		if err := r.DeleteRole(drl, drl.Status.RoleName, svcName, build, auth); err != nil {
+			return err
+		}
+	}
+
+	permissions := drl.Spec.Permissions
+	updated, syncErr := r.UpdateRole(drl, svcName, auth)
+
+	if _, _, err := util.PatchStatus(context.Background(), r.Client, drl, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidRole)
+		in.Status.RoleName = roleName
+		if syncErr == nil {
+			in.Status.CurrentPermissions = permissions
+		}
+
+		condition := metav1.Condition{
+			Type:               v1alpha1.DruidRoleConditionSynced,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Synced",
+			Message:            "role and permissions are applied",
+			ObservedGeneration: in.Generation,
+		}
+		if syncErr != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SyncFailed"
+			condition.Message = syncErr.Error()
+		}
+		if updated || syncErr != nil {
+			in.Status.Reason = condition.Reason
+			in.Status.Message = condition.Message
+			in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		}
+		meta.SetStatusCondition(&in.Status.Conditions, condition)
+		return in
+	}); err != nil {
+		return err
+	}
+
+	if syncErr != nil {
+		build.Recorder.GenericEvent(drl, v1.EventTypeWarning, syncErr.Error(), DruidRoleControllerUpdateFail)
+		return syncErr
+	}
+	if updated {
+		build.Recorder.GenericEvent(
+			drl,
+			v1.EventTypeNormal,
+			fmt.Sprintf("permissions of role [%s] updated", roleName),
+			DruidRoleControllerUpdateSuccess,
+		)
+	}
+	return nil
+}
+
+// UpdateRole creates the role when it does not exist, and sets its permissions when the role was created or
+// its permissions in Druid differ from the spec, such as after a change made outside of the operator.
+func (r *DruidRoleReconciler) UpdateRole(
+	drl *v1alpha1.DruidRole,
+	svcName string,
+	auth internalhttp.Auth,
+) (bool, error) {
+	roleName := getRoleName(drl)
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	rolePath := makeAuthorizationPath(svcName, drl.Spec.AuthorizerName, "roles", roleName)
+	created, err := ensureExists(httpClient, rolePath, "role")
+	if err != nil {
+		return false, err
+	}
+	if !created {
+		current, err := getRolePermissions(httpClient, rolePath)
+		if err != nil {
+			return false, err
+		}
+		if equality.Semantic.DeepEqual(current, drl.Spec.Permissions) {
+			return false, nil
+		}
+	}
+
+	permissions := drl.Spec.Permissions
+	if permissions == nil {
+		permissions = []v1alpha1.DruidPermission{}
+	}
+	permissionsData, err := util.ToJsonString(permissions)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := httpClient.Do(
+		http.MethodPost,
+		makeAuthorizationPath(svcName, drl.Spec.AuthorizerName, "roles", roleName, "permissions"),
+		[]byte(permissionsData),
+	)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to set permissions, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+	return true, nil
+}
+
+// getRolePermissions returns the permissions of the role in Druid, read from the full role.
+func getRolePermissions(httpClient internalhttp.DruidHTTP, rolePath string) ([]v1alpha1.DruidPermission, error) {
+	resp, err := httpClient.Do(http.MethodGet, rolePath+"?full", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get role, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+
+	var role struct {
+		Permissions []struct {
+			ResourceAction v1alpha1.DruidPermission `json:"resourceAction"`
+		} `json:"permissions"`
+	}
+	if err := json.Unmarshal([]byte(resp.ResponseBody), &role); err != nil {
+		return nil, err
+	}
+
+	permissions := make([]v1alpha1.DruidPermission, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.ResourceAction)
+	}
+	return permissions, nil
+}
+
+// DeleteRole deletes the role from the basic authorizer, a role already deleted is not an error.
+func (r *DruidRoleReconciler) DeleteRole(
+	drl *v1alpha1.DruidRole,
+	roleName string,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	deleteHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	resp, err := deleteHttp.Do(
+		http.MethodDelete,
+		makeAuthorizationPath(svcName, drl.Spec.AuthorizerName, "roles", roleName),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !isDeleted(resp) {
+		build.Recorder.GenericEvent(
+			drl,
+			v1.EventTypeWarning,
+			fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode),
+			DruidRoleControllerDeleteFail,
+		)
+		return fmt.Errorf("failed to delete role, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+
+	build.Recorder.GenericEvent(
+		drl,
+		v1.EventTypeNormal,
+		fmt.Sprintf("role [%s] deleted", roleName),
+		DruidRoleControllerDeleteSuccess,
+	)
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSyncRole(t *testing.T) {
+	stub := newSecurityStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	drl := &v1alpha1.DruidRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "readers", Namespace: "default"},
+		Spec: v1alpha1.DruidRoleSpec{
+			DruidClusterName: "tiny-cluster",
+			AuthorizerName:   "basic",
+			Permissions: []v1alpha1.DruidPermission{
+				{Resource: v1alpha1.DruidResource{Name: ".*", Type: "DATASOURCE"}, Action: "READ"},
+			},
+		},
+	}
+	r := &DruidRoleReconciler{
+		Client:   testutil.NewFakeClient(t, drl),
+		Recorder: record.NewFakeRecorder(100),
+	}
+	build := testutil.NewBuilder(r.Recorder, "DruidRoleController")
+
+	// the role is created and its permissions set
+	assert.NoError(t, r.Sync(drl, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /authorization/db/basic/roles/readers",
+		"POST /authorization/db/basic/roles/readers",
+		`POST /authorization/db/basic/roles/readers/permissions [{"resource":{"name":".*","type":"DATASOURCE"},"action":"READ"}]`,
+	}, stub.calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(drl), drl))
+	assert.Equal(t, "readers", drl.Status.RoleName)
+	assert.Equal(t, drl.Spec.Permissions, drl.Status.CurrentPermissions)
+	assert.True(t, meta.IsStatusConditionTrue(drl.Status.Conditions, v1alpha1.DruidRoleConditionSynced))
+
+	// nothing changed, the permissions are not set again
+	stub.calls = nil
+	assert.NoError(t, r.Sync(drl, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /authorization/db/basic/roles/readers",
+		"GET /authorization/db/basic/roles/readers?full",
+	}, stub.calls)
+
+	// the permissions changed outside of the operator are set again
+	stub.calls = nil
+	stub.resources["/authorization/db/basic/roles/readers/permissions"] = `[{"resource":{"name":".*","type":"DATASOURCE"},"action":"WRITE"}]`
+	assert.NoError(t, r.Sync(drl, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /authorization/db/basic/roles/readers",
+		"GET /authorization/db/basic/roles/readers?full",
+		`POST /authorization/db/basic/roles/readers/permissions [{"resource":{"name":".*","type":"DATASOURCE"},"action":"READ"}]`,
+	}, stub.calls)
+
+	// a renamed role is deleted under its previous name
+	stub.calls = nil
+	drl.Spec.RoleName = "datasource-readers"
+	assert.NoError(t, r.Sync(drl, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"DELETE /authorization/db/basic/roles/readers",
+		"GET /authorization/db/basic/roles/datasource-readers",
+		"POST /authorization/db/basic/roles/datasource-readers",
+		`POST /authorization/db/basic/roles/datasource-readers/permissions [{"resource":{"name":".*","type":"DATASOURCE"},"action":"READ"}]`,
+	}, stub.calls)
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(drl), drl))
+	assert.Equal(t, "datasource-readers", drl.Status.RoleName)
+}
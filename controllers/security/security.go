@@ -0,0 +1,78 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+)
+
+const (
+	authenticationApi = "authentication"
+	authorizationApi  = "authorization"
+)
+
+// isNotFound returns true when the basic-security API reports a missing user or role, it answers
+// 400 with a "does not exist" message rather than 404.
+func isNotFound(resp *internalhttp.Response) bool {
+	return resp.StatusCode == http.StatusNotFound ||
+		(resp.StatusCode == http.StatusBadRequest && strings.Contains(resp.ResponseBody, "does not exist"))
+}
+
+// isDeleted returns true when a user or role is deleted, or was already.
+func isDeleted(resp *internalhttp.Response) bool {
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent || isNotFound(resp)
+}
+
+// ensureExists creates the user or role at path when it does not exist, it returns true when it was created.
+func ensureExists(httpClient internalhttp.DruidHTTP, path, kind string) (bool, error) {
+	resp, err := httpClient.Do(http.MethodGet, path, nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	if !isNotFound(resp) {
+		return false, fmt.Errorf("failed to get %s, status code: %d, response body: %s", kind, resp.StatusCode, resp.ResponseBody)
+	}
+
+	resp, err = httpClient.Do(http.MethodPost, path, nil)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to create %s, status code: %d, response body: %s", kind, resp.StatusCode, resp.ResponseBody)
+	}
+	return true, nil
+}
+
+// makeAuthenticationPath returns the path of a resource of the basic authenticator.
+func makeAuthenticationPath(svcName, authenticatorName string, paths ...string) string {
+	return druidapi.MakeBasicSecurityPath(svcName, authenticationApi, authenticatorName, paths...)
+}
+
+// makeAuthorizationPath returns the path of a resource of the basic authorizer.
+func makeAuthorizationPath(svcName, authorizerName string, paths ...string) string {
+	return druidapi.MakeBasicSecurityPath(svcName, authorizationApi, authorizerName, paths...)
+}
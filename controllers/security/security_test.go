@@ -0,0 +1,97 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// securityStub is a stand-in for the basic-security API, it serves the users and roles in resources by path
+// and records the calls and their bodies. The permissions set on a role are served in its full form.
+type securityStub struct {
+	calls      []string
+	resources  map[string]string
+	statusCode int
+}
+
+func newSecurityStub() *securityStub {
+	return &securityStub{resources: map[string]string{}}
+}
+
+func (s *securityStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/druid-ext/basic-security")
+	call := r.Method + " " + path
+	if r.URL.RawQuery != "" {
+		call += "?" + r.URL.RawQuery
+	}
+	requestBody, _ := io.ReadAll(r.Body)
+	if len(requestBody) > 0 {
+		call += " " + string(requestBody)
+	}
+	s.calls = append(s.calls, call)
+
+	if s.statusCode != 0 {
+		w.WriteHeader(s.statusCode)
+		return
+	}
+
+	body, ok := s.resources[path]
+	switch r.Method {
+	case http.MethodGet:
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"error": "[%s] does not exist"}`, path)))
+			return
+		}
+		if r.URL.Query().Has("full") {
+			_, _ = w.Write([]byte(s.fullRole(path)))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	case http.MethodPost:
+		if strings.HasSuffix(path, "/permissions") {
+			s.resources[path] = string(requestBody)
+		} else if !ok {
+			s.resources[path] = "{}"
+		}
+	case http.MethodDelete:
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"error": "[%s] does not exist"}`, path)))
+			return
+		}
+		delete(s.resources, path)
+	}
+}
+
+// fullRole returns the role at path with its permissions, as served with the full flag.
+func (s *securityStub) fullRole(path string) string {
+	var permissions []json.RawMessage
+	_ = json.Unmarshal([]byte(s.resources[path+"/permissions"]), &permissions)
+
+	resourceActions := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		resourceActions = append(resourceActions, fmt.Sprintf(`{"resourceAction": %s}`, permission))
+	}
+	return fmt.Sprintf(`{"name": %q, "permissions": [%s]}`, path[strings.LastIndex(path, "/")+1:], strings.Join(resourceActions, ", "))
+}
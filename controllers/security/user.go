@@ -0,0 +1,334 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druidapi "github.com/datainfrahq/druid-operator/pkg/druidapi"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+	"github.com/datainfrahq/operator-runtime/builder"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	DruidUserControllerUpdateSuccess = "DruidUserControllerUpdateSuccess"
+	DruidUserControllerUpdateFail    = "DruidUserControllerUpdateFail"
+	DruidUserControllerDeleteSuccess = "DruidUserControllerDeleteSuccess"
+	DruidUserControllerDeleteFail    = "DruidUserControllerDeleteFail"
+	DruidUserControllerFinalizer     = "druiduser.datainfra.io/finalizer"
+)
+
+func (r *DruidUserReconciler) do(ctx context.Context, du *v1alpha1.DruidUser) error {
+	auth, err := druidapi.GetAuth(
+		ctx,
+		r.Client,
+		du.Namespace,
+		du.Spec.Auth,
+	)
+	if err != nil {
+		return err
+	}
+
+	svcName, err := druidapi.GetRouterSvcUrl(du.Namespace, du.Spec.DruidClusterName, "", druidapi.CoordinatorAPI, du.Spec.Auth.TLS != nil, r.Client)
+	if err != nil {
+		return err
+	}
+
+	build := builder.NewBuilder(
+		builder.ToNewBuilderRecorder(builder.BuilderRecorder{Recorder: r.Recorder, ControllerName: "DruidUserController"}),
+	)
+
+	if du.ObjectMeta.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(du, DruidUserControllerFinalizer) {
+			controllerutil.AddFinalizer(du, DruidUserControllerFinalizer)
+			if err := r.Update(ctx, du.DeepCopyObject().(*v1alpha1.DruidUser)); err != nil {
+				return err
+			}
+		}
+		return r.Sync(ctx, du, svcName, *build, auth)
+	}
+
+	if controllerutil.ContainsFinalizer(du, DruidUserControllerFinalizer) {
+		// our finalizer is present, so lets delete the user from the cluster
+		userName := du.Status.UserName
+		if userName == "" {
+			userName = getUserName(du)
+		}
+		if err := r.DeleteUser(du, userName, svcName, *build, auth); err != nil {
+			return err
+		}
+
+		controllerutil.RemoveFinalizer(du, DruidUserControllerFinalizer)
+		if err := r.Update(ctx, du.DeepCopyObject().(*v1alpha1.DruidUser)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getUserName returns the name of the user in Druid, the name of the DruidUser by default.
+func getUserName(du *v1alpha1.DruidUser) string {
+	if du.Spec.UserName != "" {
+		return du.Spec.UserName
+	}
+	return du.Name
+}
+
+// Sync creates the user of the DruidUser, sets its password and role bindings, deletes it under its previous
+// name when it was renamed and reports the outcome in the Synced condition.
+func (r *DruidUserReconciler) Sync(
+	ctx context.Context,
+	du *v1alpha1.DruidUser,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	userName := getUserName(du)
+
+	if du.Status.UserName != "" && du.Status.UserName != userName {
+		if err := r.DeleteUser(du, du.Status.UserName, svcName, build, auth); err != nil {
+			return err
+		}
+	}
+
+	roles := du.Spec.Roles
+	passwordSecretVersion, updated, syncErr := r.UpdateUser(ctx, du, svcName, auth)
+
+	if _, _, err := util.PatchStatus(ctx, r.Client, du, func(obj client.Object) client.Object {
+		in := obj.(*v1alpha1.DruidUser)
+		in.Status.UserName = userName
+		if syncErr == nil {
+			in.Status.Roles = roles
+			in.Status.PasswordSecretVersion = passwordSecretVersion
+		}
+
+		condition := metav1.Condition{
+			Type:               v1alpha1.DruidUserConditionSynced,
+			Status:             metav1.ConditionTrue,
+			Reason:             "Synced",
+			Message:            "user, password and roles are applied",
+			ObservedGeneration: in.Generation,
+		}
+		if syncErr != nil {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SyncFailed"
+			condition.Message = syncErr.Error()
+		}
+		if updated || syncErr != nil {
+			in.Status.Reason = condition.Reason
+			in.Status.Message = condition.Message
+			in.Status.LastUpdateTime = metav1.Time{Time: time.Now()}
+		}
+		meta.SetStatusCondition(&in.Status.Conditions, condition)
+		return in
+	}); err != nil {
+		return err
+	}
+
+	if syncErr != nil {
+		build.Recorder.GenericEvent(du, v1.EventTypeWarning, syncErr.Error(), DruidUserControllerUpdateFail)
+		return syncErr
+	}
+	if updated {
+		build.Recorder.GenericEvent(
+			du,
+			v1.EventTypeNormal,
+			fmt.Sprintf("user [%s] updated", userName),
+			DruidUserControllerUpdateSuccess,
+		)
+	}
+	return nil
+}
+
+// UpdateUser creates the user in the basic authenticator and authorizer when it does not exist, sets its
+// password when the user was created or the secret changed, and binds or unbinds its roles. It returns the
+// resource version of the password secret and whether anything was changed on the cluster.
+func (r *DruidUserReconciler) UpdateUser(
+	ctx context.Context,
+	du *v1alpha1.DruidUser,
+	svcName string,
+	auth internalhttp.Auth,
+) (string, bool, error) {
+	userName := getUserName(du)
+	renamed := du.Status.UserName != userName
+	httpClient := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	created, err := ensureExists(httpClient, makeAuthenticationPath(svcName, du.Spec.AuthenticatorName, "users", userName), "user")
+	if err != nil {
+		return "", false, err
+	}
+	updated := created
+
+	var passwordSecretVersion string
+	if du.Spec.PasswordSecret != nil {
+		secret := &v1.Secret{}
+		if err := r.Client.Get(ctx, types.NamespacedName{
+			Namespace: du.Namespace,
+			Name:      du.Spec.PasswordSecret.Name,
+		}, secret); err != nil {
+			return "", false, err
+		}
+		password, ok := secret.Data[du.Spec.PasswordSecret.Key]
+		if !ok {
+			return "", false, fmt.Errorf("key [%s] not found in secret [%s]", du.Spec.PasswordSecret.Key, du.Spec.PasswordSecret.Name)
+		}
+
+		passwordSecretVersion = secret.ResourceVersion
+		if created || renamed || passwordSecretVersion != du.Status.PasswordSecretVersion {
+			credentials, err := util.ToJsonString(map[string]string{"password": string(password)})
+			if err != nil {
+				return "", false, err
+			}
+			resp, err := httpClient.Do(
+				http.MethodPost,
+				makeAuthenticationPath(svcName, du.Spec.AuthenticatorName, "users", userName, "credentials"),
+				[]byte(credentials),
+			)
+			if err != nil {
+				return "", false, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				return "", false, fmt.Errorf("failed to set password, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+			}
+			updated = true
+		}
+	}
+
+	userPath := makeAuthorizationPath(svcName, du.Spec.AuthorizerName, "users", userName)
+	created, err = ensureExists(httpClient, userPath, "user")
+	if err != nil {
+		return "", false, err
+	}
+	updated = updated || created
+
+	resp, err := httpClient.Do(http.MethodGet, userPath, nil)
+	if err != nil {
+		return "", false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("failed to get user roles, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+	}
+	var currentUser struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.Unmarshal([]byte(resp.ResponseBody), &currentUser); err != nil {
+		return "", false, err
+	}
+	currentRoles := map[string]bool{}
+	for _, role := range currentUser.Roles {
+		currentRoles[role] = true
+	}
+	desiredRoles := map[string]bool{}
+	for _, role := range du.Spec.Roles {
+		desiredRoles[role] = true
+	}
+
+	for _, role := range du.Spec.Roles {
+		if currentRoles[role] {
+			continue
+		}
+		if err := r.setRole(httpClient, http.MethodPost, userPath, role); err != nil {
+			return "", false, err
+		}
+		updated = true
+	}
+	// only the roles bound by the operator are unbound, the ones bound outside of it are left as is
+	for _, role := range du.Status.Roles {
+		if desiredRoles[role] || !currentRoles[role] {
+			continue
+		}
+		if err := r.setRole(httpClient, http.MethodDelete, userPath, role); err != nil {
+			return "", false, err
+		}
+		updated = true
+	}
+
+	return passwordSecretVersion, updated, nil
+}
+
+// setRole binds the role to the user with POST, or unbinds it with DELETE.
+func (r *DruidUserReconciler) setRole(httpClient internalhttp.DruidHTTP, method, userPath, role string) error {
+	resp, err := httpClient.Do(method, userPath+"/roles/"+role, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to %s role [%s], status code: %d, response body: %s", method, role, resp.StatusCode, resp.ResponseBody)
+	}
+	return nil
+}
+
+// DeleteUser deletes the user from the basic authorizer then the basic authenticator, a user already
+// deleted is not an error.
+func (r *DruidUserReconciler) DeleteUser(
+	du *v1alpha1.DruidUser,
+	userName string,
+	svcName string,
+	build builder.Builder,
+	auth internalhttp.Auth,
+) error {
+	deleteHttp := internalhttp.NewHTTPClient(
+		&http.Client{},
+		&auth,
+	)
+
+	for _, path := range []string{
+		makeAuthorizationPath(svcName, du.Spec.AuthorizerName, "users", userName),
+		makeAuthenticationPath(svcName, du.Spec.AuthenticatorName, "users", userName),
+	} {
+		resp, err := deleteHttp.Do(http.MethodDelete, path, nil)
+		if err != nil {
+			return err
+		}
+
+		if !isDeleted(resp) {
+			build.Recorder.GenericEvent(
+				du,
+				v1.EventTypeWarning,
+				fmt.Sprintf("Resp [%s], StatusCode [%d]", resp.ResponseBody, resp.StatusCode),
+				DruidUserControllerDeleteFail,
+			)
+			return fmt.Errorf("failed to delete user, status code: %d, response body: %s", resp.StatusCode, resp.ResponseBody)
+		}
+	}
+
+	build.Recorder.GenericEvent(
+		du,
+		v1.EventTypeNormal,
+		fmt.Sprintf("user [%s] deleted", userName),
+		DruidUserControllerDeleteSuccess,
+	)
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	"github.com/datainfrahq/druid-operator/pkg/util"
+)
+
+// DruidUserReconciler manages a user of the basic authenticator and authorizer, its password and its roles.
+type DruidUserReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// reconcile time duration, defaults to 10s
+	ReconcileWait time.Duration
+	Recorder      record.EventRecorder
+}
+
+func NewDruidUserReconciler(mgr ctrl.Manager) *DruidUserReconciler {
+	return &DruidUserReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("User"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("druid-user"),
+	}
+}
+
+// +kubebuilder:rbac:groups=druid.apache.org,resources=druidusers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=druid.apache.org,resources=druidusers/status,verbs=get;update;patch
+func (r *DruidUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	druidUserCR := &v1alpha1.DruidUser{}
+	return util.ReconcileObject(ctx, r.Client, req, druidUserCR, func(ctx context.Context) error {
+		return r.do(ctx, druidUserCR)
+	})
+}
+
+func (r *DruidUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.DruidUser{}).
+		Complete(r)
+}
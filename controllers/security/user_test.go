@@ -0,0 +1,179 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
+	"github.com/datainfrahq/druid-operator/pkg/util/testutil"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestUser() *v1alpha1.DruidUser {
+	return &v1alpha1.DruidUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "default"},
+		Spec: v1alpha1.DruidUserSpec{
+			DruidClusterName:  "tiny-cluster",
+			AuthenticatorName: "basic",
+			AuthorizerName:    "basic",
+			PasswordSecret: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: "alice-password"},
+				Key:                  "password",
+			},
+			Roles: []string{"readers"},
+		},
+	}
+}
+
+func newTestUserReconciler(t *testing.T, objs ...client.Object) *DruidUserReconciler {
+	return &DruidUserReconciler{
+		Client:   testutil.NewFakeClient(t, objs...),
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
+
+func TestSyncUser(t *testing.T) {
+	stub := newSecurityStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	du := newTestUser()
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice-password", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	r := newTestUserReconciler(t, du, secret)
+	build := testutil.NewBuilder(r.Recorder, "DruidUserController")
+
+	// the user is created in the authenticator and the authorizer, its password is set and its role bound
+	assert.NoError(t, r.Sync(context.TODO(), du, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /authentication/db/basic/users/alice",
+		"POST /authentication/db/basic/users/alice",
+		`POST /authentication/db/basic/users/alice/credentials {"password":"secret"}`,
+		"GET /authorization/db/basic/users/alice",
+		"POST /authorization/db/basic/users/alice",
+		"GET /authorization/db/basic/users/alice",
+		"POST /authorization/db/basic/users/alice/roles/readers",
+	}, stub.calls)
+
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(du), du))
+	assert.Equal(t, "alice", du.Status.UserName)
+	assert.Equal(t, []string{"readers"}, du.Status.Roles)
+	assert.NotEmpty(t, du.Status.PasswordSecretVersion)
+	assert.True(t, meta.IsStatusConditionTrue(du.Status.Conditions, v1alpha1.DruidUserConditionSynced))
+
+	// nothing changed, the password is not set again
+	stub.calls = nil
+	stub.resources["/authorization/db/basic/users/alice"] = `{"name": "alice", "roles": ["readers", "admins"]}`
+	assert.NoError(t, r.Sync(context.TODO(), du, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /authentication/db/basic/users/alice",
+		"GET /authorization/db/basic/users/alice",
+		"GET /authorization/db/basic/users/alice",
+	}, stub.calls)
+
+	// a role removed from the spec is unbound, a role bound outside of the operator is kept
+	stub.calls = nil
+	du.Spec.Roles = []string{"writers"}
+	assert.NoError(t, r.Sync(context.TODO(), du, server.URL, build, internalhttp.Auth{}))
+	assert.Equal(t, []string{
+		"GET /authentication/db/basic/users/alice",
+		"GET /authorization/db/basic/users/alice",
+		"GET /authorization/db/basic/users/alice",
+		"POST /authorization/db/basic/users/alice/roles/writers",
+		"DELETE /authorization/db/basic/users/alice/roles/readers",
+	}, stub.calls)
+
+	// a change of the secret sets the password again
+	stub.calls = nil
+	secret.Data["password"] = []byte("rotated")
+	assert.NoError(t, r.Update(context.TODO(), secret))
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(du), du))
+	du.Spec.Roles = []string{"writers"}
+	assert.NoError(t, r.Sync(context.TODO(), du, server.URL, build, internalhttp.Auth{}))
+	assert.Contains(t, stub.calls, `POST /authentication/db/basic/users/alice/credentials {"password":"rotated"}`)
+}
+
+func TestSyncUserMissingSecret(t *testing.T) {
+	stub := newSecurityStub()
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	du := newTestUser()
+	r := newTestUserReconciler(t, du)
+	build := testutil.NewBuilder(r.Recorder, "DruidUserController")
+
+	assert.Error(t, r.Sync(context.TODO(), du, server.URL, build, internalhttp.Auth{}))
+	assert.NoError(t, r.Get(context.TODO(), client.ObjectKeyFromObject(du), du))
+	assert.True(t, meta.IsStatusConditionFalse(du.Status.Conditions, v1alpha1.DruidUserConditionSynced))
+	assert.Empty(t, du.Status.PasswordSecretVersion)
+}
+
+func TestDeleteUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		expectErr  bool
+	}{
+		{
+			name: "already deleted",
+		},
+		{
+			name:       "failure",
+			statusCode: http.StatusInternalServerError,
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newSecurityStub()
+			stub.statusCode = tt.statusCode
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			du := newTestUser()
+			r := newTestUserReconciler(t, du)
+			build := testutil.NewBuilder(r.Recorder, "DruidUserController")
+
+			err := r.DeleteUser(du, "alice", server.URL, build, internalhttp.Auth{})
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Equal(t, []string{"DELETE /authorization/db/basic/users/alice"}, stub.calls)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{
+					"DELETE /authorization/db/basic/users/alice",
+					"DELETE /authentication/db/basic/users/alice",
+				}, stub.calls)
+			}
+		})
+	}
+}
@@ -29,7 +29,9 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -37,7 +39,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	druidv1alpha1 "github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+	druiddatasourcecontrollers "github.com/datainfrahq/druid-operator/controllers/datasource"
 	druidingestioncontrollers "github.com/datainfrahq/druid-operator/controllers/ingestion"
+	druidlookupcontrollers "github.com/datainfrahq/druid-operator/controllers/lookup"
+	druidsecuritycontrollers "github.com/datainfrahq/druid-operator/controllers/security"
+	"github.com/datainfrahq/druid-operator/pkg/druidapi"
+	"github.com/datainfrahq/druid-operator/pkg/util"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -59,6 +66,12 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var disableIngestionController bool
+	var disableDataSourceController bool
+	var disableLookupController bool
+	var disableSecurityControllers bool
+	var enableWebhooks bool
+	var enableAPIEndpoint bool
+	var allowCrossNamespaceSecrets bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +79,18 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&disableIngestionController, "disable-ingestion-controller", false,
 		"Disable the DruidIngestion controller. Use this if the DruidIngestion CRD is not installed.")
+	flag.BoolVar(&disableDataSourceController, "disable-datasource-controller", false,
+		"Disable the DruidDataSource controller. Use this if the DruidDataSource CRD is not installed.")
+	flag.BoolVar(&disableLookupController, "disable-lookup-controller", false,
+		"Disable the DruidLookup controller. Use this if the DruidLookup CRD is not installed.")
+	flag.BoolVar(&disableSecurityControllers, "disable-security-controllers", false,
+		"Disable the DruidUser and DruidRole controllers. Use this if the DruidUser and DruidRole CRDs are not installed.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks. Requires the webhook serving certificates to be mounted.")
+	flag.BoolVar(&enableAPIEndpoint, "enable-api-endpoint", false,
+		"Allow the CRs to override the discovery of the Druid API with their apiEndpoint. The credentials of the CRs are sent to it.")
+	flag.BoolVar(&allowCrossNamespaceSecrets, "allow-cross-namespace-secrets", false,
+		"Allow the auth of the CRs to reference the secrets of other namespaces.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -73,6 +98,8 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	druidapi.EnableAPIEndpoint = enableAPIEndpoint
+	druidapi.AllowCrossNamespaceSecrets = allowCrossNamespaceSecrets
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -83,6 +110,10 @@ func main() {
 		LeaderElectionID:       "e6946145.apache.org",
 		Namespace:              os.Getenv("WATCH_NAMESPACE"),
 		NewCache:               watchNamespaceCache(),
+		// only the data of the configmaps and secrets created by the operator is cached, the ones referenced by the
+		// CRs are read from the API server
+		Cache:     cache.Options{ByObject: util.CacheByObject()},
+		NewClient: util.NewClient,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -100,20 +131,71 @@ func main() {
 		os.Exit(1)
 	}
 
+	// helm upgrade doesn't apply the CRDs added by a release, their controllers are skipped until they are applied
+	disableIngestionController = disableIngestionController || !hasCRDs(mgr, "DruidIngestion")
+	disableDataSourceController = disableDataSourceController || !hasCRDs(mgr, "DruidDataSource")
+	disableLookupController = disableLookupController || !hasCRDs(mgr, "DruidLookup")
+	disableSecurityControllers = disableSecurityControllers || !hasCRDs(mgr, "DruidUser", "DruidRole")
+
 	if err = (druid.NewDruidReconciler(mgr)).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Druid")
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		if err = (&druid.DruidWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Druid")
+			os.Exit(1)
+		}
+	}
+
 	if !disableIngestionController {
 		if err = (druidingestioncontrollers.NewDruidIngestionReconciler(mgr)).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "DruidIngestion")
 			os.Exit(1)
 		}
+
+		if enableWebhooks {
+			if err = (&druidingestioncontrollers.DruidIngestionWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "DruidIngestion")
+				os.Exit(1)
+			}
+		}
 	} else {
 		setupLog.Info("DruidIngestion controller is disabled")
 	}
 
+	if !disableDataSourceController {
+		if err = (druiddatasourcecontrollers.NewDruidDataSourceReconciler(mgr)).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "DruidDataSource")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("DruidDataSource controller is disabled")
+	}
+
+	if !disableLookupController {
+		if err = (druidlookupcontrollers.NewDruidLookupReconciler(mgr)).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "DruidLookup")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("DruidLookup controller is disabled")
+	}
+
+	if !disableSecurityControllers {
+		if err = (druidsecuritycontrollers.NewDruidUserReconciler(mgr)).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "DruidUser")
+			os.Exit(1)
+		}
+		if err = (druidsecuritycontrollers.NewDruidRoleReconciler(mgr)).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "DruidRole")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("DruidUser and DruidRole controllers are disabled")
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -132,6 +214,22 @@ func main() {
 	}
 }
 
+// hasCRDs returns true when the CRDs of the kinds are installed, and logs the missing ones otherwise.
+func hasCRDs(mgr ctrl.Manager, kinds ...string) bool {
+	for _, kind := range kinds {
+		gk := schema.GroupKind{Group: druidv1alpha1.GroupVersion.Group, Kind: kind}
+		if _, err := mgr.GetRESTMapper().RESTMapping(gk, druidv1alpha1.GroupVersion.Version); err != nil {
+			if meta.IsNoMatchError(err) {
+				setupLog.Info("CRD is not installed, its controller is skipped. Apply the CRDs of the chart to enable it", "kind", kind)
+			} else {
+				setupLog.Error(err, "unable to look up the CRD, its controller is skipped", "kind", kind)
+			}
+			return false
+		}
+	}
+	return true
+}
+
 func watchNamespaceCache() cache.NewCacheFunc {
 	var managerWatchCache cache.NewCacheFunc
 	ns := strings.Split(watchNamespace, ",")
@@ -16,6 +16,7 @@ KIND, either express or implied.  See the License for the
 specific language governing permissions and limitations
 under the License.
 */
+// +kubebuilder:object:generate=true
 package druidapi
 
 import (
@@ -23,7 +24,11 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	internalhttp "github.com/datainfrahq/druid-operator/pkg/http"
 	v1 "k8s.io/api/core/v1"
@@ -35,25 +40,100 @@ const (
 	DruidRouterPort  = "8088"
 	OperatorUserName = "OperatorUserName"
 	OperatorPassword = "OperatorPassword"
+	OperatorToken    = "OperatorToken"
+	CACertKey        = "ca.crt"
+	TLSCertKey       = "tls.crt"
+	TLSKeyKey        = "tls.key"
+
+	// AuthTokenDirEnv is the environment variable of the directory of the operator the tokenPath of the CRs
+	// must be in. The tokenPath is rejected when it is unset.
+	AuthTokenDirEnv = "AUTH_TOKEN_DIR"
 )
 
+// EnableAPIEndpoint allows the CRs to override the discovery of the Druid API with their apiEndpoint. It is set by
+// the --enable-api-endpoint flag of the operator, as the credentials of the CR are sent to the endpoint.
+var EnableAPIEndpoint bool
+
+// AllowCrossNamespaceSecrets allows the auth of the CRs to reference the secrets of other namespaces, as before
+// the secrets were pinned to the namespace of the CR. It is set by the --allow-cross-namespace-secrets flag of the
+// operator.
+var AllowCrossNamespaceSecrets bool
+
 type AuthType string
 
 const (
-	BasicAuth AuthType = "basic-auth"
+	BasicAuth   AuthType = "basic-auth"
+	BearerToken AuthType = "bearer-token"
 )
 
 type Auth struct {
-	// +required
-	Type AuthType `json:"type"`
-	// +required
-	SecretRef v1.SecretReference `json:"secretRef"`
+	// Type is the type of the credentials sent to the cluster, basic-auth or bearer-token. No credentials
+	// are sent when unset, the TLS and headers settings still apply.
+	// +optional
+	// +kubebuilder:validation:Enum=basic-auth;bearer-token
+	Type AuthType `json:"type,omitempty"`
+	// SecretRef is the secret holding the credentials, in the namespace of the CR unless the operator runs with
+	// --allow-cross-namespace-secrets.
+	// +optional
+	SecretRef v1.SecretReference `json:"secretRef,omitempty"`
 
 	// UsernameKey specifies the key within the Kubernetes secret that contains the username for authentication.
 	UsernameKey string `json:"usernameKey,omitempty"`
 
 	// PasswordKey specifies the key within the Kubernetes secret that contains the password for authentication.
 	PasswordKey string `json:"passwordKey,omitempty"`
+
+	// TokenKey specifies the key within the Kubernetes secret that contains the bearer token.
+	TokenKey string `json:"tokenKey,omitempty"`
+
+	// TokenPath is the path of a file the bearer token is read from rather than the secret, such as a
+	// projected service account token mounted in the operator. It is read on every reconcile, and must be
+	// in the directory set in the AUTH_TOKEN_DIR environment variable of the operator.
+	// +optional
+	TokenPath string `json:"tokenPath,omitempty"`
+
+	// TLS configures the CA bundle and the client certificate used to connect to the cluster.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+
+	// Headers are added to every request to the cluster.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// TLSConfig configures the TLS connections to the cluster.
+type TLSConfig struct {
+	// CASecretRef is the secret holding the CA bundle the certificate of the cluster is verified with.
+	// The system CAs are used when unset.
+	// +optional
+	CASecretRef *v1.SecretReference `json:"caSecretRef,omitempty"`
+
+	// CAKey specifies the key within the CA secret that contains the CA bundle, defaults to ca.crt.
+	// +optional
+	CAKey string `json:"caKey,omitempty"`
+
+	// ClientCertSecretRef is the secret holding the client certificate and key for mutual TLS, a
+	// kubernetes.io/tls secret by default.
+	// +optional
+	ClientCertSecretRef *v1.SecretReference `json:"clientCertSecretRef,omitempty"`
+
+	// CertKey specifies the key within the client certificate secret that contains the certificate,
+	// defaults to tls.crt.
+	// +optional
+	CertKey string `json:"certKey,omitempty"`
+
+	// KeyKey specifies the key within the client certificate secret that contains the private key,
+	// defaults to tls.key.
+	// +optional
+	KeyKey string `json:"keyKey,omitempty"`
+
+	// ServerName overrides the name the certificate of the cluster is verified against.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables the verification of the certificate of the cluster.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
 }
 
 // GetAuthCreds retrieves basic authentication credentials from a Kubernetes secret.
@@ -62,6 +142,7 @@ type Auth struct {
 //
 //	ctx: The context object.
 //	c: The Kubernetes client.
+//	namespace: The namespace of the CR, the secret must be in it.
 //	auth: The Auth object containing the secret reference.
 //
 // Returns:
@@ -70,6 +151,7 @@ type Auth struct {
 func GetAuthCreds(
 	ctx context.Context,
 	c client.Client,
+	namespace string,
 	auth Auth,
 ) (internalhttp.BasicAuth, error) {
 	userNameKey := OperatorUserName
@@ -84,21 +166,18 @@ func GetAuthCreds(
 	}
 
 	// Check if the mentioned secret exists
-	if auth != (Auth{}) {
-		secret := v1.Secret{}
-		if err := c.Get(ctx, types.NamespacedName{
-			Namespace: auth.SecretRef.Namespace,
-			Name:      auth.SecretRef.Name,
-		}, &secret); err != nil {
+	if auth.SecretRef != (v1.SecretReference{}) && (auth.Type == "" || auth.Type == BasicAuth) {
+		secret, err := getSecret(ctx, c, namespace, auth.SecretRef)
+		if err != nil {
 			return internalhttp.BasicAuth{}, err
 		}
 
 		if _, ok := secret.Data[userNameKey]; !ok {
-			return internalhttp.BasicAuth{}, fmt.Errorf("username key %q not found in secret %s/%s", userNameKey, auth.SecretRef.Namespace, auth.SecretRef.Name)
+			return internalhttp.BasicAuth{}, fmt.Errorf("username key %q not found in secret %s/%s", userNameKey, secret.Namespace, secret.Name)
 		}
 
 		if _, ok := secret.Data[passwordKey]; !ok {
-			return internalhttp.BasicAuth{}, fmt.Errorf("password key %q not found in secret %s/%s", passwordKey, auth.SecretRef.Namespace, auth.SecretRef.Name)
+			return internalhttp.BasicAuth{}, fmt.Errorf("password key %q not found in secret %s/%s", passwordKey, secret.Namespace, secret.Name)
 		}
 
 		creds := internalhttp.BasicAuth{
@@ -112,6 +191,138 @@ func GetAuthCreds(
 	return internalhttp.BasicAuth{}, nil
 }
 
+// GetAuth builds the authentication of the requests to the cluster: the basic authentication credentials
+// or the bearer token, the TLS configuration and the headers.
+// Parameters:
+//
+//	ctx: The context object.
+//	c: The Kubernetes client.
+//	namespace: The namespace of the CR, the secrets must be in it.
+//	auth: The Auth object of the CR.
+//
+// Returns:
+//
+//	Auth: The authentication of the requests, or an error if a secret or the token file can't be read.
+func GetAuth(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	auth Auth,
+) (internalhttp.Auth, error) {
+	basicAuth, err := GetAuthCreds(ctx, c, namespace, auth)
+	if err != nil {
+		return internalhttp.Auth{}, err
+	}
+	httpAuth := internalhttp.Auth{
+		BasicAuth: basicAuth,
+		Headers:   auth.Headers,
+	}
+
+	if auth.Type == BearerToken {
+		if auth.TokenPath != "" {
+			token, err := readTokenFile(auth.TokenPath)
+			if err != nil {
+				return internalhttp.Auth{}, err
+			}
+			httpAuth.BearerToken = strings.TrimSpace(string(token))
+		} else {
+			token, err := getSecretKey(ctx, c, namespace, auth.SecretRef, auth.TokenKey, OperatorToken)
+			if err != nil {
+				return internalhttp.Auth{}, err
+			}
+			httpAuth.BearerToken = strings.TrimSpace(string(token))
+		}
+	}
+
+	if auth.TLS != nil {
+		httpAuth.TLS = &internalhttp.TLS{
+			ServerName:         auth.TLS.ServerName,
+			InsecureSkipVerify: auth.TLS.InsecureSkipVerify,
+		}
+		if auth.TLS.CASecretRef != nil {
+			if httpAuth.TLS.CA, err = getSecretKey(ctx, c, namespace, *auth.TLS.CASecretRef, auth.TLS.CAKey, CACertKey); err != nil {
+				return internalhttp.Auth{}, err
+			}
+		}
+		if auth.TLS.ClientCertSecretRef != nil {
+			if httpAuth.TLS.Cert, err = getSecretKey(ctx, c, namespace, *auth.TLS.ClientCertSecretRef, auth.TLS.CertKey, TLSCertKey); err != nil {
+				return internalhttp.Auth{}, err
+			}
+			if httpAuth.TLS.Key, err = getSecretKey(ctx, c, namespace, *auth.TLS.ClientCertSecretRef, auth.TLS.KeyKey, TLSKeyKey); err != nil {
+				return internalhttp.Auth{}, err
+			}
+		}
+	}
+
+	return httpAuth, nil
+}
+
+// readTokenFile reads the token file at tokenPath, relative to the AUTH_TOKEN_DIR directory unless absolute. The
+// file must be in that directory once its symlinks are resolved, so that a CR can't read any file of the operator.
+func readTokenFile(tokenPath string) ([]byte, error) {
+	dir := os.Getenv(AuthTokenDirEnv)
+	if dir == "" {
+		return nil, fmt.Errorf("tokenPath is not allowed, %s is not set in the operator", AuthTokenDirEnv)
+	}
+	if !filepath.IsAbs(tokenPath) {
+		tokenPath = filepath.Join(dir, tokenPath)
+	}
+
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	resolvedPath, err := filepath.EvalSymlinks(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(resolvedDir, resolvedPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("tokenPath %q is not in %s %q", tokenPath, AuthTokenDirEnv, dir)
+	}
+	return os.ReadFile(resolvedPath)
+}
+
+// getSecret returns the secret of ref, which must be in the namespace of the CR so that a CR can't make the operator
+// send the secrets of other namespaces, unless AllowCrossNamespaceSecrets is set. The namespace of the CR is used
+// when ref has none.
+func getSecret(ctx context.Context, c client.Client, namespace string, ref v1.SecretReference) (*v1.Secret, error) {
+	if ref.Namespace != "" && ref.Namespace != namespace {
+		if !AllowCrossNamespaceSecrets {
+			return nil, fmt.Errorf("secret %s/%s is not in the namespace %s of the CR, the operator is not run with --allow-cross-namespace-secrets",
+				ref.Namespace, ref.Name, namespace)
+		}
+		namespace = ref.Namespace
+	}
+
+	secret := &v1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      ref.Name,
+	}, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// getSecretKey returns the value of key in the secret, or of defaultKey when key is empty.
+func getSecretKey(ctx context.Context, c client.Client, namespace string, ref v1.SecretReference, key, defaultKey string) ([]byte, error) {
+	if key == "" {
+		key = defaultKey
+	}
+
+	secret, err := getSecret(ctx, c, namespace, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found in secret %s/%s", key, secret.Namespace, secret.Name)
+	}
+	return value, nil
+}
+
 // MakePath constructs the appropriate path for the specified Druid API.
 // Parameters:
 //
@@ -141,39 +352,155 @@ func MakePath(baseURL, componentType, apiType string, additionalPaths ...string)
 	return u.String()
 }
 
-// GetRouterSvcUrl retrieves the URL of the Druid router service.
+// MakeSQLTaskPath constructs the path of the SQL task API, which runs INSERT and REPLACE
+// statements as multi-stage query tasks.
+// Parameters:
+//
+//	baseURL: The base URL of the Druid cluster. For example, http://router-svc.namespace.svc.cluster.local:8088.
+//
+// Returns:
+//
+//	string: The constructed path.
+func MakeSQLTaskPath(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		fmt.Println("Error parsing URL:", err)
+		return ""
+	}
+
+	u.Path = path.Join("druid", "v2", "sql", "task")
+
+	return u.String()
+}
+
+// MakeBasicSecurityPath constructs the path of the basic-security extension API of an authenticator or
+// authorizer. apiType is "authentication" or "authorization", name is the name of the authenticator or authorizer.
+func MakeBasicSecurityPath(baseURL, apiType, name string, additionalPaths ...string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		fmt.Println("Error parsing URL:", err)
+		return ""
+	}
+
+	u.Path = path.Join(append([]string{"druid-ext", "basic-security", apiType, "db", name}, additionalPaths...)...)
+
+	return u.String()
+}
+
+// The Druid APIs, named after the component of their path such as /druid/indexer/v1, and the SQL API of
+// /druid/v2/sql.
+const (
+	CoordinatorAPI = "coordinator"
+	IndexerAPI     = "indexer"
+	SQLAPI         = "sql"
+)
+
+// apiServices are the services serving each Druid API when the cluster has no router. A coordinator running as an
+// overlord also serves the indexer API.
+var apiServices = map[string][]string{
+	CoordinatorAPI: {"coordinator"},
+	IndexerAPI:     {"overlord", "coordinator"},
+	SQLAPI:         {"broker"},
+}
+
+// GetRouterSvcUrl retrieves the URL of a Druid API of a cluster. The endpoint is used as is when set, which
+// requires EnableAPIEndpoint, otherwise the URL is built from the router service, or the service serving the API
+// when the cluster has no router: the coordinator for the coordinator API, the overlord for the indexer API, the
+// broker for the SQL API. The actual port of the service is used.
 // Parameters:
 //
 //	namespace: The namespace of the Druid cluster.
 //	druidClusterName: The name of the Druid cluster.
+//	endpoint: The URL of the Druid API overriding the discovery, such as https://druid.example.com:9088.
+//	api: The Druid API the URL is used for, CoordinatorAPI, IndexerAPI or SQLAPI.
+//	useTLS: Whether the Druid API is served over TLS, https is also used when the service port is a TLS port.
 //	c: The Kubernetes client.
 //
 // Returns:
 //
-//	string: The URL of the Druid router service.
-func GetRouterSvcUrl(namespace, druidClusterName string, c client.Client) (string, error) {
-	listOpts := []client.ListOption{
-		client.InNamespace(namespace),
-		client.MatchingLabels(map[string]string{
-			"druid_cr":  druidClusterName,
-			"component": "router",
-		}),
+//	string: The URL of the Druid API.
+func GetRouterSvcUrl(namespace, druidClusterName, endpoint, api string, useTLS bool, c client.Client) (string, error) {
+	if endpoint != "" {
+		if !EnableAPIEndpoint {
+			return "", errors.New("apiEndpoint is not allowed, the operator is not run with --enable-api-endpoint")
+		}
+		return strings.TrimSuffix(endpoint, "/"), nil
 	}
-	svcList := &v1.ServiceList{}
-	if err := c.List(context.Background(), svcList, listOpts...); err != nil {
-		return "", err
+
+	services, ok := apiServices[api]
+	if !ok {
+		return "", fmt.Errorf("unknown Druid API [%s]", api)
 	}
-	var svcName string
 
-	for range svcList.Items {
-		svcName = svcList.Items[0].Name
+	for _, component := range append([]string{"router"}, services...) {
+		listOpts := []client.ListOption{
+			client.InNamespace(namespace),
+			client.MatchingLabels(map[string]string{
+				"druid_cr":  druidClusterName,
+				"component": component,
+			}),
+		}
+		svcList := &v1.ServiceList{}
+		if err := c.List(context.Background(), svcList, listOpts...); err != nil {
+			return "", err
+		}
+
+		svc, ok := pickService(svcList.Items)
+		if !ok {
+			continue
+		}
+
+		port, tls := pickServicePort(svc.Spec.Ports, useTLS)
+		scheme := "http"
+		if useTLS || tls {
+			scheme = "https"
+		}
+		return fmt.Sprintf("%s://%s.%s:%d", scheme, svc.Name, namespace, port), nil
 	}
 
-	if svcName == "" {
-		return "", errors.New("router svc discovery fail")
+	return "", errors.New("router svc discovery fail")
+}
+
+// pickService returns the first service by name with ports, preferring the services with a cluster IP over
+// the headless ones.
+func pickService(services []v1.Service) (v1.Service, bool) {
+	var candidates []v1.Service
+	for _, svc := range services {
+		if len(svc.Spec.Ports) > 0 {
+			candidates = append(candidates, svc)
+		}
+	}
+	if len(candidates) == 0 {
+		return v1.Service{}, false
 	}
 
-	newName := "http://" + svcName + "." + namespace + ":" + DruidRouterPort
+	sort.Slice(candidates, func(i, j int) bool {
+		iHeadless := candidates[i].Spec.ClusterIP == v1.ClusterIPNone
+		jHeadless := candidates[j].Spec.ClusterIP == v1.ClusterIPNone
+		if iHeadless != jHeadless {
+			return !iHeadless
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates[0], true
+}
 
-	return newName, nil
+// pickServicePort returns the first TLS port of the service when useTLS is set and the first plaintext port
+// otherwise, or its first port when it has none of them. It also returns whether the port is a TLS port.
+func pickServicePort(ports []v1.ServicePort, useTLS bool) (int32, bool) {
+	for _, port := range ports {
+		if isTLSPort(port) == useTLS {
+			return port.Port, useTLS
+		}
+	}
+	return ports[0].Port, isTLSPort(ports[0])
+}
+
+// isTLSPort returns true when the port is named or declared as a https port.
+func isTLSPort(port v1.ServicePort) bool {
+	if port.AppProtocol != nil && strings.EqualFold(*port.AppProtocol, "https") {
+		return true
+	}
+	name := strings.ToLower(port.Name)
+	return strings.Contains(name, "https") || strings.Contains(name, "tls")
 }
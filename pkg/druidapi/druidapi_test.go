@@ -24,26 +24,57 @@ import (
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
 )
 
 func TestGetAuthCreds(t *testing.T) {
 	tests := []struct {
-		name      string
-		auth      Auth
-		expected  internalhttp.BasicAuth
-		expectErr bool
+		name           string
+		auth           Auth
+		allowNamespace bool
+		expected       internalhttp.BasicAuth
+		expectErr      bool
 	}{
 		{
 			name: "default keys present",
 			auth: Auth{
 				Type:      BasicAuth,
-				SecretRef: v1.SecretReference{Name: "test-default", Namespace: "test"},
+				SecretRef: v1.SecretReference{Name: "test-default", Namespace: "default"},
 			},
 			expected:  internalhttp.BasicAuth{UserName: "test-user", Password: "test-password"},
 			expectErr: false,
 		},
+		{
+			name: "secret in the namespace of the CR by default",
+			auth: Auth{
+				Type:      BasicAuth,
+				SecretRef: v1.SecretReference{Name: "test-default"},
+			},
+			expected:  internalhttp.BasicAuth{UserName: "test-user", Password: "test-password"},
+			expectErr: false,
+		},
+		{
+			name: "secret in another namespace",
+			auth: Auth{
+				Type:      BasicAuth,
+				SecretRef: v1.SecretReference{Name: "test-other", Namespace: "other"},
+			},
+			expected:  internalhttp.BasicAuth{},
+			expectErr: true,
+		},
+		{
+			name: "secret in another namespace allowed",
+			auth: Auth{
+				Type:      BasicAuth,
+				SecretRef: v1.SecretReference{Name: "test-other", Namespace: "other"},
+			},
+			allowNamespace: true,
+			expected:       internalhttp.BasicAuth{UserName: "other-user", Password: "other-password"},
+			expectErr:      false,
+		},
 		{
 			name: "custom keys present",
 			auth: Auth{
@@ -99,13 +130,23 @@ func TestGetAuthCreds(t *testing.T) {
 		WithObjects(&v1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "test-default",
-				Namespace: "test",
+				Namespace: "default",
 			},
 			Data: map[string][]byte{
 				OperatorUserName: []byte("test-user"),
 				OperatorPassword: []byte("test-password"),
 			},
 		}).
+		WithObjects(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-other",
+				Namespace: "other",
+			},
+			Data: map[string][]byte{
+				OperatorUserName: []byte("other-user"),
+				OperatorPassword: []byte("other-password"),
+			},
+		}).
 		WithObjects(&v1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "test",
@@ -120,7 +161,156 @@ func TestGetAuthCreds(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			actual, err := GetAuthCreds(context.TODO(), client, tt.auth)
+			AllowCrossNamespaceSecrets = tt.allowNamespace
+			defer func() { AllowCrossNamespaceSecrets = false }()
+
+			actual, err := GetAuthCreds(context.TODO(), client, "default", tt.auth)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestGetAuth(t *testing.T) {
+	tokenDir := t.TempDir()
+	t.Setenv(AuthTokenDirEnv, tokenDir)
+	tokenPath := filepath.Join(tokenDir, "token")
+	assert.NoError(t, os.WriteFile(tokenPath, []byte("projected-token\n"), 0600))
+	outsidePath := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(outsidePath, []byte("other-token"), 0600))
+	assert.NoError(t, os.Symlink(outsidePath, filepath.Join(tokenDir, "link")))
+
+	tests := []struct {
+		name      string
+		auth      Auth
+		expected  internalhttp.Auth
+		expectErr bool
+	}{
+		{
+			name: "basic auth",
+			auth: Auth{
+				Type:      BasicAuth,
+				SecretRef: v1.SecretReference{Name: "creds", Namespace: "default"},
+				Headers:   map[string]string{"X-Tenant": "team-a"},
+			},
+			expected: internalhttp.Auth{
+				BasicAuth: internalhttp.BasicAuth{UserName: "admin", Password: "password"},
+				Headers:   map[string]string{"X-Tenant": "team-a"},
+			},
+		},
+		{
+			name: "bearer token from a secret",
+			auth: Auth{
+				Type:      BearerToken,
+				SecretRef: v1.SecretReference{Name: "creds", Namespace: "default"},
+			},
+			expected: internalhttp.Auth{BearerToken: "secret-token"},
+		},
+		{
+			name: "bearer token from a file",
+			auth: Auth{
+				Type:      BearerToken,
+				TokenPath: tokenPath,
+			},
+			expected: internalhttp.Auth{BearerToken: "projected-token"},
+		},
+		{
+			name: "bearer token from a file relative to the token directory",
+			auth: Auth{
+				Type:      BearerToken,
+				TokenPath: "token",
+			},
+			expected: internalhttp.Auth{BearerToken: "projected-token"},
+		},
+		{
+			name: "bearer token from a file outside of the token directory",
+			auth: Auth{
+				Type:      BearerToken,
+				TokenPath: outsidePath,
+			},
+			expected:  internalhttp.Auth{},
+			expectErr: true,
+		},
+		{
+			name: "bearer token from a file escaping the token directory",
+			auth: Auth{
+				Type:      BearerToken,
+				TokenPath: filepath.Join(tokenDir, "..", filepath.Base(filepath.Dir(outsidePath)), "token"),
+			},
+			expected:  internalhttp.Auth{},
+			expectErr: true,
+		},
+		{
+			name: "bearer token from a symlink out of the token directory",
+			auth: Auth{
+				Type:      BearerToken,
+				TokenPath: "link",
+			},
+			expected:  internalhttp.Auth{},
+			expectErr: true,
+		},
+		{
+			name: "mutual TLS with a CA bundle",
+			auth: Auth{
+				TLS: &TLSConfig{
+					CASecretRef:         &v1.SecretReference{Name: "ca", Namespace: "default"},
+					ClientCertSecretRef: &v1.SecretReference{Name: "client-cert", Namespace: "default"},
+					ServerName:          "router",
+				},
+			},
+			expected: internalhttp.Auth{
+				TLS: &internalhttp.TLS{CA: []byte("ca"), Cert: []byte("cert"), Key: []byte("key"), ServerName: "router"},
+			},
+		},
+		{
+			name: "CA secret in another namespace",
+			auth: Auth{
+				TLS: &TLSConfig{
+					CASecretRef: &v1.SecretReference{Name: "ca", Namespace: "kube-system"},
+				},
+			},
+			expected:  internalhttp.Auth{},
+			expectErr: true,
+		},
+		{
+			name: "missing CA key",
+			auth: Auth{
+				TLS: &TLSConfig{
+					CASecretRef: &v1.SecretReference{Name: "ca", Namespace: "default"},
+					CAKey:       "nope",
+				},
+			},
+			expected:  internalhttp.Auth{},
+			expectErr: true,
+		},
+	}
+
+	client := fake.NewClientBuilder().
+		WithObjects(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"},
+			Data: map[string][]byte{
+				OperatorUserName: []byte("admin"),
+				OperatorPassword: []byte("password"),
+				OperatorToken:    []byte("secret-token"),
+			},
+		}).
+		WithObjects(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "default"},
+			Data:       map[string][]byte{CACertKey: []byte("ca")},
+		}).
+		WithObjects(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "client-cert", Namespace: "default"},
+			Data:       map[string][]byte{TLSCertKey: []byte("cert"), TLSKeyKey: []byte("key")},
+		}).Build()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := GetAuth(context.TODO(), client, "default", tt.auth)
 			if tt.expectErr {
 				assert.Error(t, err)
 			} else {
@@ -132,6 +322,15 @@ func TestGetAuthCreds(t *testing.T) {
 	}
 }
 
+func TestGetAuthTokenDirUnset(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenPath, []byte("projected-token"), 0600))
+	t.Setenv(AuthTokenDirEnv, "")
+
+	_, err := GetAuth(context.TODO(), nil, "default", Auth{Type: BearerToken, TokenPath: tokenPath})
+	assert.Error(t, err)
+}
+
 func TestMakePath(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -182,3 +381,157 @@ func TestMakePath(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeSQLTaskPath(t *testing.T) {
+	assert.Equal(t, "http://example-druid-service/druid/v2/sql/task", MakeSQLTaskPath("http://example-druid-service"))
+	assert.Equal(t, "druid/v2/sql/task", MakeSQLTaskPath(""))
+}
+
+func TestMakeBasicSecurityPath(t *testing.T) {
+	assert.Equal(t,
+		"http://example-druid-service/druid-ext/basic-security/authentication/db/basic/users/alice/credentials",
+		MakeBasicSecurityPath("http://example-druid-service", "authentication", "basic", "users", "alice", "credentials"))
+	assert.Equal(t,
+		"http://example-druid-service/druid-ext/basic-security/authorization/db/basic/roles/readers",
+		MakeBasicSecurityPath("http://example-druid-service", "authorization", "basic", "roles", "readers"))
+}
+
+func TestGetRouterSvcUrl(t *testing.T) {
+	service := func(name, component string, ports ...v1.ServicePort) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "druid",
+				Labels:    map[string]string{"druid_cr": "tiny-cluster", "component": component},
+			},
+			Spec: v1.ServiceSpec{Ports: ports},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		services       []*v1.Service
+		endpoint       string
+		api            string
+		enableEndpoint bool
+		useTLS         bool
+		expected       string
+		expectErr      bool
+	}{
+		{
+			name:     "router port",
+			services: []*v1.Service{service("router", "router", v1.ServicePort{Name: "service-port", Port: 8888})},
+			expected: "http://router.druid:8888",
+		},
+		{
+			name: "router TLS port",
+			services: []*v1.Service{service("router", "router",
+				v1.ServicePort{Name: "service-port", Port: 8088},
+				v1.ServicePort{Name: "https", Port: 9088},
+			)},
+			useTLS:   true,
+			expected: "https://router.druid:9088",
+		},
+		{
+			name:     "router port named as a TLS port",
+			services: []*v1.Service{service("router", "router", v1.ServicePort{Name: "tls", Port: 9088})},
+			expected: "https://router.druid:9088",
+		},
+		{
+			name: "coordinator without router",
+			services: []*v1.Service{
+				service("overlord", "overlord", v1.ServicePort{Name: "service-port", Port: 8090}),
+				service("coordinator", "coordinator", v1.ServicePort{Name: "service-port", Port: 8081}),
+			},
+			expected: "http://coordinator.druid:8081",
+		},
+		{
+			name: "overlord without router",
+			services: []*v1.Service{
+				service("overlord", "overlord", v1.ServicePort{Name: "service-port", Port: 8090}),
+				service("coordinator", "coordinator", v1.ServicePort{Name: "service-port", Port: 8081}),
+			},
+			api:      IndexerAPI,
+			expected: "http://overlord.druid:8090",
+		},
+		{
+			name:     "coordinator as overlord without router",
+			services: []*v1.Service{service("coordinator", "coordinator", v1.ServicePort{Name: "service-port", Port: 8081})},
+			api:      IndexerAPI,
+			expected: "http://coordinator.druid:8081",
+		},
+		{
+			name:      "no coordinator without router",
+			services:  []*v1.Service{service("overlord", "overlord", v1.ServicePort{Name: "service-port", Port: 8090})},
+			expectErr: true,
+		},
+		{
+			name: "router for the indexer API",
+			services: []*v1.Service{
+				service("router", "router", v1.ServicePort{Name: "service-port", Port: 8088}),
+				service("overlord", "overlord", v1.ServicePort{Name: "service-port", Port: 8090}),
+			},
+			api:      IndexerAPI,
+			expected: "http://router.druid:8088",
+		},
+		{
+			name: "broker for the SQL API without router",
+			services: []*v1.Service{
+				service("overlord", "overlord", v1.ServicePort{Name: "service-port", Port: 8090}),
+				service("broker", "broker", v1.ServicePort{Name: "service-port", Port: 8082}),
+			},
+			api:      SQLAPI,
+			expected: "http://broker.druid:8082",
+		},
+		{
+			name: "router for the SQL API",
+			services: []*v1.Service{
+				service("router", "router", v1.ServicePort{Name: "service-port", Port: 8088}),
+				service("broker", "broker", v1.ServicePort{Name: "service-port", Port: 8082}),
+			},
+			api:      SQLAPI,
+			expected: "http://router.druid:8088",
+		},
+		{
+			name:           "endpoint override",
+			services:       []*v1.Service{service("router", "router", v1.ServicePort{Name: "service-port", Port: 8088})},
+			endpoint:       "https://druid.example.com:9088/",
+			enableEndpoint: true,
+			expected:       "https://druid.example.com:9088",
+		},
+		{
+			name:      "endpoint override not enabled",
+			services:  []*v1.Service{service("router", "router", v1.ServicePort{Name: "service-port", Port: 8088})},
+			endpoint:  "https://druid.example.com:9088/",
+			expectErr: true,
+		},
+		{
+			name:      "no service",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			EnableAPIEndpoint = tt.enableEndpoint
+			defer func() { EnableAPIEndpoint = false }()
+
+			builder := fake.NewClientBuilder()
+			for _, svc := range tt.services {
+				builder = builder.WithObjects(svc)
+			}
+
+			api := tt.api
+			if api == "" {
+				api = CoordinatorAPI
+			}
+			actual, err := GetRouterSvcUrl("druid", "tiny-cluster", tt.endpoint, api, tt.useTLS, builder.Build())
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
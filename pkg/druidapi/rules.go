@@ -0,0 +1,79 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druidapi
+
+import (
+	"fmt"
+)
+
+// ruleRequiredFields maps every Druid load, drop and broadcast rule type to the fields it requires.
+// https://druid.apache.org/docs/latest/operations/rule-configuration
+var ruleRequiredFields = map[string][]string{
+	"loadForever":         {},
+	"loadByInterval":      {"interval"},
+	"loadByPeriod":        {"period"},
+	"dropForever":         {},
+	"dropByInterval":      {"interval"},
+	"dropByPeriod":        {"period"},
+	"dropBeforeByPeriod":  {"period"},
+	"broadcastForever":    {},
+	"broadcastByInterval": {"interval"},
+	"broadcastByPeriod":   {"period"},
+}
+
+// ValidateRules checks that every rule is a well-formed Druid retention rule.
+// Parameters:
+//
+//	rules: The rules, each one unmarshalled from its JSON representation.
+//
+// Returns:
+//
+//	error: The first malformed rule found, nil if all rules are valid.
+func ValidateRules(rules []map[string]interface{}) error {
+	for i, rule := range rules {
+		ruleType, ok := rule["type"].(string)
+		if !ok {
+			return fmt.Errorf("rule[%d] has no type", i)
+		}
+
+		requiredFields, ok := ruleRequiredFields[ruleType]
+		if !ok {
+			return fmt.Errorf("rule[%d] has unknown type %q", i, ruleType)
+		}
+
+		for _, field := range requiredFields {
+			if value, ok := rule[field].(string); !ok || value == "" {
+				return fmt.Errorf("rule[%d] of type %q requires field %q", i, ruleType, field)
+			}
+		}
+
+		if tieredReplicants, ok := rule["tieredReplicants"]; ok {
+			tiers, ok := tieredReplicants.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("rule[%d] tieredReplicants must be a map of tier to replicas", i)
+			}
+			for tier, replicas := range tiers {
+				if n, ok := replicas.(float64); !ok || n < 0 || n != float64(int64(n)) {
+					return fmt.Errorf("rule[%d] tier %q must have a non-negative integer replica count", i, tier)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package druidapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []map[string]interface{}
+		expectErr bool
+	}{
+		{
+			name: "valid rules",
+			rules: []map[string]interface{}{
+				{"type": "loadByPeriod", "period": "P1M", "tieredReplicants": map[string]interface{}{"_default_tier": float64(2)}},
+				{"type": "dropForever"},
+			},
+			expectErr: false,
+		},
+		{
+			name:      "missing type",
+			rules:     []map[string]interface{}{{"period": "P1M"}},
+			expectErr: true,
+		},
+		{
+			name:      "unknown type",
+			rules:     []map[string]interface{}{{"type": "load"}},
+			expectErr: true,
+		},
+		{
+			name:      "missing interval",
+			rules:     []map[string]interface{}{{"type": "dropByInterval"}},
+			expectErr: true,
+		},
+		{
+			name:      "negative replicas",
+			rules:     []map[string]interface{}{{"type": "loadForever", "tieredReplicants": map[string]interface{}{"hot": float64(-1)}}},
+			expectErr: true,
+		},
+		{
+			name:      "tieredReplicants is not a map",
+			rules:     []map[string]interface{}{{"type": "loadForever", "tieredReplicants": float64(2)}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRules(tt.rules)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
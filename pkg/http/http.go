@@ -20,8 +20,14 @@ package http
 
 import (
 	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"io"
 	"net/http"
+	"sync"
 )
 
 // DruidHTTP interface
@@ -48,6 +54,12 @@ func NewHTTPClient(client *http.Client, auth *Auth) DruidHTTP {
 // with druid clusters
 type Auth struct {
 	BasicAuth BasicAuth
+	// BearerToken is sent in the Authorization header when set
+	BearerToken string
+	// Headers are added to every request
+	Headers map[string]string
+	// TLS configures the CA bundle and client certificate of https requests
+	TLS *TLS
 }
 
 // BasicAuth
@@ -56,6 +68,102 @@ type BasicAuth struct {
 	Password string
 }
 
+// TLS holds the PEM encoded CA bundle and client certificate used to connect to the cluster.
+type TLS struct {
+	CA                 []byte
+	Cert               []byte
+	Key                []byte
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// maxTransports bounds the transports cached, the least recently used one is evicted past it, such as the
+// transport of a rotated certificate.
+const maxTransports = 32
+
+// transportCache caches the transports by TLS configuration, the HTTP clients are created on every request and
+// would otherwise leak their idle connections.
+type transportCache struct {
+	mu         sync.Mutex
+	max        int
+	order      *list.List
+	transports map[string]*list.Element
+}
+
+type cachedTransport struct {
+	key       string
+	transport *http.Transport
+}
+
+var transports = newTransportCache(maxTransports)
+
+func newTransportCache(max int) *transportCache {
+	return &transportCache{max: max, order: list.New(), transports: map[string]*list.Element{}}
+}
+
+// get returns the transport of the key, or stores the one built by newTransport.
+func (c *transportCache) get(key string, newTransport func() (*http.Transport, error)) (*http.Transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.transports[key]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*cachedTransport).transport, nil
+	}
+
+	transport, err := newTransport()
+	if err != nil {
+		return nil, err
+	}
+	c.transports[key] = c.order.PushFront(&cachedTransport{key: key, transport: transport})
+
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		evicted := oldest.Value.(*cachedTransport)
+		delete(c.transports, evicted.key)
+		evicted.transport.CloseIdleConnections()
+	}
+	return transport, nil
+}
+
+func (t *TLS) transport() (*http.Transport, error) {
+	hash := sha256.New()
+	for _, b := range [][]byte{t.CA, t.Cert, t.Key, []byte(t.ServerName)} {
+		hash.Write(b)
+		hash.Write([]byte{0})
+	}
+	if t.InsecureSkipVerify {
+		hash.Write([]byte{1})
+	}
+	key := string(hash.Sum(nil))
+
+	return transports.get(key, func() (*http.Transport, error) {
+		config := &tls.Config{
+			ServerName:         t.ServerName,
+			InsecureSkipVerify: t.InsecureSkipVerify,
+		}
+		if len(t.CA) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(t.CA) {
+				return nil, errors.New("no certificate found in the CA bundle")
+			}
+			config.RootCAs = pool
+		}
+		if len(t.Cert) > 0 || len(t.Key) > 0 {
+			cert, err := tls.X509KeyPair(t.Cert, t.Key)
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = config
+		return transport, nil
+	})
+}
+
 // Response passed to controller
 type Response struct {
 	ResponseBody string
@@ -74,8 +182,27 @@ func (c *DruidClient) Do(Method, url string, body []byte) (*Response, error) {
 		req.SetBasicAuth(c.Auth.BasicAuth.UserName, c.Auth.BasicAuth.Password)
 	}
 
+	if c.Auth.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Auth.BearerToken)
+	}
+
 	req.Header.Add("Content-Type", "application/json")
-	resp, err := c.HTTPClient.Do(req)
+	for key, value := range c.Auth.Headers {
+		req.Header.Set(key, value)
+	}
+
+	httpClient := c.HTTPClient
+	if c.Auth.TLS != nil && httpClient.Transport == nil {
+		transport, err := c.Auth.TLS.transport()
+		if err != nil {
+			return nil, err
+		}
+		withTransport := *httpClient
+		withTransport.Transport = transport
+		httpClient = &withTransport
+	}
+
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,91 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoAuth(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	// the server certificate doubles as the client certificate
+	cert := server.TLS.Certificates[0]
+	der, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	assert.NoError(t, err)
+	key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	auth := &Auth{
+		BearerToken: "token",
+		Headers:     map[string]string{"X-Tenant": "team-a"},
+		TLS:         &TLS{CA: ca, Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), Key: key},
+	}
+	resp, err := NewHTTPClient(&http.Client{}, auth).Do(http.MethodGet, server.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer token", received.Header.Get("Authorization"))
+	assert.Equal(t, "team-a", received.Header.Get("X-Tenant"))
+	assert.Len(t, received.TLS.PeerCertificates, 1)
+
+	// the transport is shared by the clients of a configuration
+	first, err := auth.TLS.transport()
+	assert.NoError(t, err)
+	second, err := auth.TLS.transport()
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+
+	// the certificate of the server isn't trusted without the CA bundle
+	_, err = NewHTTPClient(&http.Client{}, &Auth{TLS: &TLS{}}).Do(http.MethodGet, server.URL, nil)
+	assert.Error(t, err)
+}
+
+func TestTransportCacheEviction(t *testing.T) {
+	cache := newTransportCache(2)
+	newTransport := func() (*http.Transport, error) { return &http.Transport{}, nil }
+
+	a, _ := cache.get("a", newTransport)
+	b, _ := cache.get("b", newTransport)
+	// a is used again, b is now the least recently used transport
+	again, _ := cache.get("a", newTransport)
+	assert.Same(t, a, again)
+
+	_, _ = cache.get("c", newTransport)
+	assert.Equal(t, 2, cache.order.Len())
+	assert.Contains(t, cache.transports, "a")
+	assert.Contains(t, cache.transports, "c")
+	assert.NotContains(t, cache.transports, "b")
+
+	// an evicted transport is built again
+	rebuilt, _ := cache.get("b", newTransport)
+	assert.NotSame(t, b, rebuilt)
+}
@@ -0,0 +1,97 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package util
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cachedDataLabels are the labels of the configmaps and secrets created by the operator, the only ones whose data is
+// cached.
+var cachedDataLabels = labels.SelectorFromSet(labels.Set{"app": "druid"})
+
+// CacheByObject returns the cache options of the configmaps and secrets. The data of those not created by the
+// operator, such as the ones referenced by the CRs, is dropped from the cache, only their metadata is kept for the
+// watches.
+func CacheByObject() map[client.Object]cache.ByObject {
+	return map[client.Object]cache.ByObject{
+		&v1.ConfigMap{}: {Transform: dropUncachedData},
+		&v1.Secret{}:    {Transform: dropUncachedData},
+	}
+}
+
+func dropUncachedData(obj interface{}) (interface{}, error) {
+	switch o := obj.(type) {
+	case *v1.ConfigMap:
+		if !isDataCached(o) {
+			o.Data, o.BinaryData = nil, nil
+		}
+	case *v1.Secret:
+		if !isDataCached(o) {
+			o.Data, o.StringData = nil, nil
+		}
+	}
+	return obj, nil
+}
+
+// isDataCached returns false for the configmaps and secrets whose data is dropped from the cache.
+func isDataCached(obj client.Object) bool {
+	switch obj.(type) {
+	case *v1.ConfigMap, *v1.Secret:
+		return cachedDataLabels.Matches(labels.Set(obj.GetLabels()))
+	}
+	return true
+}
+
+// NewClient returns the client of the manager. It reads from the cache, except the configmaps and secrets whose data
+// is dropped from the cache by CacheByObject, which are read from the API server.
+func NewClient(config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Cache = nil
+	apiReader, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	return &uncachedDataClient{Client: c, apiReader: apiReader}, nil
+}
+
+type uncachedDataClient struct {
+	client.Client
+	apiReader client.Reader
+}
+
+func (c *uncachedDataClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.Client.Get(ctx, key, obj, opts...); err != nil {
+		return err
+	}
+	if !isDataCached(obj) {
+		return c.apiReader.Get(ctx, key, obj, opts...)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package util
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUncachedDataClient(t *testing.T) {
+	owned := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "druid-tiny-cluster-tls", Namespace: "druid", Labels: map[string]string{"app": "druid"}},
+		Data:       map[string][]byte{"tls.key": []byte("cached")},
+	}
+	referenced := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "metadata", Namespace: "druid"},
+		Data:       map[string][]byte{"password": []byte("s3cr3t")},
+	}
+	referencedConfigMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "druid"},
+		Data:       map[string]string{"threads": "4"},
+	}
+
+	// the cache holds the objects as transformed by CacheByObject
+	var cached []interface{}
+	for _, obj := range []interface{}{owned.DeepCopy(), referenced.DeepCopy(), referencedConfigMap.DeepCopy()} {
+		transformed, err := dropUncachedData(obj)
+		if err != nil {
+			t.Fatalf("failed to transform: %v", err)
+		}
+		cached = append(cached, transformed)
+	}
+	if cached[0].(*v1.Secret).Data == nil {
+		t.Errorf("expected the data of the owned secret to be cached")
+	}
+	if cached[1].(*v1.Secret).Data != nil || cached[2].(*v1.ConfigMap).Data != nil {
+		t.Errorf("expected the data of the referenced objects to be dropped")
+	}
+
+	apiServerSecret := owned.DeepCopy()
+	apiServerSecret.Data["tls.key"] = []byte("api server")
+	c := &uncachedDataClient{
+		Client:    fake.NewClientBuilder().WithObjects(cached[0].(*v1.Secret), cached[1].(*v1.Secret), cached[2].(*v1.ConfigMap)).Build(),
+		apiReader: fake.NewClientBuilder().WithObjects(apiServerSecret, referenced, referencedConfigMap).Build(),
+	}
+	ctx := context.TODO()
+
+	secret := &v1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "druid-tiny-cluster-tls", Namespace: "druid"}, secret); err != nil {
+		t.Fatalf("failed to get the owned secret: %v", err)
+	}
+	if string(secret.Data["tls.key"]) != "cached" {
+		t.Errorf("expected the owned secret to be read from the cache, got %s", secret.Data["tls.key"])
+	}
+
+	secret = &v1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "metadata", Namespace: "druid"}, secret); err != nil {
+		t.Fatalf("failed to get the referenced secret: %v", err)
+	}
+	if string(secret.Data["password"]) != "s3cr3t" {
+		t.Errorf("expected the referenced secret to be read from the API server, got %v", secret.Data)
+	}
+
+	cm := &v1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: "settings", Namespace: "druid"}, cm); err != nil {
+		t.Fatalf("failed to get the referenced configmap: %v", err)
+	}
+	if cm.Data["threads"] != "4" {
+		t.Errorf("expected the referenced configmap to be read from the API server, got %v", cm.Data)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+package util
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TransformStatusFunc returns the object with its status updated, it is given a copy of the object.
+type TransformStatusFunc func(obj client.Object) client.Object
+
+// LookupReconcileTime returns the period of the requeue of the CRs reconciled against the Druid APIs, read from
+// RECONCILE_WAIT and 10s by default.
+func LookupReconcileTime() time.Duration {
+	val, exists := os.LookupEnv("RECONCILE_WAIT")
+	if !exists {
+		return time.Second * 10
+	} else {
+		v, err := time.ParseDuration(val)
+		if err != nil {
+			// Exit Program if not valid
+			os.Exit(1)
+		}
+		return v
+	}
+}
+
+// ReconcileObject gets the object of the request into obj and runs do on it. It requeues the object after
+// LookupReconcileTime, an object already deleted is not requeued.
+func ReconcileObject(ctx context.Context, c client.Client, req ctrl.Request, obj client.Object, do func(ctx context.Context) error) (ctrl.Result, error) {
+	logr := log.FromContext(ctx)
+
+	if err := c.Get(ctx, req.NamespacedName, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if err := do(ctx); err != nil {
+		logr.Error(err, err.Error())
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: LookupReconcileTime()}, nil
+}
+
+// PatchStatus gets the latest version of obj and patches its status with the one returned by transform. It returns
+// the patched object and whether it was patched, a status left unchanged by transform is not patched.
+func PatchStatus(ctx context.Context, c client.Client, obj client.Object, transform TransformStatusFunc) (client.Object, bool, error) {
+	key := types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	if err := c.Get(ctx, key, obj); err != nil {
+		return nil, false, err
+	}
+
+	patch := client.MergeFrom(obj)
+	current := obj
+	obj = transform(obj.DeepCopyObject().(client.Object))
+	if equality.Semantic.DeepEqual(current, obj) {
+		return obj, false, nil
+	}
+	if err := c.Status().Patch(ctx, obj, patch); err != nil {
+		return nil, false, err
+	}
+	return obj, true, nil
+}
@@ -0,0 +1,61 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+// Package testutil holds the fixtures shared by the tests of the controllers.
+package testutil
+
+import (
+	"testing"
+
+	"github.com/datainfrahq/operator-runtime/builder"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/datainfrahq/druid-operator/apis/druid/v1alpha1"
+)
+
+// NewFakeClient returns a fake client holding the given objects, their status is a subresource as on a cluster.
+func NewFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	return NewFakeClientBuilder(t, objs...).Build()
+}
+
+// NewFakeClientBuilder returns the builder of NewFakeClient, to add interceptors or indexes to the client.
+func NewFakeClientBuilder(t *testing.T, objs ...client.Object) *fake.ClientBuilder {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(objs...)
+}
+
+// NewBuilder returns a builder emitting its events on the recorder under the name of the controller.
+func NewBuilder(recorder record.EventRecorder, controllerName string) builder.Builder {
+	return *builder.NewBuilder(
+		builder.ToNewBuilderRecorder(builder.BuilderRecorder{Recorder: recorder, ControllerName: controllerName}),
+	)
+}
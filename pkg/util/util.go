@@ -51,6 +51,30 @@ func IncludesJson(currentJson, desiredJson string) (bool, error) {
 	return includes(current, desired), nil
 }
 
+// IncludesJsonList checks if the current JSON list has as many elements as the desired JSON list, in the same order,
+// each of them including the key-value pairs of the desired element.
+func IncludesJsonList(currentJson, desiredJson string) (bool, error) {
+	var current, desired []map[string]interface{}
+
+	if err := json.Unmarshal([]byte(currentJson), &current); err != nil {
+		return false, fmt.Errorf("error parsing current JSON: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(desiredJson), &desired); err != nil {
+		return false, fmt.Errorf("error parsing desired JSON: %w", err)
+	}
+
+	if len(current) != len(desired) {
+		return false, nil
+	}
+	for i := range desired {
+		if !includes(current[i], desired[i]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
 // includes recursively checks if all key-value pairs in the desired map are present in the current map.
 func includes(current, desired map[string]interface{}) bool {
 	for key, desiredValue := range desired {
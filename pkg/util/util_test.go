@@ -181,3 +181,57 @@ func TestIncludesJson(t *testing.T) {
 		})
 	}
 }
+
+func TestIncludesJsonList(t *testing.T) {
+	tests := []struct {
+		name          string
+		currentJson   string
+		desiredJson   string
+		expectedEqual bool
+		expectError   bool
+	}{
+		{
+			name:          "Defaults added to the current elements",
+			currentJson:   `[{"type": "loadByPeriod", "period": "P7D", "includeFuture": true, "tieredReplicants": {"_default_tier": 2}}, {"type": "dropForever"}]`,
+			desiredJson:   `[{"type": "loadByPeriod", "period": "P7D", "tieredReplicants": {"_default_tier": 2}}, {"type": "dropForever"}]`,
+			expectedEqual: true,
+		},
+		{
+			name:          "Changed element",
+			currentJson:   `[{"type": "loadByPeriod", "period": "P7D", "tieredReplicants": {"_default_tier": 1}}, {"type": "dropForever"}]`,
+			desiredJson:   `[{"type": "loadByPeriod", "period": "P7D", "tieredReplicants": {"_default_tier": 2}}, {"type": "dropForever"}]`,
+			expectedEqual: false,
+		},
+		{
+			name:          "Extra element",
+			currentJson:   `[{"type": "loadForever"}, {"type": "dropForever"}]`,
+			desiredJson:   `[{"type": "loadForever"}]`,
+			expectedEqual: false,
+		},
+		{
+			name:          "Different order",
+			currentJson:   `[{"type": "dropForever"}, {"type": "loadForever"}]`,
+			desiredJson:   `[{"type": "loadForever"}, {"type": "dropForever"}]`,
+			expectedEqual: false,
+		},
+		{
+			name:        "Invalid JSON",
+			currentJson: `{"type": "loadForever"}`,
+			desiredJson: `[{"type": "loadForever"}]`,
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			equal, err := IncludesJsonList(test.currentJson, test.desiredJson)
+			if (err != nil) != test.expectError {
+				t.Errorf("IncludesJsonList() error = %v, expectError %v", err, test.expectError)
+				return
+			}
+			if equal != test.expectedEqual {
+				t.Errorf("IncludesJsonList() = %v, expectedEqual %v", equal, test.expectedEqual)
+			}
+		})
+	}
+}